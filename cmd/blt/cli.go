@@ -1,23 +1,46 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rdo34/blt/internal/app"
 	"github.com/rdo34/blt/internal/model"
 	"github.com/rdo34/blt/internal/store"
+	"golang.org/x/term"
 )
 
-// runCLI parses CLI subcommands. Returns (handled, exitCode).
+// runCLI parses CLI subcommands. Returns (handled, exitCode). Warnings
+// from the stores a command opened are printed to stderr afterwards.
 func runCLI(args []string) (bool, int) {
+	handled, code := dispatch(args)
+	for _, st := range opened {
+		for _, w := range st.Warnings() {
+			fmt.Fprintln(os.Stderr, "warning:", w)
+		}
+	}
+	return handled, code
+}
+
+func dispatch(args []string) (bool, int) {
 	if len(args) == 0 {
 		return false, 0
 	}
+	if name, rest, ok := leadingJournal(args); ok {
+		// `blt --journal work [command ...]`; without a command the TUI
+		// opens that journal.
+		journal = name
+		return dispatch(rest)
+	}
 	switch args[0] {
 	case "help", "-h", "--help":
 		printHelp()
@@ -30,26 +53,171 @@ func runCLI(args []string) (bool, int) {
 		return true, cliDelete(args[1:])
 	case "complete":
 		return true, cliComplete(args[1:])
+	case "cancel":
+		return true, cliCancel(args[1:])
 	case "migrate":
 		return true, cliMigrate(args[1:])
 	case "schedule":
 		return true, cliSchedule(args[1:])
 	case "edit":
 		return true, cliEdit(args[1:])
+	case "recur":
+		return true, cliRecur(args[1:])
+	case "collection", "collections":
+		return true, cliCollection(args[1:])
+	case "review":
+		return true, cliReview(args[1:], os.Stdin)
+	case "search":
+		return true, cliSearch(args[1:])
+	case "reindex":
+		return true, cliReindex(args[1:])
+	case "doctor":
+		return true, cliDoctor(args[1:])
+	case "migrate-data":
+		return true, cliMigrateData(args[1:])
+	case "encrypt":
+		return true, cliEncrypt(args[1:])
+	case "decrypt":
+		return true, cliDecrypt(args[1:])
+	case "trash":
+		return true, cliTrash(args[1:])
+	case "journal", "journals":
+		return true, cliJournal(args[1:])
+	case "backup", "backups":
+		return true, cliBackup(args[1:])
+	case "git":
+		return true, cliGit(args[1:])
+	case "show":
+		return true, cliShow(args[1:])
+	case "history":
+		return true, cliHistory(args[1:])
+	case "sync":
+		return true, cliSync(args[1:])
+	case "merge":
+		return true, cliMerge(args[1:])
+	case "index":
+		return true, cliIndex(args[1:])
+	case "future-pull":
+		return true, cliFuturePull(args[1:])
+	case "undo":
+		return true, cliUndo(args[1:], false)
+	case "redo":
+		return true, cliUndo(args[1:], true)
 	default:
 		// Not a CLI subcommand; fall back to TUI
 		return false, 0
 	}
 }
 
-func newAppWithContext(span, dateStr, dataDir string) (*app.App, error) {
-	var st *store.FSStore
-	var err error
-	if dataDir != "" {
-		st, err = store.NewFSStore(dataDir)
-	} else {
-		st, err = store.NewDefaultFSStore()
+// leadingJournal splits a --journal flag given before the command off args.
+func leadingJournal(args []string) (string, []string, bool) {
+	switch a := args[0]; {
+	case a == "--journal" || a == "-journal":
+		if len(args) > 1 {
+			return args[1], args[2:], true
+		}
+	case strings.HasPrefix(a, "--journal="), strings.HasPrefix(a, "-journal="):
+		return a[strings.Index(a, "=")+1:], args[1:], true
+	}
+	return "", nil, false
+}
+
+// opened records the stores a command used so runCLI can report warnings.
+var opened []*store.FSStore
+
+// openStore opens the data directory, asking for the passphrase when it is
+// encrypted, and takes the day's snapshot if that is still due. It returns
+// the file store and the Store to read and write the journal through,
+// which is an EncryptedStore over it when the directory is encrypted.
+func openStore(dataDir string) (*store.FSStore, store.Store, error) {
+	dir, err := resolveDir(dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := store.NewFSStore(dir)
+	var data store.Store = st
+	if errors.Is(err, store.ErrEncrypted) {
+		var pass string
+		if pass, err = passphrase("", false); err != nil {
+			return nil, nil, err
+		}
+		// The git merge driver that `blt sync` runs needs it as well.
+		_ = os.Setenv("BLT_PASSPHRASE", pass)
+		st, data, err = store.NewEncryptedFSStore(dir, pass)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	opened = append(opened, st)
+	if _, err := app.DailyBackup(st, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: daily backup:", err)
+	}
+	return st, data, nil
+}
+
+// journal is the --journal flag shared by all commands.
+var journal string
+
+// dataDirFlags registers --data-dir and --journal on fs, returning the
+// former.
+func dataDirFlags(fs *flag.FlagSet) *string {
+	fs.StringVar(&journal, "journal", journal, "use the named journal (see blt journal list)")
+	return fs.String("data-dir", "", "override data directory")
+}
+
+// resolveDir returns dataDir, or else the directory of the journal named
+// by --journal or the default one, and points preferences at it.
+func resolveDir(dataDir string) (string, error) {
+	dir := dataDir
+	if dir == "" {
+		j, err := store.ResolveJournal(journal)
+		if err != nil {
+			return "", err
+		}
+		dir = j.Dir
+	}
+	store.SetPreferencesDir(dir)
+	return dir, nil
+}
+
+// passphrase returns the passphrase from keyfile, BLT_PASSPHRASE or
+// BLT_KEYFILE, or prompts for it on the terminal (twice with confirm).
+func passphrase(keyfile string, confirm bool) (string, error) {
+	if keyfile != "" {
+		return store.ReadKeyFile(keyfile)
+	}
+	if pass, ok, err := store.PassphraseFromEnv(); ok || err != nil {
+		return pass, err
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("%w: set BLT_PASSPHRASE or BLT_KEYFILE", store.ErrEncrypted)
+	}
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
 	}
+	if len(pass) == 0 {
+		return "", errors.New("empty passphrase")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(again) != string(pass) {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return string(pass), nil
+}
+
+func newAppWithContext(span, dateStr, dataDir string) (*app.App, error) {
+	_, st, err := openStore(dataDir)
 	if err != nil {
 		return nil, err
 	}
@@ -60,6 +228,10 @@ func newAppWithContext(span, dateStr, dataDir string) (*app.App, error) {
 		_ = a.SetPeriod(model.PeriodWeek)
 	case "month":
 		_ = a.SetPeriod(model.PeriodMonth)
+	case "monthlog":
+		_ = a.SetPeriod(model.PeriodMonthLog)
+	case "future":
+		_ = a.SetPeriod(model.PeriodFuture)
 	default:
 		_ = a.SetPeriod(model.PeriodDay)
 	}
@@ -67,6 +239,8 @@ func newAppWithContext(span, dateStr, dataDir string) (*app.App, error) {
 	if dateStr != "" {
 		if d, err := time.Parse("2006-01-02", dateStr); err == nil {
 			_ = a.JumpToDate(d)
+		} else if d, err := time.Parse("2006-01", dateStr); err == nil {
+			_ = a.JumpToDate(d)
 		}
 	} else {
 		_ = a.LoadDay(time.Now())
@@ -97,6 +271,8 @@ func parseTypesCSV(s string) []model.BulletType {
 			out = append(out, model.Migrated)
 		case "scheduled":
 			out = append(out, model.Scheduled)
+		case "cancelled", "canceled", "irrelevant":
+			out = append(out, model.Cancelled)
 		}
 	}
 	return out
@@ -118,81 +294,149 @@ func parseTagsCSV(s string) []string {
 
 func cliList(args []string) int {
 	fs := flag.NewFlagSet("list", flag.ContinueOnError)
-	span := fs.String("timespan", "day", "day|week|month")
+	span := fs.String("timespan", "day", "day|week|month|monthlog|future")
 	dateStr := fs.String("date", "", "YYYY-MM-DD (defaults to today)")
 	types := fs.String("type", "", "comma-separated types")
 	tags := fs.String("tags", "", "comma-separated tags")
 	text := fs.String("text", "", "text filter")
 	jsonOut := fs.Bool("json", false, "output JSON")
-	dataDir := fs.String("data-dir", "", "override data directory")
+	all := fs.Bool("all-journals", false, "list every journal, each under its name")
+	sortBy := fs.String("sort", "manual", "manual|type|created|tag|open: order of each day's items")
+	dataDir := dataDirFlags(fs)
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
-
-	a, err := newAppWithContext(*span, *dateStr, *dataDir)
+	mode, err := app.ParseSortMode(*sortBy)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		return 1
+		return 2
 	}
-	if *text != "" {
-		a.SetTextFilter(*text)
+	journals, err := listedJournals(*all, *dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
-	if *types != "" {
-		a.SetTypeFilter(parseTypesCSV(*types))
+
+	// Emit JSON array of entries
+	type J struct {
+		Journal    string `json:",omitempty"`
+		Date       string
+		Log        string `json:",omitempty"`
+		Month      string `json:",omitempty"`
+		ID         string
+		Type       string
+		Text       string
+		Tags       []string
+		ParentID   string `json:",omitempty"`
+		Order      int    `json:",omitempty"`
+		Depth      int
+		OriginID   string `json:",omitempty"`
+		OriginDate string `json:",omitempty"`
+		CloneID    string `json:",omitempty"`
 	}
-	if *tags != "" {
-		a.SetTagFilter(parseTagsCSV(*tags))
+	out := []J{}
+	for _, jn := range journals {
+		a, err := newAppWithContext(*span, *dateStr, jn.Dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if *text != "" {
+			a.SetTextFilter(*text)
+		}
+		if *types != "" {
+			a.SetTypeFilter(parseTypesCSV(*types))
+		}
+		if *tags != "" {
+			a.SetTagFilter(parseTagsCSV(*tags))
+		}
+		a.Sort = mode
+		_ = a.Refresh()
+		vis := a.Visible()
+		if *jsonOut {
+			for _, e := range vis {
+				j := J{Journal: jn.Name, Date: e.Date.Format("2006-01-02"), Log: e.Log, Month: e.Item.Month, ID: e.Item.ID, Type: string(e.Item.Type), Text: e.Item.Text, Tags: e.Item.Tags, ParentID: e.Item.ParentID, Order: e.Item.Order, Depth: e.Depth, OriginID: e.Item.OriginID, CloneID: e.Item.CloneID}
+				if e.Item.OriginDate != nil {
+					j.OriginDate = e.Item.OriginDate.Format("2006-01-02")
+				}
+				out = append(out, j)
+			}
+			continue
+		}
+		if *all {
+			fmt.Printf("== %s ==\n", jn.Name)
+		}
+		printList(a, vis)
 	}
-	_ = a.Refresh()
-	vis := a.Visible()
 	if *jsonOut {
-		// Emit JSON array of entries
-		type J struct {
-			Date string
-			ID   string
-			Type string
-			Text string
-			Tags []string
-		}
-		out := make([]J, 0, len(vis))
-		for _, e := range vis {
-			out = append(out, J{Date: e.Date.Format("2006-01-02"), ID: e.Item.ID, Type: string(e.Item.Type), Text: e.Item.Text, Tags: e.Item.Tags})
-		}
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
 		_ = enc.Encode(out)
-		return 0
 	}
+	return 0
+}
+
+// printList prints entries as `blt list` does, with absolute indexes
+// within each day (ignoring filters), read once per day.
+func printList(a *app.App, vis []app.Entry) {
+	index := map[string]int{}
+	loaded := map[string]bool{}
+	notes := a.LineageSummaries(vis)
 	for _, e := range vis {
-		// compute absolute index within the day (ignores filters)
-		dayItems, _ := a.Store.LoadDay(e.Date)
-		abs := 0
-		for i := range dayItems {
-			if dayItems[i].ID == e.Item.ID {
-				abs = i
-				break
+		key := e.Log + "/" + e.Date.Format("2006-01-02")
+		if !loaded[key] {
+			loaded[key] = true
+			dayItems, _ := a.LoadLog(e.Log, e.Date)
+			for i := range dayItems {
+				index[dayItems[i].ID] = i
 			}
 		}
-		label := formatBullet(e.Item)
-		if a.Period != model.PeriodDay {
-			fmt.Printf("%s %d. %s\n", e.Date.Format("2006-01-02"), abs, label)
-		} else {
+		abs := index[e.Item.ID]
+		label := strings.Repeat("  ", e.Depth) + formatBullet(e.Item)
+		if note := notes[e.Item.ID]; note != "" {
+			label += "  (" + note + ")"
+		}
+		switch a.Period {
+		case model.PeriodDay, model.PeriodMonthLog:
 			fmt.Printf("%d. %s\n", abs, label)
+		case model.PeriodFuture:
+			fmt.Printf("%s %d. %s\n", e.Date.Format("2006-01"), abs, label)
+		default:
+			fmt.Printf("%s %d. %s\n", e.Date.Format("2006-01-02"), abs, label)
 		}
 	}
-	return 0
+}
+
+// listedJournals returns every journal for --all-journals, or else the one
+// selected by --data-dir or --journal with an empty name, so output for a
+// single journal stays as it was.
+func listedJournals(all bool, dataDir string) ([]store.Journal, error) {
+	if !all {
+		return []store.Journal{{Dir: dataDir}}, nil
+	}
+	if dataDir != "" || journal != "" {
+		return nil, errors.New("--all-journals cannot be combined with --data-dir or --journal")
+	}
+	return store.Journals()
 }
 
 func cliAdd(args []string) int {
 	fs := flag.NewFlagSet("add", flag.ContinueOnError)
-	dataDir := fs.String("data-dir", "", "override data directory")
+	dataDir := dataDirFlags(fs)
 	dateStr := fs.String("date", "", "YYYY-MM-DD (defaults to today)")
 	typ := fs.String("type", "task", "task|event|note|important|inspiration")
 	note := fs.String("note", "", "shortcut for --type note with given text")
 	text := fs.String("text", "", "bullet text")
+	parent := fs.String("parent", "", "nest under the bullet with this ID (or unique prefix); implies its log and date")
+	month := fs.String("month", "", "YYYY-MM: add to that month's monthly log")
+	future := fs.String("future", "", "YYYY-MM: add to the future log for that month")
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
+	if *month != "" && *future != "" {
+		fmt.Fprintln(os.Stderr, "use only one of --month or --future")
+		return 2
+	}
 	t := strings.TrimSpace(*text)
 	if *note != "" {
 		t = *note
@@ -202,12 +446,7 @@ func cliAdd(args []string) int {
 		fmt.Fprintln(os.Stderr, "missing --text or --note")
 		return 2
 	}
-	st, err := func() (*store.FSStore, error) {
-		if *dataDir != "" {
-			return store.NewFSStore(*dataDir)
-		}
-		return store.NewDefaultFSStore()
-	}()
+	_, st, err := openStore(*dataDir)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
@@ -234,7 +473,36 @@ func cliAdd(args []string) int {
 		fmt.Fprintln(os.Stderr, "invalid --type")
 		return 2
 	}
-	if err := st.Append(d, b); err != nil {
+	a := app.New(st)
+	if *month != "" || *future != "" {
+		log, spec := app.LogMonthly, *month
+		if *future != "" {
+			log, spec = app.LogFuture, *future
+		}
+		m, err := time.Parse("2006-01", spec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid month (want YYYY-MM)")
+			return 2
+		}
+		if _, err := a.AddToLog(log, m, b); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	if *parent != "" {
+		pe, err := a.LocateEntry(*parent)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if _, err := a.AddUnder(pe, b); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	if _, err := a.AddTo(d, b); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
@@ -243,26 +511,27 @@ func cliAdd(args []string) int {
 
 func cliDelete(args []string) int {
 	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
-	dateStr := fs.String("date", "", "YYYY-MM-DD (required)")
-	dataDir := fs.String("data-dir", "", "override data directory")
-	if err := fs.Parse(args); err != nil {
-		return 2
-	}
-	if fs.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "missing index")
+	dateStr := fs.String("date", "", "YYYY-MM-DD (only with a day index)")
+	dataDir := dataDirFlags(fs)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
 		return 2
 	}
-	if strings.TrimSpace(*dateStr) == "" {
-		fmt.Fprintln(os.Stderr, "--date is required for delete")
+	if len(pos) < 1 {
+		fmt.Fprintln(os.Stderr, "missing id or index")
 		return 2
 	}
-	idx := parseIndex(fs.Arg(0))
 	a, err := newAppWithContext("day", *dateStr, *dataDir)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
-	if err := a.DeleteDayIndex(parseDate(*dateStr), idx); err != nil {
+	ref, err := resolveTarget(a.Store, pos[0], *dateStr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := a.DeleteID(ref); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
@@ -271,26 +540,56 @@ func cliDelete(args []string) int {
 
 func cliComplete(args []string) int {
 	fs := flag.NewFlagSet("complete", flag.ContinueOnError)
-	dateStr := fs.String("date", "", "YYYY-MM-DD (required)")
-	dataDir := fs.String("data-dir", "", "override data directory")
-	if err := fs.Parse(args); err != nil {
+	dateStr := fs.String("date", "", "YYYY-MM-DD (only with a day index)")
+	dataDir := dataDirFlags(fs)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
 		return 2
 	}
-	if fs.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "missing index")
+	if len(pos) < 1 {
+		fmt.Fprintln(os.Stderr, "missing id or index")
+		return 2
+	}
+	a, err := newAppWithContext("day", *dateStr, *dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	ref, err := resolveTarget(a.Store, pos[0], *dateStr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := a.CompleteID(ref); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func cliCancel(args []string) int {
+	fs := flag.NewFlagSet("cancel", flag.ContinueOnError)
+	dateStr := fs.String("date", "", "YYYY-MM-DD (only with a day index)")
+	dataDir := dataDirFlags(fs)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
 		return 2
 	}
-	if strings.TrimSpace(*dateStr) == "" {
-		fmt.Fprintln(os.Stderr, "--date is required for complete")
+	if len(pos) < 1 {
+		fmt.Fprintln(os.Stderr, "missing id or index")
 		return 2
 	}
-	idx := parseIndex(fs.Arg(0))
 	a, err := newAppWithContext("day", *dateStr, *dataDir)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
-	if err := a.CompleteDayIndex(parseDate(*dateStr), idx); err != nil {
+	ref, err := resolveTarget(a.Store, pos[0], *dateStr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := a.CancelID(ref); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
@@ -299,26 +598,27 @@ func cliComplete(args []string) int {
 
 func cliMigrate(args []string) int {
 	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
-	dateStr := fs.String("date", "", "YYYY-MM-DD (required)")
-	dataDir := fs.String("data-dir", "", "override data directory")
-	if err := fs.Parse(args); err != nil {
-		return 2
-	}
-	if fs.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "missing index")
+	dateStr := fs.String("date", "", "YYYY-MM-DD (only with a day index)")
+	dataDir := dataDirFlags(fs)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
 		return 2
 	}
-	if strings.TrimSpace(*dateStr) == "" {
-		fmt.Fprintln(os.Stderr, "--date is required for migrate")
+	if len(pos) < 1 {
+		fmt.Fprintln(os.Stderr, "missing id or index")
 		return 2
 	}
-	idx := parseIndex(fs.Arg(0))
 	a, err := newAppWithContext("day", *dateStr, *dataDir)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
-	if err := a.MigrateDayIndex(parseDate(*dateStr), idx); err != nil {
+	ref, err := resolveTarget(a.Store, pos[0], *dateStr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := a.MigrateID(ref); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
@@ -327,36 +627,37 @@ func cliMigrate(args []string) int {
 
 func cliSchedule(args []string) int {
 	fs := flag.NewFlagSet("schedule", flag.ContinueOnError)
-	dateStr := fs.String("date", "", "YYYY-MM-DD (required)")
+	dateStr := fs.String("date", "", "YYYY-MM-DD (only with a day index)")
 	to := fs.String("to", "", "YYYY-MM-DD target date (required)")
-	dataDir := fs.String("data-dir", "", "override data directory")
-	if err := fs.Parse(args); err != nil {
+	dataDir := dataDirFlags(fs)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
 		return 2
 	}
-	if fs.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "missing index")
+	if len(pos) < 1 {
+		fmt.Fprintln(os.Stderr, "missing id or index")
 		return 2
 	}
 	if *to == "" {
 		fmt.Fprintln(os.Stderr, "--to is required")
 		return 2
 	}
-	if strings.TrimSpace(*dateStr) == "" {
-		fmt.Fprintln(os.Stderr, "--date is required for schedule")
-		return 2
-	}
 	target, err := time.Parse("2006-01-02", *to)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "invalid --to date")
 		return 2
 	}
-	idx := parseIndex(fs.Arg(0))
 	a, err := newAppWithContext("day", *dateStr, *dataDir)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
-	if err := a.ScheduleDayIndex(parseDate(*dateStr), idx, target); err != nil {
+	ref, err := resolveTarget(a.Store, pos[0], *dateStr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := a.ScheduleID(ref, target); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
@@ -365,47 +666,1514 @@ func cliSchedule(args []string) int {
 
 func cliEdit(args []string) int {
 	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
-	dateStr := fs.String("date", "", "YYYY-MM-DD (required)")
+	dateStr := fs.String("date", "", "YYYY-MM-DD (only with a day index)")
 	newText := fs.String("set", "", "new text (required)")
-	dataDir := fs.String("data-dir", "", "override data directory")
-	if err := fs.Parse(args); err != nil {
+	dataDir := dataDirFlags(fs)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
 		return 2
 	}
-	if fs.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "missing index")
+	if len(pos) < 1 {
+		fmt.Fprintln(os.Stderr, "missing id or index")
 		return 2
 	}
 	if strings.TrimSpace(*newText) == "" {
 		fmt.Fprintln(os.Stderr, "--set is required")
 		return 2
 	}
-	if strings.TrimSpace(*dateStr) == "" {
-		fmt.Fprintln(os.Stderr, "--date is required for edit")
+	a, err := newAppWithContext("day", *dateStr, *dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	ref, err := resolveTarget(a.Store, pos[0], *dateStr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := a.UpdateTextID(ref, *newText); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func cliUndo(args []string, redo bool) int {
+	name := "undo"
+	if redo {
+		name = "redo"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	if err := fs.Parse(args); err != nil {
 		return 2
 	}
-	idx := parseIndex(fs.Arg(0))
-	a, err := newAppWithContext("day", *dateStr, *dataDir)
+	_, st, err := openStore(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	a := app.New(st)
+	var label string
+	if redo {
+		label, err = a.Redo()
+	} else {
+		label, err = a.Undo()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if label == "" {
+		fmt.Println("nothing to " + name)
+		return 0
+	}
+	if redo {
+		fmt.Println("redid " + label)
+	} else {
+		fmt.Println("undid " + label)
+	}
+	return 0
+}
+
+func cliFuturePull(args []string) int {
+	fs := flag.NewFlagSet("future-pull", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	monthStr := fs.String("month", "", "YYYY-MM (defaults to this month)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	month := time.Now()
+	if *monthStr != "" {
+		m, err := time.Parse("2006-01", *monthStr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid --month (want YYYY-MM)")
+			return 2
+		}
+		month = m
+	}
+	_, st, err := openStore(*dataDir)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
-	if err := a.UpdateDayIndexText(parseDate(*dateStr), idx, *newText); err != nil {
+	n, err := app.New(st).PullFuture(month)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
+	fmt.Printf("pulled %d item(s) into %s\n", n, month.Format("2006-01"))
 	return 0
 }
 
-func parseIndex(s string) int {
-	// best-effort parse; on failure, return -1 which will no-op in app methods
-	var i int
-	_, _ = fmt.Sscanf(s, "%d", &i)
-	return i
+func cliCollection(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: blt collection list|show|create|rename|delete|add|move|pull")
+		return 2
+	}
+	fs := flag.NewFlagSet("collection "+args[0], flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	open := func() (*app.App, bool) {
+		_, st, err := openStore(*dataDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, false
+		}
+		return app.New(st), true
+	}
+	fail := func(err error) int {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	switch args[0] {
+	case "list", "ls":
+		jsonOut := fs.Bool("json", false, "output JSON")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		cols, err := a.Collections()
+		if err != nil {
+			return fail(err)
+		}
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(cols)
+			return 0
+		}
+		for _, c := range cols {
+			fmt.Printf("%s  (%d items, %d open)\n", c.Name, c.Count, c.Open)
+		}
+		return 0
+	case "show":
+		jsonOut := fs.Bool("json", false, "output JSON")
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: blt collection show <name> [--json]")
+			return 2
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		if err := a.OpenCollection(pos[0]); err != nil {
+			return fail(err)
+		}
+		vis := a.Visible()
+		if *jsonOut {
+			type J struct {
+				ID       string
+				Type     string
+				Text     string
+				Tags     []string
+				ParentID string `json:",omitempty"`
+				Depth    int
+				OriginID string `json:",omitempty"`
+			}
+			out := make([]J, 0, len(vis))
+			for _, e := range vis {
+				out = append(out, J{ID: e.Item.ID, Type: string(e.Item.Type), Text: e.Item.Text, Tags: e.Item.Tags, ParentID: e.Item.ParentID, Depth: e.Depth, OriginID: e.Item.OriginID})
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(out)
+			return 0
+		}
+		notes := a.LineageSummaries(vis)
+		for _, e := range vis {
+			label := strings.Repeat("  ", e.Depth) + formatBullet(e.Item)
+			if note := notes[e.Item.ID]; note != "" {
+				label += "  (" + note + ")"
+			}
+			fmt.Printf("%s  %s\n", e.Item.ID, label)
+		}
+		return 0
+	case "create", "new":
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: blt collection create <name>")
+			return 2
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		if err := a.CreateCollection(pos[0]); err != nil {
+			return fail(err)
+		}
+		return 0
+	case "rename", "mv":
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: blt collection rename <old> <new>")
+			return 2
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		if err := a.RenameCollection(pos[0], pos[1]); err != nil {
+			return fail(err)
+		}
+		return 0
+	case "delete", "rm":
+		force := fs.Bool("force", false, "delete even if the collection has items")
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) != 1 {
+			fmt.Fprintln(os.Stderr, "usage: blt collection delete <name> [--force]")
+			return 2
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		if !*force {
+			items, err := a.LoadLog(app.CollectionLog(pos[0]), time.Time{})
+			if err != nil {
+				return fail(err)
+			}
+			if len(items) > 0 {
+				fmt.Fprintf(os.Stderr, "collection %q has %d items; use --force to delete it\n", pos[0], len(items))
+				return 1
+			}
+		}
+		if err := a.DeleteCollection(pos[0]); err != nil {
+			return fail(err)
+		}
+		return 0
+	case "add":
+		text := fs.String("text", "", "bullet text")
+		typ := fs.String("type", "task", "task|event|note|important|inspiration")
+		tags := fs.String("tags", "", "comma-separated tags")
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) != 1 || strings.TrimSpace(*text) == "" {
+			fmt.Fprintln(os.Stderr, "usage: blt collection add <name> --text \"...\" [--type ...] [--tags ...]")
+			return 2
+		}
+		types := parseTypesCSV(*typ)
+		if len(types) != 1 {
+			fmt.Fprintln(os.Stderr, "invalid --type")
+			return 2
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		b, err := a.AddToCollection(pos[0], model.Bullet{Type: types[0], Text: strings.TrimSpace(*text), Tags: parseTagsCSV(*tags), CreatedAt: time.Now()})
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Println(b.ID)
+		return 0
+	case "move":
+		dateStr := fs.String("date", "", "YYYY-MM-DD (only with a day index)")
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: blt collection move <id> <name>")
+			return 2
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		ref, err := resolveTarget(a.Store, pos[0], *dateStr)
+		if err != nil {
+			return fail(err)
+		}
+		if err := a.MoveIDToCollection(ref, pos[1]); err != nil {
+			return fail(err)
+		}
+		return 0
+	case "pull":
+		to := fs.String("to", "", "YYYY-MM-DD day to move the item into (defaults to today)")
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: blt collection pull <name> <id> [--to YYYY-MM-DD]")
+			return 2
+		}
+		d := time.Now()
+		if *to != "" {
+			if d, err = time.Parse("2006-01-02", *to); err != nil {
+				fmt.Fprintln(os.Stderr, "invalid --to date")
+				return 2
+			}
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		if err := a.MoveFromCollection(pos[0], pos[1], d); err != nil {
+			return fail(err)
+		}
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown collection command %q (list|show|create|rename|delete|add|move|pull)\n", args[0])
+		return 2
+	}
 }
 
-func parseDate(s string) time.Time {
-	d, _ := time.Parse("2006-01-02", s)
-	return d
+func cliIndex(args []string) int {
+	fs := flag.NewFlagSet("index", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	jsonOut := fs.Bool("json", false, "output JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	_, st, err := openStore(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	idx, err := app.New(st).BuildIndex()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *jsonOut {
+		type T struct {
+			Tag   string
+			Count int
+			First string
+			Last  string
+		}
+		type M struct {
+			Month     string
+			Days      int
+			Total     int
+			Open      int
+			Done      int
+			Migrated  int
+			Cancelled int
+			Events    int
+		}
+		out := struct {
+			Collections []app.CollectionInfo
+			Tags        []T
+			Months      []M
+		}{Collections: idx.Collections, Tags: []T{}, Months: []M{}}
+		if out.Collections == nil {
+			out.Collections = []app.CollectionInfo{}
+		}
+		for _, t := range idx.Tags {
+			out.Tags = append(out.Tags, T{Tag: t.Tag, Count: t.Count, First: t.First.Format("2006-01-02"), Last: t.Last.Format("2006-01-02")})
+		}
+		for _, m := range idx.Months {
+			out.Months = append(out.Months, M{Month: m.Month.Format("2006-01"), Days: m.Days, Total: m.Total, Open: m.Open, Done: m.Done, Migrated: m.Migrated, Cancelled: m.Cancelled, Events: m.Events})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(out)
+		return 0
+	}
+	fmt.Println("Collections")
+	for _, c := range idx.Collections {
+		fmt.Printf("  %s  (%d items, %d open)\n", c.Name, c.Count, c.Open)
+	}
+	fmt.Println("Tags")
+	for _, t := range idx.Tags {
+		fmt.Printf("  #%s  %d  %s → %s\n", t.Tag, t.Count, t.First.Format("2006-01-02"), t.Last.Format("2006-01-02"))
+	}
+	fmt.Println("Months")
+	for _, m := range idx.Months {
+		fmt.Printf("  %s  %d days, %d items: %d open, %d done, %d migrated, %d cancelled, %d events\n", m.Month.Format("2006-01"), m.Days, m.Total, m.Open, m.Done, m.Migrated, m.Cancelled, m.Events)
+	}
+	return 0
+}
+
+// cliReview walks the open tasks of a month, reading one decision per item
+// from in, then writes a summary note to today's log.
+func cliReview(args []string, in io.Reader) int {
+	fs := flag.NewFlagSet("review", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	monthStr := fs.String("month", "", "YYYY-MM to review (defaults to this month)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	month := time.Now()
+	if *monthStr != "" {
+		m, err := time.ParseInLocation("2006-01", *monthStr, time.Local)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid --month (want YYYY-MM)")
+			return 2
+		}
+		month = m
+	}
+	_, st, err := openStore(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	a := app.New(st)
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.Local)
+	entries, err := a.ReviewItems(model.DateRange{Start: start, End: start.AddDate(0, 1, -1)})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(entries) == 0 {
+		fmt.Println("nothing open to review")
+		return 0
+	}
+	title := start.Format("January 2006")
+	sc := bufio.NewScanner(in)
+	ask := func(prompt string) (string, bool) {
+		fmt.Print(prompt)
+		if !sc.Scan() {
+			fmt.Println()
+			return "", false
+		}
+		return strings.TrimSpace(sc.Text()), true
+	}
+	counts := app.ReviewCounts{}
+	decided := 0
+review:
+	for i, e := range entries {
+		fmt.Printf("\n[%d/%d] %s  %s\n", i+1, len(entries), e.Date.Format("Mon Jan 2"), formatBullet(e.Item))
+		for {
+			answer, ok := ask("[m] next day  [s] schedule  [f] future log  [x] irrelevant  [c] done  [n] skip  [q] quit > ")
+			if !ok {
+				break review
+			}
+			var decision app.ReviewDecision
+			var target time.Time
+			switch strings.ToLower(answer) {
+			case "m":
+				decision = app.ReviewMigrate
+			case "s":
+				ans, ok := ask("schedule for (YYYY-MM-DD): ")
+				if !ok {
+					break review
+				}
+				if target, err = time.ParseInLocation("2006-01-02", ans, time.Local); err != nil {
+					fmt.Println("invalid date")
+					continue
+				}
+				decision = app.ReviewSchedule
+			case "f":
+				def := start.AddDate(0, 1, 0).Format("2006-01")
+				ans, ok := ask("future log month (YYYY-MM) [" + def + "]: ")
+				if !ok {
+					break review
+				}
+				if ans == "" {
+					ans = def
+				}
+				if target, err = time.ParseInLocation("2006-01", ans, time.Local); err != nil {
+					fmt.Println("invalid month")
+					continue
+				}
+				decision = app.ReviewFuture
+			case "x":
+				decision = app.ReviewCancel
+			case "c":
+				decision = app.ReviewDone
+			case "n", "":
+				decision = app.ReviewSkip
+			case "q":
+				break review
+			default:
+				continue
+			}
+			if err := a.Review(e, decision, target); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			counts[decision]++
+			decided++
+			break
+		}
+	}
+	if decided == 0 {
+		return 0
+	}
+	counts[app.ReviewSkip] += len(entries) - decided
+	text, err := a.FinishReview(title, counts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(text)
+	return 0
+}
+
+func cliSearch(args []string) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	types := fs.String("type", "", "comma-separated types")
+	tags := fs.String("tags", "", "comma-separated tags")
+	from := fs.String("from", "", "YYYY-MM-DD earliest day")
+	to := fs.String("to", "", "YYYY-MM-DD latest day")
+	jsonOut := fs.Bool("json", false, "output JSON")
+	all := fs.Bool("all-journals", false, "search every journal")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return 2
+	}
+	q := store.Query{Text: strings.Join(pos, " "), Types: parseTypesCSV(*types), Tags: parseTagsCSV(*tags)}
+	for _, f := range []struct {
+		val string
+		dst *time.Time
+	}{{*from, &q.Start}, {*to, &q.End}} {
+		if f.val == "" {
+			continue
+		}
+		d, err := time.ParseInLocation("2006-01-02", f.val, time.Local)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid date %q\n", f.val)
+			return 2
+		}
+		*f.dst = d
+	}
+	journals, err := listedJournals(*all, *dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	type J struct {
+		Journal string `json:",omitempty"`
+		Date    string
+		ID      string
+		Type    string
+		Text    string
+		Tags    []string
+	}
+	out := []J{}
+	for _, jn := range journals {
+		_, st, err := openStore(jn.Dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		entries, err := app.New(st).Search(q)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, e := range entries {
+			if *jsonOut {
+				out = append(out, J{Journal: jn.Name, Date: e.Date.Format("2006-01-02"), ID: e.Item.ID, Type: string(e.Item.Type), Text: e.Item.Text, Tags: e.Item.Tags})
+				continue
+			}
+			if *all {
+				fmt.Printf("%s: ", jn.Name)
+			}
+			fmt.Printf("%s %s  %s\n", e.Date.Format("2006-01-02"), e.Item.ID, formatBullet(e.Item))
+		}
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(out)
+	}
+	return 0
+}
+
+func cliReindex(args []string) int {
+	fs := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	_, st, err := openStore(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := app.New(st).Reindex(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func cliDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	fix := fs.Bool("fix", false, "repair the problems found")
+	jsonOut := fs.Bool("json", false, "output JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	st, data, err := openStore(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	problems, err := st.Check(*fix, store.KeyOf(data))
+	if err == nil && *fix && len(problems) > 0 {
+		err = st.Commit(fmt.Sprintf("doctor: fix %d problem(s)", len(problems)))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *jsonOut {
+		if problems == nil {
+			problems = []store.Problem{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(problems)
+	} else {
+		for _, p := range problems {
+			loc := p.Path
+			if p.Line > 0 {
+				loc += ":" + strconv.Itoa(p.Line)
+			}
+			fixed := ""
+			if p.Fixed {
+				fixed = " (fixed)"
+			}
+			fmt.Printf("%s: %s: %s%s\n", loc, p.Kind, p.Detail, fixed)
+		}
+		switch {
+		case len(problems) == 0:
+			fmt.Println("No problems found.")
+		case *fix:
+			fmt.Printf("Fixed %d problem(s).\n", len(problems))
+		default:
+			fmt.Printf("%d problem(s) found; run `blt doctor --fix` to repair them.\n", len(problems))
+		}
+	}
+	if len(problems) > 0 && !*fix {
+		return 1
+	}
+	return 0
+}
+
+func cliMigrateData(args []string) int {
+	fs := flag.NewFlagSet("migrate-data", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "show the changes without writing them")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dir, err := resolveDir(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// Open without the automatic upgrade so it can be previewed and reported.
+	// `blt encrypt` upgrades data before sealing it, so the upgrades to date
+	// have nothing to do in an encrypted directory and need no passphrase.
+	st, err := store.OpenFSStore(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	rep, err := st.UpgradeSchema(*dryRun)
+	if err == nil && !*dryRun && len(rep.Steps) > 0 {
+		err = st.Commit(fmt.Sprintf("migrate data to schema version %d", rep.To))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(rep.Steps) == 0 {
+		fmt.Printf("Data is at schema version %d; nothing to migrate.\n", rep.To)
+		return 0
+	}
+	fmt.Printf("Schema version %d -> %d\n", rep.From, rep.To)
+	for _, step := range rep.Steps {
+		fmt.Printf("  v%d -> v%d: %s (%d change(s))\n", step.From, step.To, step.Description, len(step.Changes))
+		for _, c := range step.Changes {
+			fmt.Println("    " + c)
+		}
+	}
+	if *dryRun {
+		fmt.Println("Dry run: nothing was changed.")
+	} else {
+		fmt.Println("Backup:", filepath.Join(dir, rep.Backup))
+	}
+	return 0
+}
+
+func cliEncrypt(args []string) int {
+	fs := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	keyfile := fs.String("keyfile", "", "read the passphrase from the first line of this file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dir, err := resolveDir(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	st, err := store.OpenFSStore(dir)
+	if err == nil && !st.Encrypted() {
+		// Bring plain data up to date before sealing it.
+		st, err = store.NewFSStore(dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	pass, err := passphrase(*keyfile, !st.Encrypted())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	n, backups, err := st.Encrypt(pass)
+	if err == nil {
+		err = st.Commit("encrypt journal")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Encrypted %d file(s) in %s.\n", n, dir)
+	if backups > 0 {
+		fmt.Printf("Encrypted %d archive(s) in backups/ as well.\n", backups)
+	}
+	if st.GitEnabled() {
+		fmt.Println("Note: earlier git commits still hold the plain files.")
+	}
+	return 0
+}
+
+func cliDecrypt(args []string) int {
+	fs := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	keyfile := fs.String("keyfile", "", "read the passphrase from the first line of this file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dir, err := resolveDir(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	st, err := store.OpenFSStore(dir)
+	if err == nil && !st.Encrypted() {
+		err = fmt.Errorf("%w: %s", store.ErrNotEncrypted, dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var key *store.Key
+	pass, err := passphrase(*keyfile, false)
+	if err == nil {
+		key, err = st.Unlock(pass)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	n, err := st.Decrypt(key)
+	if err == nil {
+		err = st.Commit("decrypt journal")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Decrypted %d file(s) in %s.\n", n, dir)
+	if backups, _ := st.Backups(); len(backups) > 0 {
+		fmt.Printf("Note: the %d archive(s) in backups/ stay encrypted; restoring one encrypts the journal again.\n", len(backups))
+	}
+	return 0
+}
+
+func cliTrash(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: blt trash list|restore|empty|retention")
+		return 2
+	}
+	fs := flag.NewFlagSet("trash "+args[0], flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	open := func() (*app.App, bool) {
+		_, st, err := openStore(*dataDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, false
+		}
+		return app.New(st), true
+	}
+	fail := func(err error) int {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	switch args[0] {
+	case "list", "ls":
+		jsonOut := fs.Bool("json", false, "output JSON")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		if _, err := a.PurgeTrash(time.Now()); err != nil {
+			return fail(err)
+		}
+		items, err := a.Trash()
+		if err != nil {
+			return fail(err)
+		}
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(items)
+			return 0
+		}
+		if len(items) == 0 {
+			fmt.Println("Trash is empty.")
+		}
+		now := time.Now()
+		for _, b := range items {
+			when := ""
+			if b.Deleted != nil {
+				when = ", deleted " + app.Ago(b.Deleted.At, now)
+			}
+			fmt.Printf("%s  %s  (%s%s)\n", b.ID, formatBullet(b), app.DeletedFrom(b), when)
+		}
+		return 0
+	case "restore":
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) < 1 {
+			fmt.Fprintln(os.Stderr, "missing id")
+			return 2
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		b, err := a.Restore(pos[0])
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("Restored %q to %s.\n", b.Text, app.DeletedFrom(b))
+		return 0
+	case "empty":
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		a, ok := open()
+		if !ok {
+			return 1
+		}
+		n, err := a.EmptyTrash()
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("Deleted %d bullet(s) for good.\n", n)
+		return 0
+	case "retention":
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if _, err := resolveDir(*dataDir); err != nil {
+			return fail(err)
+		}
+		if len(pos) > 0 {
+			days, err := strconv.Atoi(pos[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "retention must be a number of days (0 keeps deleted bullets until `blt trash empty`)")
+				return 2
+			}
+			if err := app.SetTrashDays(days); err != nil {
+				return fail(err)
+			}
+		}
+		if days := app.TrashDays(); days > 0 {
+			fmt.Printf("Deleted bullets are purged after %d days.\n", days)
+		} else {
+			fmt.Println("Deleted bullets are kept until the trash is emptied.")
+		}
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown trash command %q (list|restore|empty|retention)\n", args[0])
+		return 2
+	}
+}
+
+func cliJournal(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: blt journal list|add|remove|default")
+		return 2
+	}
+	fs := flag.NewFlagSet("journal "+args[0], flag.ContinueOnError)
+	fail := func(err error) int {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	switch args[0] {
+	case "list", "ls":
+		jsonOut := fs.Bool("json", false, "output JSON")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		journals, err := store.Journals()
+		if err != nil {
+			return fail(err)
+		}
+		def := store.DefaultJournalName()
+		if *jsonOut {
+			type J struct {
+				store.Journal
+				Default bool `json:"default,omitempty"`
+			}
+			out := make([]J, 0, len(journals))
+			for _, j := range journals {
+				out = append(out, J{Journal: j, Default: j.Name == def})
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(out)
+			return 0
+		}
+		for _, j := range journals {
+			mark := " "
+			if j.Name == def {
+				mark = "*"
+			}
+			fmt.Printf("%s %-16s %s\n", mark, j.Name, j.Dir)
+		}
+		return 0
+	case "add":
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) < 2 {
+			fmt.Fprintln(os.Stderr, "usage: blt journal add <name> <dir>")
+			return 2
+		}
+		j, err := store.AddJournal(pos[0], pos[1])
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("Added journal %q at %s; use it with --journal %s.\n", j.Name, j.Dir, j.Name)
+		return 0
+	case "remove", "rm":
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) < 1 {
+			fmt.Fprintln(os.Stderr, "missing journal name")
+			return 2
+		}
+		j, err := store.RemoveJournal(pos[0])
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("Removed journal %q; its data is still in %s.\n", j.Name, j.Dir)
+		return 0
+	case "default":
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) > 0 {
+			if err := store.SetDefaultJournal(pos[0]); err != nil {
+				return fail(err)
+			}
+		}
+		fmt.Printf("Default journal: %s\n", store.DefaultJournalName())
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown journal command %q (list|add|remove|default)\n", args[0])
+		return 2
+	}
+}
+
+func cliBackup(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: blt backup create|list|restore|retention")
+		return 2
+	}
+	fs := flag.NewFlagSet("backup "+args[0], flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	fail := func(err error) int {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	switch args[0] {
+	case "create":
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		st, _, err := openStore(*dataDir)
+		if err != nil {
+			return fail(err)
+		}
+		b, err := st.Snapshot(time.Now())
+		if err != nil {
+			return fail(err)
+		}
+		if keep := app.BackupKeep(); keep > 0 {
+			if _, err := st.PruneSnapshots(keep); err != nil {
+				return fail(err)
+			}
+		}
+		fmt.Printf("Backed up %s to %s (%s).\n", st.Root(), b.Path, byteSize(b.Size))
+		return 0
+	case "list", "ls":
+		jsonOut := fs.Bool("json", false, "output JSON")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		st, _, err := openStore(*dataDir)
+		if err != nil {
+			return fail(err)
+		}
+		backups, err := st.Backups()
+		if err != nil {
+			return fail(err)
+		}
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(backups)
+			return 0
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups.")
+		}
+		for _, b := range backups {
+			fmt.Printf("%-32s  %s  %8s\n", b.Name(), b.Time.Format("2006-01-02 15:04"), byteSize(b.Size))
+		}
+		return 0
+	case "restore":
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) < 1 {
+			fmt.Fprintln(os.Stderr, "missing backup name (see `blt backup list`)")
+			return 2
+		}
+		st, _, err := openStore(*dataDir)
+		if err != nil {
+			return fail(err)
+		}
+		archive := pos[0]
+		if b, err := st.FindBackup(pos[0]); err == nil {
+			archive = b.Path
+		} else if _, serr := os.Stat(pos[0]); serr != nil {
+			return fail(err)
+		}
+		safety, err := st.RestoreBackup(archive, time.Now())
+		if err == nil {
+			err = st.Commit("restore backup " + filepath.Base(archive))
+		}
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("Restored %s from %s; the previous state is in %s.\n", st.Root(), archive, safety.Path)
+		return 0
+	case "retention":
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if _, err := resolveDir(*dataDir); err != nil {
+			return fail(err)
+		}
+		if len(pos) > 0 {
+			n, err := strconv.Atoi(pos[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "retention must be a number of snapshots (0 turns daily snapshots off)")
+				return 2
+			}
+			if err := app.SetBackupKeep(n); err != nil {
+				return fail(err)
+			}
+		}
+		if keep := app.BackupKeep(); keep > 0 {
+			fmt.Printf("A snapshot is taken once a day on startup; the newest %d are kept.\n", keep)
+		} else {
+			fmt.Println("Daily snapshots are off.")
+		}
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown backup command %q (create|list|restore|retention)\n", args[0])
+		return 2
+	}
+}
+
+// byteSize formats n bytes as e.g. "12.3 KB".
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+func cliGit(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: blt git enable [--remote URL] | disable | status")
+		return 2
+	}
+	fs := flag.NewFlagSet("git "+args[0], flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	remote := fs.String("remote", "", "URL of the remote to sync with (enable)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	st, _, err := openStore(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	switch args[0] {
+	case "enable":
+		err = st.EnableGit(*remote, mergeDriver())
+		if err == nil {
+			fmt.Printf("Git history enabled in %s; every change is committed.\n", st.Root())
+		}
+	case "disable":
+		err = st.DisableGit()
+		if err == nil {
+			fmt.Println("Git history disabled; the repository is left in place.")
+		}
+	case "status":
+		if st.GitEnabled() {
+			fmt.Println("enabled")
+		} else {
+			fmt.Println("disabled")
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown git command %q (enable|disable|status)\n", args[0])
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// mergeDriver is the git merge driver command line that runs this binary's
+// `blt merge` on bullet files.
+func mergeDriver() string {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "blt"
+	}
+	return "'" + strings.ReplaceAll(exe, "'", `'\''`) + "' merge --data-dir . %O %A %B"
+}
+
+func cliMerge(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(pos) != 0 && len(pos) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: blt merge [<base> <ours> <theirs>]")
+		return 2
+	}
+	dir, err := resolveDir(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(pos) == 3 {
+		// Run as a git merge driver: the store may be locked by `blt sync`,
+		// so open it without upgrading.
+		st, err := store.OpenFSStore(dir)
+		var key *store.Key
+		if err == nil && st.Encrypted() {
+			var pass string
+			if pass, err = passphrase("", false); err == nil {
+				key, err = st.Unlock(pass)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		both, err := st.MergeFiles(pos[0], pos[1], pos[2], pos[1], key)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if both > 0 {
+			fmt.Fprintf(os.Stderr, "blt merge: %d bullet(s) changed on both sides; kept the later change\n", both)
+		}
+		return 0
+	}
+	st, data, err := openStore(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	done, err := st.ResolveConflicts(store.KeyOf(data))
+	for _, c := range done {
+		fmt.Printf("Merged %s into %s.\n", c.Path, c.Original)
+	}
+	if err == nil && len(done) > 0 {
+		err = st.Commit(fmt.Sprintf("merge %d sync conflict copies", len(done)))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(done) == 0 {
+		fmt.Println("No conflict copies found.")
+	}
+	return 0
+}
+
+func cliShow(args []string) int {
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	jsonOut := fs.Bool("json", false, "output JSON")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(pos) < 1 {
+		fmt.Fprintln(os.Stderr, "missing id")
+		return 2
+	}
+	a, err := newAppWithContext("day", "", *dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	e, err := a.LocateEntry(pos[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(e.Item)
+		return 0
+	}
+	for _, line := range a.Details(e, time.Now()) {
+		fmt.Println(line)
+	}
+	return 0
+}
+
+func cliHistory(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	jsonOut := fs.Bool("json", false, "output JSON")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(pos) < 1 {
+		fmt.Fprintln(os.Stderr, "missing id")
+		return 2
+	}
+	st, data, err := openStore(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	revs, err := st.History(pos[0], store.KeyOf(data))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(revs)
+		return 0
+	}
+	for _, r := range revs {
+		fmt.Printf("%s  %.7s  %s\n", r.At.Format("2006-01-02 15:04"), r.Commit, r.Message)
+		if r.Bullet == nil {
+			fmt.Printf("    (removed from %s)\n", r.File)
+			continue
+		}
+		fmt.Printf("    %s\n", formatBullet(*r.Bullet))
+	}
+	return 0
+}
+
+func cliSync(args []string) int {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	remote := fs.String("remote", store.DefaultRemote, "git remote to sync with")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	st, _, err := openStore(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	res, err := st.Sync(*remote)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Synced with %s: pulled %d, pushed %d commit(s).\n", *remote, res.Pulled, res.Pushed)
+	return 0
+}
+
+func cliRecur(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: blt recur add|list|rm")
+		return 2
+	}
+	fs := flag.NewFlagSet("recur "+args[0], flag.ContinueOnError)
+	dataDir := dataDirFlags(fs)
+	switch args[0] {
+	case "add":
+		rule := fs.String("rule", "", "daily | weekdays | weekly mon,thu | monthly 15 | monthly 2nd tue | monthly last fri | after N")
+		text := fs.String("text", "", "bullet text")
+		typ := fs.String("type", "task", "task|event|note|important|inspiration")
+		tags := fs.String("tags", "", "comma-separated tags")
+		start := fs.String("start", "", "YYYY-MM-DD first possible day (defaults to today)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if strings.TrimSpace(*text) == "" {
+			fmt.Fprintln(os.Stderr, "missing --text")
+			return 2
+		}
+		r, err := model.ParseRule(*rule)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		types := parseTypesCSV(*typ)
+		if len(types) != 1 {
+			fmt.Fprintln(os.Stderr, "invalid --type")
+			return 2
+		}
+		r.Type = types[0]
+		r.Text = strings.TrimSpace(*text)
+		r.Tags = parseTagsCSV(*tags)
+		if *start != "" {
+			d, err := time.Parse("2006-01-02", *start)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "invalid --start date")
+				return 2
+			}
+			r.Start = d
+		}
+		_, st, err := openStore(*dataDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		r, err = app.New(st).AddRecurrence(r)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(r.ID)
+		return 0
+	case "list":
+		jsonOut := fs.Bool("json", false, "output JSON")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		_, st, err := openStore(*dataDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		rs, err := app.New(st).Recurrences()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if *jsonOut {
+			type J struct {
+				ID    string
+				Rule  string
+				Type  string
+				Text  string
+				Tags  []string
+				Start string
+			}
+			out := make([]J, 0, len(rs))
+			for _, r := range rs {
+				out = append(out, J{ID: r.ID, Rule: r.Rule(), Type: string(r.Type), Text: r.Text, Tags: r.Tags, Start: r.Start.Format("2006-01-02")})
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(out)
+			return 0
+		}
+		for _, r := range rs {
+			fmt.Printf("%s  %-18s %s\n", r.ID, r.Rule(), formatBullet(model.Bullet{Type: r.Type, Text: r.Text, Tags: r.Tags}))
+		}
+		return 0
+	case "rm", "remove", "delete":
+		pos, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return 2
+		}
+		if len(pos) < 1 {
+			fmt.Fprintln(os.Stderr, "missing recurrence id")
+			return 2
+		}
+		_, st, err := openStore(*dataDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if err := app.New(st).RemoveRecurrence(pos[0]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown recur command %q (add|list|rm)\n", args[0])
+		return 2
+	}
+}
+
+// parseFlags parses args allowing flags both before and after positional
+// arguments (e.g. `blt complete <id> --data-dir PATH`) and returns the
+// positional arguments in order.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// maxIndexDigits is the longest number read as a day-local index. Longer
+// numbers are ID prefixes: IDs start with a 19-digit timestamp, so useful
+// prefixes are all digits.
+const maxIndexDigits = 6
+
+// resolveTarget maps a command argument to a bullet ID or unique ID prefix.
+// A number of up to maxIndexDigits digits is a day-local index (legacy
+// form), which requires --date and is read from that day once; anything
+// else is passed on as an ID or prefix, and the owning log and day are
+// looked up by ID.
+func resolveTarget(st store.Store, ref, dateStr string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	idx, err := strconv.Atoi(ref)
+	if err != nil || len(ref) > maxIndexDigits {
+		return ref, nil
+	}
+	if strings.TrimSpace(dateStr) == "" {
+		return "", fmt.Errorf("index %s requires --date (or pass the bullet ID from `blt list --json`)", ref)
+	}
+	d, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid --date %q", dateStr)
+	}
+	items, err := st.LoadDay(d)
+	if err != nil {
+		return "", err
+	}
+	if idx < 0 || idx >= len(items) {
+		return "", fmt.Errorf("no bullet at index %d on %s", idx, dateStr)
+	}
+	return items[idx].ID, nil
 }
 
 func formatBullet(b model.Bullet) string {
@@ -417,6 +2185,8 @@ func formatBullet(b model.Bullet) string {
 		prefix = "»"
 	case model.Scheduled:
 		prefix = "⧗"
+	case model.Cancelled:
+		prefix = "✗"
 	case model.Event:
 		prefix = "◇"
 	case model.Note:
@@ -450,15 +2220,42 @@ func formatBullet(b model.Bullet) string {
 func printHelp() {
 	fmt.Println("blt CLI")
 	fmt.Println("\nUsage:")
-	fmt.Println("  blt list [--timespan day|week|month] [--date YYYY-MM-DD] [--type ...] [--tags ...] [--text ...] [--json]")
-	fmt.Println("  blt add [--type task|event|note|important|inspiration] --text \"...\" | --note \"...\"")
-	fmt.Println("  blt delete <index> --date YYYY-MM-DD [--data-dir PATH]")
-	fmt.Println("  blt complete <index> --date YYYY-MM-DD [--data-dir PATH]")
-	fmt.Println("  blt migrate  <index> --date YYYY-MM-DD [--data-dir PATH]")
-	fmt.Println("  blt schedule <index> --date YYYY-MM-DD --to YYYY-MM-DD [--data-dir PATH]")
-	fmt.Println("  blt edit     <index> --date YYYY-MM-DD --set \"new text\" [--data-dir PATH]")
+	fmt.Println("  blt list [--timespan day|week|month|monthlog|future] [--date YYYY-MM-DD] [--type ...] [--tags ...] [--text ...] [--sort manual|type|created|tag|open] [--json] [--all-journals]")
+	fmt.Println("  blt add [--type task|event|note|important|inspiration] [--parent ID | --month YYYY-MM | --future YYYY-MM] --text \"...\" | --note \"...\"")
+	fmt.Println("  blt delete   <id> | <index> --date YYYY-MM-DD [--data-dir PATH]")
+	fmt.Println("  blt complete <id> | <index> --date YYYY-MM-DD [--data-dir PATH]")
+	fmt.Println("  blt cancel   <id> | <index> --date YYYY-MM-DD [--data-dir PATH]   (toggle irrelevant)")
+	fmt.Println("  blt migrate  <id> | <index> --date YYYY-MM-DD [--data-dir PATH]")
+	fmt.Println("  blt schedule <id> | <index> --date YYYY-MM-DD --to YYYY-MM-DD [--data-dir PATH]")
+	fmt.Println("  blt edit     <id> | <index> --date YYYY-MM-DD --set \"new text\" [--data-dir PATH]")
+	fmt.Println("  blt recur add --rule RULE --text \"...\" [--type ...] [--tags ...] [--start YYYY-MM-DD]")
+	fmt.Println("  blt recur list [--json] | blt recur rm <id>")
+	fmt.Println("  blt future-pull [--month YYYY-MM]   migrate due future-log items into the monthly log")
+	fmt.Println("  blt collection list [--json] | show <name> [--json] | create <name> | rename <old> <new> | delete <name> [--force]")
+	fmt.Println("  blt collection add <name> --text \"...\" [--type ...] [--tags ...]")
+	fmt.Println("  blt collection move <id> <name> | pull <name> <id> [--to YYYY-MM-DD]")
+	fmt.Println("  blt review [--month YYYY-MM]   walk open tasks of the month and decide each (reads stdin)")
+	fmt.Println("  blt search [words...] [--type ...] [--tags ...] [--from YYYY-MM-DD] [--to YYYY-MM-DD] [--json] [--all-journals]")
+	fmt.Println("  blt reindex   rebuild the search index (index/) from the day files")
+	fmt.Println("  blt index [--json]   collections, tags (counts and date ranges) and month summaries")
+	fmt.Println("  blt encrypt | decrypt [--keyfile PATH]   encrypt the data dir at rest, or turn it back into plain JSONL")
+	fmt.Println("  blt trash list [--json] | restore <id> | empty | retention [DAYS]   deleted bullets (purged after 30 days by default)")
+	fmt.Println("  blt journal list [--json] | add <name> <dir> | remove <name> | default [<name>]   named journals, e.g. work and personal")
+	fmt.Println("  blt backup create | list [--json] | restore <name> | retention [N]   tar.gz snapshots in backups/ (one per day on startup, newest 7 kept)")
+	fmt.Println("  blt git enable [--remote URL] | disable | status   commit every change to a git repository in the data dir")
+	fmt.Println("  blt show <id> [--json]   show a bullet's details: timestamps (\"edited 2h ago\"), lineage and earlier versions")
+	fmt.Println("  blt history <id> [--json]   show a bullet's committed changes over time")
+	fmt.Println("  blt sync [--remote NAME]   commit, pull --rebase and push the data dir's repository")
+	fmt.Println("  blt merge [<base> <ours> <theirs>]   merge bullet files by ID (git merge driver), or fold in sync conflict copies")
+	fmt.Println("  blt migrate-data [--dry-run]   upgrade the data dir to the current schema (backs up to backups/ first)")
+	fmt.Println("  blt doctor [--fix] [--json]   check for unreadable lines, duplicate IDs, temp files, dangling links and sync conflict copies")
+	fmt.Println("  blt undo | redo [--data-dir PATH]")
 	fmt.Println("\nContext flags:")
-	fmt.Println("  --timespan day|week|month   --date YYYY-MM-DD   --type t1,t2   --tags tag1,tag2   --text query   --data-dir path")
+	fmt.Println("  --timespan day|week|month|monthlog|future   --date YYYY-MM-DD   --type t1,t2   --tags tag1,tag2   --text query   --data-dir path   --journal name")
+	fmt.Println("\nRecurrence rules:")
+	fmt.Println("  daily   weekdays   weekly mon,thu   monthly 15   monthly 2nd tue   monthly last fri   after N (days after completion)")
 	fmt.Println("\nNotes:")
-	fmt.Println("  Indexes shown by 'list' are absolute per day and unaffected by filters.")
+	fmt.Println("  <id> is a bullet ID from 'list --json' or any unique prefix of it; the owning day, log or collection is found automatically.")
+	fmt.Println("  Indexes shown by 'list' are absolute per day and unaffected by filters; they require --date. Numbers longer than 6 digits are ID prefixes.")
+	fmt.Println("  'blt --journal NAME' opens the TUI on that journal; without --journal or --data-dir, commands use BLT_DATA_DIR or the default journal.")
 }
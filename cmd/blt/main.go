@@ -1,8 +1,10 @@
 package main
 
 import (
-	"github.com/rdo34/blt/internal/ui"
+	"fmt"
 	"os"
+
+	"github.com/rdo34/blt/internal/ui"
 )
 
 func main() {
@@ -13,7 +15,11 @@ func main() {
 			return
 		}
 	}
-	if err := ui.New().Run(); err != nil {
-		panic(err)
+	// A leading --journal NAME picks the journal the TUI opens. Errors such
+	// as an unknown journal, a held lock or a wrong passphrase are reported
+	// like the CLI's.
+	if err := ui.NewJournal(journal).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
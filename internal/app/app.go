@@ -1,6 +1,7 @@
 package app
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
@@ -9,9 +10,14 @@ import (
 )
 
 // Entry represents a bullet and its owning date, enabling range views.
+// Log names the collection holding it (LogDaily for day files); Depth and
+// Children describe its place in the bullet tree.
 type Entry struct {
-	Date time.Time
-	Item model.Bullet
+	Date     time.Time
+	Item     model.Bullet
+	Log      string
+	Depth    int
+	Children int
 }
 
 // App holds application state and provides methods to load/update bullets.
@@ -19,6 +25,7 @@ type App struct {
 	Store       store.Store
 	CurrentDate time.Time
 	Period      model.Period
+	Collection  string // shown when Period is PeriodCollection
 
 	Items []Entry
 
@@ -26,36 +33,64 @@ type App struct {
 	TextFilter string
 	TypeFilter map[model.BulletType]bool
 	TagFilter  map[string]bool // normalized to no leading '#'
+
+	// Collapsed holds IDs of bullets whose children are hidden.
+	Collapsed map[string]bool
+
+	// Sort orders each day's bullets for display; see SortMode.
+	Sort SortMode
+
+	// Undo journal: operation being recorded, and the fallback log used
+	// when the store does not persist one.
+	pending *store.Op
+	log     store.OpLog
 }
 
 func New(s store.Store) *App {
-	return &App{Store: s, Period: model.PeriodDay, TypeFilter: map[model.BulletType]bool{}, TagFilter: map[string]bool{}}
+	return &App{Store: s, Period: model.PeriodDay, TypeFilter: map[model.BulletType]bool{}, TagFilter: map[string]bool{}, Collapsed: map[string]bool{}}
 }
 
 // LoadDay loads all bullets for a given date into state.
 func (a *App) LoadDay(date time.Time) error { a.CurrentDate = date; a.SavePrefs(); return a.Refresh() }
 
 // Refresh reloads items for the current period and date, applying no filters.
+// Recurring items due up to today are materialized first.
 func (a *App) Refresh() error {
+	switch a.Period {
+	case model.PeriodMonthLog, model.PeriodFuture, model.PeriodCollection:
+		return a.refreshLog()
+	}
 	rng := a.dateRange()
+	if err := a.materialize(); err != nil {
+		return err
+	}
+	days, err := a.Store.LoadRange(rng.Start, rng.End)
+	if err != nil {
+		return err
+	}
 	var entries []Entry
-	for d := rng.Start; !d.After(rng.End); d = d.Add(24 * time.Hour) {
-		items, err := a.Store.LoadDay(d)
-		if err != nil {
-			return err
-		}
-		for _, it := range items {
-			entries = append(entries, Entry{Date: dateOnly(d), Item: it})
-		}
+	for _, d := range days {
+		entries = append(entries, treeOrder(LogDaily, d.Date, d.Items)...)
 	}
-	a.Items = entries
+	a.Items = sortEntries(entries, a.Sort)
 	return nil
 }
 
-// Visible returns items matching current filters.
+// Visible returns items matching current filters, skipping the descendants
+// of collapsed bullets.
 func (a *App) Visible() []Entry {
 	out := make([]Entry, 0, len(a.Items))
+	hideBelow := -1
 	for _, e := range a.Items {
+		if hideBelow >= 0 {
+			if e.Depth > hideBelow {
+				continue
+			}
+			hideBelow = -1
+		}
+		if a.Collapsed[e.Item.ID] && e.Children > 0 {
+			hideBelow = e.Depth
+		}
 		if a.match(e) {
 			out = append(out, e)
 		}
@@ -103,8 +138,10 @@ func (a *App) shift(delta int) time.Time {
 	switch a.Period {
 	case model.PeriodWeek:
 		return a.CurrentDate.AddDate(0, 0, 7*delta)
-	case model.PeriodMonth:
+	case model.PeriodMonth, model.PeriodMonthLog:
 		return a.CurrentDate.AddDate(0, delta, 0)
+	case model.PeriodFuture, model.PeriodCollection:
+		return a.CurrentDate
 	default:
 		return a.CurrentDate.AddDate(0, 0, delta)
 	}
@@ -122,8 +159,8 @@ func (a *App) dateRange() model.DateRange {
 		start := d.AddDate(0, 0, -(wd - 1))
 		end := start.AddDate(0, 0, 6)
 		return model.DateRange{Start: start, End: end}
-	case model.PeriodMonth:
-		start := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, d.Location())
+	case model.PeriodMonth, model.PeriodMonthLog:
+		start := monthStart(d)
 		end := start.AddDate(0, 1, -1)
 		return model.DateRange{Start: start, End: end}
 	default:
@@ -136,19 +173,45 @@ func dateOnly(t time.Time) time.Time {
 	return time.Date(y, m, day, 0, 0, 0, 0, t.Location())
 }
 
-// Add creates a new default Task bullet for the current date.
+// Add creates a new default Task bullet for the current date, or in the
+// current month's log or open collection when viewing one.
 func (a *App) Add(text string) (model.Bullet, error) {
-	b := model.Bullet{Text: text, Type: model.Task, CreatedAt: time.Now()}
-	if err := a.Store.Append(a.CurrentDate, b); err != nil {
+	b := model.Bullet{ID: store.NewID(), Text: text, Type: model.Task, CreatedAt: time.Now()}
+	log, d := LogDaily, a.CurrentDate
+	switch a.Period {
+	case model.PeriodMonthLog:
+		log, d = LogMonthly, monthStart(a.CurrentDate)
+	case model.PeriodFuture:
+		log, d = LogFuture, monthStart(a.CurrentDate)
+		b.Month = d.Format("2006-01")
+	case model.PeriodCollection:
+		log, d = CollectionLog(a.Collection), time.Time{}
+	}
+	err := a.record(opLabel("add", b), func() error { return a.add(log, d, b) })
+	if err != nil {
 		return model.Bullet{}, err
 	}
-	// Reload range to pick up ID.
 	if err := a.Refresh(); err != nil {
 		return model.Bullet{}, err
 	}
 	return b, nil
 }
 
+// AddTo appends b to the given date as an undoable operation and returns it
+// with its assigned ID. Unlike Add it does not refresh the loaded range.
+func (a *App) AddTo(date time.Time, b model.Bullet) (model.Bullet, error) {
+	if b.ID == "" {
+		b.ID = store.NewID()
+	}
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = time.Now()
+	}
+	if err := a.record(opLabel("add", b), func() error { return a.add(LogDaily, dateOnly(date), b) }); err != nil {
+		return model.Bullet{}, err
+	}
+	return b, nil
+}
+
 // UpdateText updates the text of an item by index for the current date.
 func (a *App) UpdateText(index int, text string) error {
 	vis := a.Visible()
@@ -156,22 +219,20 @@ func (a *App) UpdateText(index int, text string) error {
 		return nil
 	}
 	e := vis[index]
-	it := e.Item
-	it.Text = text
-	if err := a.Store.Update(e.Date, it); err != nil {
+	if err := a.updateText(e.Log, e.Date, e.Item, text); err != nil {
 		return err
 	}
 	return a.Refresh()
 }
 
-// DeleteIndex removes an item by index from the current date.
+// DeleteIndex moves an item by index from the current date to the trash.
 func (a *App) DeleteIndex(index int) error {
 	vis := a.Visible()
 	if index < 0 || index >= len(vis) {
 		return nil
 	}
 	e := vis[index]
-	if err := a.Store.Delete(e.Date, e.Item.ID); err != nil {
+	if err := a.delete(e.Log, e.Date, e.Item); err != nil {
 		return err
 	}
 	return a.Refresh()
@@ -184,23 +245,20 @@ func (a *App) CompleteIndex(index int) error {
 		return nil
 	}
 	e := vis[index]
-	it := e.Item
-	// Do not allow completing migrated or scheduled items
-	if it.Type == model.Migrated || it.Type == model.Scheduled {
-		return nil
+	if err := a.complete(e.Log, e.Date, e.Item); err != nil {
+		return err
 	}
-	// Toggle: Task <-> Done; ignore other types
-	if it.Type == model.Task {
-		now := time.Now()
-		it.Type = model.Done
-		it.CompletedAt = &now
-	} else if it.Type == model.Done {
-		it.Type = model.Task
-		it.CompletedAt = nil
-	} else {
+	return a.Refresh()
+}
+
+// CancelIndex toggles a Task to Cancelled (irrelevant) and back.
+func (a *App) CancelIndex(index int) error {
+	vis := a.Visible()
+	if index < 0 || index >= len(vis) {
 		return nil
 	}
-	if err := a.Store.Update(e.Date, it); err != nil {
+	e := vis[index]
+	if err := a.cancel(e.Log, e.Date, e.Item); err != nil {
 		return err
 	}
 	return a.Refresh()
@@ -213,139 +271,427 @@ func (a *App) MigrateIndex(index int) error {
 		return nil
 	}
 	e := vis[index]
-	orig := e.Item
-	// If already migrated, undo the migration.
-	if orig.Type == model.Migrated {
-		// Determine target date: prefer ScheduledFor if set, else next day
-		target := e.Date.Add(24 * time.Hour)
-		if orig.ScheduledFor != nil {
-			target = dateOnly(*orig.ScheduledFor)
+	if err := a.migrate(e.Log, e.Date, e.Item); err != nil {
+		return err
+	}
+	return a.Refresh()
+}
+
+// ScheduleIndex moves the item to a specific date and marks as scheduled.
+func (a *App) ScheduleIndex(index int, date time.Time) error {
+	vis := a.Visible()
+	if index < 0 || index >= len(vis) {
+		return nil
+	}
+	e := vis[index]
+	if err := a.schedule(e.Log, e.Date, e.Item, date); err != nil {
+		return err
+	}
+	return a.Refresh()
+}
+
+func (a *App) updateText(log string, d time.Time, it model.Bullet, text string) error {
+	label := opLabel("edit", it)
+	it.Text = text
+	return a.record(label, func() error { return a.put(log, d, it) })
+}
+
+// delete moves it together with all of its descendants to the trash, or
+// removes them when the store keeps no trash, then purges expired trash.
+func (a *App) delete(log string, d time.Time, it model.Bullet) error {
+	_, trash := a.Store.(store.TrashStore)
+	err := a.record(opLabel("delete", it), func() error {
+		items, err := a.loadLog(log, d)
+		if err != nil {
+			return err
 		}
-		// Find the migrated clone on target day and delete it, restoring original type
-		items, err := a.Store.LoadDay(target)
+		sub := subtree(items, it)
+		now := time.Now()
+		// Children first so a partial failure never leaves orphans behind.
+		for i := len(sub) - 1; i >= 0; i-- {
+			if trash {
+				if err := a.add(LogTrash, time.Time{}, trashed(sub[i], log, d, now)); err != nil {
+					return err
+				}
+			}
+			if err := a.remove(log, d, sub[i].ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || !trash {
+		return err
+	}
+	_, err = a.PurgeTrash(time.Now())
+	return err
+}
+
+// complete toggles Task <-> Done; other types are ignored. Completing a task
+// also completes its open sub-tasks; reopening affects only the task itself.
+func (a *App) complete(log string, d time.Time, it model.Bullet) error {
+	label := opLabel("complete", it)
+	// Do not allow completing migrated or scheduled items
+	if it.Type == model.Migrated || it.Type == model.Scheduled {
+		return nil
+	}
+	if it.Type == model.Task {
+		now := time.Now()
+		it.Type = model.Done
+		it.CompletedAt = &now
+	} else if it.Type == model.Done {
+		label = opLabel("reopen", it)
+		it.Type = model.Task
+		it.CompletedAt = nil
+	} else {
+		return nil
+	}
+	return a.record(label, func() error {
+		if err := a.put(log, d, it); err != nil {
+			return err
+		}
+		if it.Type != model.Done {
+			return nil
+		}
+		items, err := a.loadLog(log, d)
 		if err != nil {
 			return err
 		}
-		var cloneID string
-		var restoreType model.BulletType = model.Task
-		for _, it := range items {
-			if strings.EqualFold(it.Text, orig.Text) {
-				// Only consider likely original types (Task/Event)
-				if it.Type == model.Task || it.Type == model.Event {
-					cloneID = it.ID
-					restoreType = it.Type
-					break
+		for _, c := range subtree(items, it)[1:] {
+			if c.Type == model.Task {
+				c.Type = model.Done
+				c.CompletedAt = it.CompletedAt
+				if err := a.put(log, d, c); err != nil {
+					return err
 				}
 			}
 		}
-		// Update current-day item back to the original type
-		orig.Type = restoreType
-		orig.CompletedAt = nil
-		orig.ScheduledFor = nil
-		if err := a.Store.Update(e.Date, orig); err != nil {
+		return nil
+	})
+}
+
+// cancel toggles a Task to Cancelled (irrelevant) and back. Cancelling
+// also cancels open sub-tasks; restoring affects only the item itself.
+func (a *App) cancel(log string, d time.Time, it model.Bullet) error {
+	label := opLabel("cancel", it)
+	switch it.Type {
+	case model.Task:
+		it.Type = model.Cancelled
+	case model.Cancelled:
+		label = opLabel("restore", it)
+		it.Type = model.Task
+	default:
+		return nil
+	}
+	it.CompletedAt = nil
+	return a.record(label, func() error {
+		if err := a.put(log, d, it); err != nil {
 			return err
 		}
-		// Delete the clone if we found one
-		if cloneID != "" {
-			if err := a.Store.Delete(target, cloneID); err != nil {
-				return err
+		if it.Type != model.Cancelled {
+			return nil
+		}
+		items, err := a.loadLog(log, d)
+		if err != nil {
+			return err
+		}
+		for _, c := range subtree(items, it)[1:] {
+			if c.Type == model.Task {
+				c.Type = model.Cancelled
+				if err := a.put(log, d, c); err != nil {
+					return err
+				}
 			}
 		}
-		return a.Refresh()
+		return nil
+	})
+}
+
+// migrate toggles migration of orig (owned by day d in log) forward: to the
+// next day for daily logs, to the next month's log for monthly logs, into
+// the target month's log for future-log items, and into today's log for
+// collection items.
+func (a *App) migrate(log string, d time.Time, orig model.Bullet) error {
+	d = dateOnly(d)
+	if orig.Type == model.Migrated {
+		return a.record(opLabel("unmigrate", orig), func() error { return a.unlink(log, d, orig) })
 	}
 	// Only migrate tasks or events
 	if orig.Type != model.Task && orig.Type != model.Event {
 		return nil
 	}
-	// 1) Mark current-day item as Migrated (retain it on the current date)
-	marked := orig
-	marked.Type = model.Migrated
-	marked.CompletedAt = nil
-	next := e.Date.Add(24 * time.Hour)
-	// Store target date to enable undo
-	marked.ScheduledFor = &next
-	if err := a.Store.Update(e.Date, marked); err != nil {
-		return err
-	}
-	// 2) Add a copy on the next day with the original type
-	clone := orig
-	clone.ID = ""          // new identity on target day
-	clone.Type = orig.Type // keep original style
-	clone.CompletedAt = nil
-	clone.ScheduledFor = nil
-	clone.CreatedAt = time.Time{} // let Append set now
-	if err := a.Store.Append(next, clone); err != nil {
-		return err
+	dst, target := log, d.AddDate(0, 0, 1)
+	switch log {
+	case LogDaily:
+	case LogMonthly:
+		target = monthStart(d).AddDate(0, 1, 0)
+	case LogFuture:
+		dst, target = LogMonthly, futureMonth(orig, time.Now())
+	default:
+		// Collections are undated; their items move back into today's log.
+		dst, target = LogDaily, dateOnly(time.Now())
 	}
-	return a.Refresh()
+	return a.record(opLabel("migrate", orig), func() error {
+		return a.link(log, d, orig, model.Migrated, dst, target)
+	})
 }
 
-// ScheduleIndex moves the item to a specific date and marks as scheduled.
-func (a *App) ScheduleIndex(index int, date time.Time) error {
-	vis := a.Visible()
-	if index < 0 || index >= len(vis) {
-		return nil
-	}
-	e := vis[index]
-	orig := e.Item
-	// If already scheduled, undo the scheduling.
+// schedule toggles scheduling of orig (owned by day d in log) to a day.
+func (a *App) schedule(log string, d time.Time, orig model.Bullet, target time.Time) error {
+	d = dateOnly(d)
 	if orig.Type == model.Scheduled {
 		if orig.ScheduledFor == nil {
 			return nil
 		}
-		target := dateOnly(*orig.ScheduledFor)
-		items, err := a.Store.LoadDay(target)
-		if err != nil {
+		return a.record(opLabel("unschedule", orig), func() error { return a.unlink(log, d, orig) })
+	}
+	// Only schedule tasks or events
+	if orig.Type != model.Task && orig.Type != model.Event {
+		return nil
+	}
+	return a.record(opLabel("schedule", orig), func() error {
+		return a.link(log, d, orig, model.Scheduled, LogDaily, dateOnly(target))
+	})
+}
+
+// link marks orig as moved (Migrated/Scheduled), keeping it on day d of log
+// src, and adds a copy with the original type on target in log dst. Open
+// descendants travel with it, keeping their hierarchy. Both sides record the
+// link so the move can be undone and the chain followed later.
+func (a *App) link(src string, d time.Time, orig model.Bullet, marker model.BulletType, dst string, target time.Time) error {
+	items, err := a.loadLog(src, d)
+	if err != nil {
+		return err
+	}
+	cloneIDs := map[string]string{}
+	origin := d
+	for i, it := range subtree(items, orig) {
+		if i > 0 {
+			// Descendants move only while open and under a moved parent.
+			if _, ok := cloneIDs[it.ParentID]; !ok || it.Type == model.Done || it.Type == model.Migrated || it.Type == model.Scheduled {
+				continue
+			}
+		}
+		cloneID := store.NewID()
+		cloneIDs[it.ID] = cloneID
+		// 1) Mark current-day item (retain it on the current date)
+		marked := it
+		if it.Type == model.Task || it.Type == model.Event {
+			marked.Type = marker
+			if _, undated := CollectionName(dst); !undated {
+				marked.ScheduledFor = &target
+			}
+		}
+		marked.CompletedAt = nil
+		marked.CloneID = cloneID
+		marked.CloneLog = dst
+		if err := a.put(src, d, marked); err != nil {
+			return err
+		}
+		// 2) Add a copy on the target day with the original style
+		clone := it
+		clone.ID = cloneID
+		clone.ParentID = cloneIDs[it.ParentID]
+		if i == 0 {
+			clone.ParentID = ""
+		}
+		clone.CompletedAt = nil
+		clone.ScheduledFor = nil
+		clone.CloneID = ""
+		clone.CloneLog = ""
+		clone.OriginID = it.ID
+		clone.OriginDate = &origin
+		clone.OriginLog = src
+		clone.CreatedAt = time.Time{} // let Append set now
+		if dst == LogFuture {
+			clone.Month = target.Format("2006-01")
+		} else {
+			clone.Month = ""
+		}
+		if err := a.add(dst, target, clone); err != nil {
 			return err
 		}
+	}
+	return nil
+}
+
+// unlink undoes link: it deletes the copies on the target day and restores
+// the marked bullet (and any moved descendants) to the copies' types.
+func (a *App) unlink(src string, d time.Time, orig model.Bullet) error {
+	// Determine target date: prefer ScheduledFor if set, else next day
+	dst := orig.CloneLog
+	target := d.AddDate(0, 0, 1)
+	if orig.ScheduledFor != nil {
+		target = dateOnly(*orig.ScheduledFor)
+	}
+	items, err := a.loadLog(src, d)
+	if err != nil {
+		return err
+	}
+	titems, err := a.loadLog(dst, target)
+	if err != nil {
+		return err
+	}
+	for i, it := range subtree(items, orig) {
+		if i > 0 && it.CloneID == "" {
+			continue
+		}
 		var cloneID string
-		var restoreType model.BulletType = model.Task
-		for _, it := range items {
-			if strings.EqualFold(it.Text, orig.Text) {
-				if it.Type == model.Task || it.Type == model.Event {
-					cloneID = it.ID
-					restoreType = it.Type
-					break
+		restoreType := model.Task
+		for _, c := range titems {
+			if it.CloneID != "" {
+				if c.ID != it.CloneID {
+					continue
 				}
+				cloneID = c.ID
+				restoreType = c.Type
+				break
+			}
+			// Legacy data without links: match by text, original types only
+			if strings.EqualFold(c.Text, it.Text) && (c.Type == model.Task || c.Type == model.Event) {
+				cloneID = c.ID
+				restoreType = c.Type
+				break
 			}
 		}
-		// Restore current-day item
-		orig.Type = restoreType
-		orig.ScheduledFor = nil
-		orig.CompletedAt = nil
-		if err := a.Store.Update(e.Date, orig); err != nil {
+		// Update current-day item back to the original type
+		if it.Type == model.Migrated || it.Type == model.Scheduled {
+			if restoreType != model.Task && restoreType != model.Event {
+				restoreType = model.Task
+			}
+			it.Type = restoreType
+			it.ScheduledFor = nil
+		}
+		it.CompletedAt = nil
+		it.CloneID = ""
+		it.CloneLog = ""
+		if err := a.put(src, d, it); err != nil {
 			return err
 		}
+		// Delete the clone if we found one
 		if cloneID != "" {
-			if err := a.Store.Delete(target, cloneID); err != nil {
+			if err := a.remove(dst, target, cloneID); err != nil {
 				return err
 			}
 		}
-		return a.Refresh()
 	}
-	// Only schedule tasks or events
-	if orig.Type != model.Task && orig.Type != model.Event {
-		return nil
+	return nil
+}
+
+// Lineage follows OriginID/OriginDate links backwards from e and returns the
+// bullets it was migrated or scheduled from, oldest first.
+func (a *App) Lineage(e Entry) ([]Entry, error) { return lineage(e, a.loadLog) }
+
+// lineage is Lineage reading the logs along the chain with load.
+func lineage(e Entry, load func(log string, d time.Time) ([]model.Bullet, error)) ([]Entry, error) {
+	var chain []Entry
+	seen := map[string]bool{e.Item.ID: true}
+	cur := e.Item
+	for cur.OriginID != "" && cur.OriginDate != nil && !seen[cur.OriginID] {
+		seen[cur.OriginID] = true
+		d := dateOnly(*cur.OriginDate)
+		log := cur.OriginLog
+		items, err := load(log, d)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, it := range items {
+			if it.ID == cur.OriginID {
+				chain = append(chain, Entry{Date: d, Item: it, Log: log})
+				cur = it
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
 	}
-	// 1) Mark current-day item as Scheduled and keep it
-	marked := orig
-	marked.Type = model.Scheduled
-	marked.ScheduledFor = &date
-	marked.CompletedAt = nil
-	if err := a.Store.Update(e.Date, marked); err != nil {
-		return err
+	// Reverse to oldest first
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
 	}
-	// 2) Add a copy on the scheduled day with the original style
-	clone := orig
-	clone.ID = ""
-	clone.Type = orig.Type
-	clone.ScheduledFor = nil
-	clone.CompletedAt = nil
-	clone.CreatedAt = time.Time{}
-	if err := a.Store.Append(dateOnly(date), clone); err != nil {
-		return err
+	return chain, nil
+}
+
+// LineageSummary describes how often e has been carried forward, e.g.
+// "migrated 3 times since Mar 2". Empty when e has no recorded origin.
+func (a *App) LineageSummary(e Entry) string {
+	if e.Item.OriginID == "" {
+		return ""
 	}
-	return a.Refresh()
+	chain, err := a.Lineage(e)
+	if err != nil {
+		return ""
+	}
+	return summarizeLineage(chain)
+}
+
+// LineageSummaries returns the LineageSummary of each of entries that has
+// one, keyed by bullet ID. Every log file along the chains is read at most
+// once, so a view can describe all of its bullets on each refresh.
+func (a *App) LineageSummaries(entries []Entry) map[string]string {
+	files := map[string][]model.Bullet{}
+	load := func(log string, d time.Time) ([]model.Bullet, error) {
+		key := log + "/" + d.Format("2006-01-02")
+		if items, ok := files[key]; ok {
+			return items, nil
+		}
+		items, err := a.loadLog(log, d)
+		if err == nil {
+			files[key] = items
+		}
+		return items, err
+	}
+	out := map[string]string{}
+	for _, e := range entries {
+		if e.Item.OriginID == "" {
+			continue
+		}
+		if chain, err := lineage(e, load); err == nil {
+			if note := summarizeLineage(chain); note != "" {
+				out[e.Item.ID] = note
+			}
+		}
+	}
+	return out
+}
+
+// summarizeLineage describes a chain returned by Lineage; see
+// LineageSummary.
+func summarizeLineage(chain []Entry) string {
+	if len(chain) == 0 {
+		return ""
+	}
+	verb := ""
+	for _, c := range chain {
+		var v string
+		switch c.Item.Type {
+		case model.Migrated:
+			v = "migrated"
+		case model.Scheduled:
+			v = "scheduled"
+		default:
+			v = "moved"
+		}
+		if verb == "" {
+			verb = v
+		} else if verb != v {
+			verb = "moved"
+		}
+	}
+	times := strconv.Itoa(len(chain)) + " times"
+	if len(chain) == 1 {
+		times = "once"
+	}
+	since := chain[0].Date.Format("Jan 2")
+	if name, ok := CollectionName(chain[0].Log); ok {
+		return verb + " " + times + " from " + name
+	} else if chain[0].Log != LogDaily {
+		since = chain[0].Date.Format("Jan 2006")
+	}
+	return verb + " " + times + " since " + since
 }
 
 // ChangeTypeIndex updates the type and adjusts related fields.
@@ -356,6 +702,7 @@ func (a *App) ChangeTypeIndex(index int, t model.BulletType) error {
 	}
 	e := vis[index]
 	it := e.Item
+	label := opLabel("type", it)
 	it.Type = t
 	// Normalize related fields
 	if t == model.Done {
@@ -367,7 +714,11 @@ func (a *App) ChangeTypeIndex(index int, t model.BulletType) error {
 	if t != model.Scheduled {
 		it.ScheduledFor = nil
 	}
-	if err := a.Store.Update(e.Date, it); err != nil {
+	if t != model.Migrated && t != model.Scheduled {
+		it.CloneID = ""
+		it.CloneLog = ""
+	}
+	if err := a.record(label, func() error { return a.put(e.Log, e.Date, it) }); err != nil {
 		return err
 	}
 	return a.Refresh()
@@ -381,8 +732,9 @@ func (a *App) UpdateTagsIndex(index int, tags []string) error {
 	}
 	e := vis[index]
 	it := e.Item
+	label := opLabel("tags", it)
 	it.Tags = tags
-	if err := a.Store.Update(e.Date, it); err != nil {
+	if err := a.record(label, func() error { return a.put(e.Log, e.Date, it) }); err != nil {
 		return err
 	}
 	return a.Refresh()
@@ -411,11 +763,8 @@ func (a *App) ClearFilters() {
 	a.SavePrefs()
 }
 
-// SavePrefs persists the current period, filters, and date.
+// SavePrefs persists the current period, filters, sort mode and date.
 func (a *App) SavePrefs() {
-	// Load existing prefs to preserve unrelated fields (e.g., UI settings)
-	p, _ := store.LoadPreferences()
-	// Update controlled fields
 	var types []model.BulletType
 	for t, ok := range a.TypeFilter {
 		if ok {
@@ -428,183 +777,69 @@ func (a *App) SavePrefs() {
 			tags = append(tags, t)
 		}
 	}
-	p.Period = a.Period
-	p.TextFilter = a.TextFilter
-	p.Types = types
-	p.Tags = tags
-	p.LastDate = a.CurrentDate.Format("2006-01-02")
-	_ = store.SavePreferences(p)
+	// Update only the fields the App controls, keeping unrelated ones
+	// (e.g. UI settings) as stored.
+	_ = store.UpdatePreferences(func(p *store.Preferences) {
+		p.Period = a.Period
+		p.Collection = a.Collection
+		p.TextFilter = a.TextFilter
+		p.Types = types
+		p.Tags = tags
+		p.LastDate = a.CurrentDate.Format("2006-01-02")
+		p.Sort = string(a.Sort)
+	})
 }
 
-// --- Day-index operations for CLI (ignore filters/range) ---
-
-// DeleteDayIndex removes an item by zero-based index within a specific day.
-func (a *App) DeleteDayIndex(date time.Time, index int) error {
-	items, err := a.Store.LoadDay(dateOnly(date))
-	if err != nil {
-		return err
+// Warnings reports recoverable storage problems, such as unreadable lines
+// skipped while loading, for display. Stores that do not track them report
+// none.
+func (a *App) Warnings() []string {
+	if w, ok := a.Store.(store.Warner); ok {
+		return w.Warnings()
 	}
-	if index < 0 || index >= len(items) {
-		return nil
-	}
-	return a.Store.Delete(dateOnly(date), items[index].ID)
+	return nil
 }
 
-// UpdateDayIndexText updates text by zero-based index within a day.
-func (a *App) UpdateDayIndexText(date time.Time, index int, text string) error {
-	items, err := a.Store.LoadDay(dateOnly(date))
+// --- ID operations for the CLI (ignore filters and range) ---
+
+// byID runs fn on the bullet with the given ID or unique ID prefix, in
+// whichever log holds it.
+func (a *App) byID(ref string, fn func(e Entry) error) error {
+	e, err := a.LocateEntry(ref)
 	if err != nil {
 		return err
 	}
-	if index < 0 || index >= len(items) {
-		return nil
-	}
-	it := items[index]
-	it.Text = text
-	return a.Store.Update(dateOnly(date), it)
+	return fn(e)
 }
 
-// CompleteDayIndex toggles Task<->Done for a day item; no-op for other types.
-func (a *App) CompleteDayIndex(date time.Time, index int) error {
-	d := dateOnly(date)
-	items, err := a.Store.LoadDay(d)
-	if err != nil {
-		return err
-	}
-	if index < 0 || index >= len(items) {
-		return nil
-	}
-	it := items[index]
-	if it.Type == model.Migrated || it.Type == model.Scheduled {
-		return nil
-	}
-	if it.Type == model.Task {
-		now := time.Now()
-		it.Type = model.Done
-		it.CompletedAt = &now
-	} else if it.Type == model.Done {
-		it.Type = model.Task
-		it.CompletedAt = nil
-	} else {
-		return nil
-	}
-	return a.Store.Update(d, it)
+// DeleteID moves the bullet matching ref, with its sub-items, to the trash.
+func (a *App) DeleteID(ref string) error {
+	return a.byID(ref, func(e Entry) error { return a.delete(e.Log, e.Date, e.Item) })
 }
 
-// MigrateDayIndex toggles migration for Task/Event and Migrated.
-func (a *App) MigrateDayIndex(date time.Time, index int) error {
-	d := dateOnly(date)
-	items, err := a.Store.LoadDay(d)
-	if err != nil {
-		return err
-	}
-	if index < 0 || index >= len(items) {
-		return nil
-	}
-	orig := items[index]
-	if orig.Type == model.Migrated {
-		target := d.AddDate(0, 0, 1)
-		if orig.ScheduledFor != nil {
-			target = dateOnly(*orig.ScheduledFor)
-		}
-		titems, err := a.Store.LoadDay(target)
-		if err != nil {
-			return err
-		}
-		var cloneID string
-		restoreType := model.Task
-		for _, it := range titems {
-			if strings.EqualFold(it.Text, orig.Text) && (it.Type == model.Task || it.Type == model.Event) {
-				cloneID = it.ID
-				restoreType = it.Type
-				break
-			}
-		}
-		orig.Type = restoreType
-		orig.CompletedAt = nil
-		orig.ScheduledFor = nil
-		if err := a.Store.Update(d, orig); err != nil {
-			return err
-		}
-		if cloneID != "" {
-			return a.Store.Delete(target, cloneID)
-		}
-		return nil
-	}
-	if orig.Type != model.Task && orig.Type != model.Event {
-		return nil
-	}
-	next := d.AddDate(0, 0, 1)
-	marked := orig
-	marked.Type = model.Migrated
-	marked.CompletedAt = nil
-	marked.ScheduledFor = &next
-	if err := a.Store.Update(d, marked); err != nil {
-		return err
-	}
-	clone := orig
-	clone.ID = ""
-	clone.CompletedAt = nil
-	clone.ScheduledFor = nil
-	clone.CreatedAt = time.Time{}
-	return a.Store.Append(next, clone)
+// UpdateTextID replaces the text of the bullet matching ref.
+func (a *App) UpdateTextID(ref, text string) error {
+	return a.byID(ref, func(e Entry) error { return a.updateText(e.Log, e.Date, e.Item, text) })
 }
 
-// ScheduleDayIndex toggles scheduling to a date or unschedules.
-func (a *App) ScheduleDayIndex(date time.Time, index int, target time.Time) error {
-	d := dateOnly(date)
-	items, err := a.Store.LoadDay(d)
-	if err != nil {
-		return err
-	}
-	if index < 0 || index >= len(items) {
-		return nil
-	}
-	orig := items[index]
-	if orig.Type == model.Scheduled {
-		if orig.ScheduledFor == nil {
-			return nil
-		}
-		targetDay := dateOnly(*orig.ScheduledFor)
-		titems, err := a.Store.LoadDay(targetDay)
-		if err != nil {
-			return err
-		}
-		var cloneID string
-		restoreType := model.Task
-		for _, it := range titems {
-			if strings.EqualFold(it.Text, orig.Text) && (it.Type == model.Task || it.Type == model.Event) {
-				cloneID = it.ID
-				restoreType = it.Type
-				break
-			}
-		}
-		orig.Type = restoreType
-		orig.CompletedAt = nil
-		orig.ScheduledFor = nil
-		if err := a.Store.Update(d, orig); err != nil {
-			return err
-		}
-		if cloneID != "" {
-			return a.Store.Delete(targetDay, cloneID)
-		}
-		return nil
-	}
-	if orig.Type != model.Task && orig.Type != model.Event {
-		return nil
-	}
-	tgt := dateOnly(target)
-	marked := orig
-	marked.Type = model.Scheduled
-	marked.CompletedAt = nil
-	marked.ScheduledFor = &tgt
-	if err := a.Store.Update(d, marked); err != nil {
-		return err
-	}
-	clone := orig
-	clone.ID = ""
-	clone.CompletedAt = nil
-	clone.ScheduledFor = nil
-	clone.CreatedAt = time.Time{}
-	return a.Store.Append(tgt, clone)
+// CompleteID toggles Task<->Done for the bullet matching ref; no-op for
+// other types.
+func (a *App) CompleteID(ref string) error {
+	return a.byID(ref, func(e Entry) error { return a.complete(e.Log, e.Date, e.Item) })
+}
+
+// CancelID toggles Cancelled for the task matching ref (or back).
+func (a *App) CancelID(ref string) error {
+	return a.byID(ref, func(e Entry) error { return a.cancel(e.Log, e.Date, e.Item) })
+}
+
+// MigrateID toggles migration for the Task/Event or Migrated bullet matching
+// ref.
+func (a *App) MigrateID(ref string) error {
+	return a.byID(ref, func(e Entry) error { return a.migrate(e.Log, e.Date, e.Item) })
+}
+
+// ScheduleID toggles scheduling of the bullet matching ref to target.
+func (a *App) ScheduleID(ref string, target time.Time) error {
+	return a.byID(ref, func(e Entry) error { return a.schedule(e.Log, e.Date, e.Item, target) })
 }
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+func TestLocateEntry(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	st, err := store.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newTestApp(t, st, day)
+	add := func(log string, d time.Time, id string) {
+		t.Helper()
+		b := model.Bullet{ID: id, Type: model.Task, Text: id}
+		var err error
+		switch name, ok := CollectionName(log); {
+		case ok:
+			_, err = a.AddToCollection(name, b)
+		case log == LogDaily:
+			_, err = a.AddTo(d, b)
+		default:
+			_, err = a.AddToLog(log, d, b)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(LogDaily, day, "1000-aa")
+	add(LogMonthly, day, "2000-bb")
+	add(LogFuture, day, "3000-cc")
+	add(CollectionLog("ideas"), time.Time{}, "4000-dd")
+	add(CollectionLog("ideas"), time.Time{}, "4001-ee")
+
+	tests := []struct {
+		ref  string
+		log  string
+		want string
+		err  error
+	}{
+		{"1000-aa", LogDaily, "1000-aa", nil},
+		{"1", LogDaily, "1000-aa", nil},
+		{"2000", LogMonthly, "2000-bb", nil},
+		{"3", LogFuture, "3000-cc", nil},
+		{"4000", CollectionLog("ideas"), "4000-dd", nil},
+		{"400", "", "", store.ErrAmbiguousID},
+		{"5", "", "", store.ErrNotFound},
+		{" ", "", "", store.ErrNotFound},
+	}
+	for _, tt := range tests {
+		e, err := a.LocateEntry(tt.ref)
+		if !errors.Is(err, tt.err) || e.Log != tt.log || e.Item.ID != tt.want {
+			t.Errorf("LocateEntry(%q) = %q in %q, %v; want %q in %q, %v", tt.ref, e.Item.ID, e.Log, err, tt.want, tt.log, tt.err)
+		}
+	}
+
+	// Operations by ID act on the bullet in whichever log holds it.
+	if err := a.CompleteID("2000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.UpdateTextID("4001", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.DeleteID("3000"); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := a.LocateEntry("2000"); e.Item.Type != model.Done {
+		t.Errorf("monthly log bullet is %s after CompleteID", e.Item.Type)
+	}
+	if e, _ := a.LocateEntry("4001"); e.Item.Text != "renamed" {
+		t.Errorf("collection bullet reads %q after UpdateTextID", e.Item.Text)
+	}
+	if _, err := a.LocateEntry("3000"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("future log bullet still found after DeleteID: %v", err)
+	}
+	if err := a.CompleteID("9"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("CompleteID on an unknown ID = %v", err)
+	}
+}
+
+// countingStore counts the days loaded from a MemStore.
+type countingStore struct {
+	*store.MemStore
+	loads int
+}
+
+func (s *countingStore) LoadDay(date time.Time) ([]model.Bullet, error) {
+	s.loads++
+	return s.MemStore.LoadDay(date)
+}
+
+func TestLineageSummaries(t *testing.T) {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	st := &countingStore{MemStore: store.NewMemStore()}
+	a := newTestApp(t, st, start)
+	if _, err := a.Add("carried"); err != nil {
+		t.Fatal(err)
+	}
+	// Migrate the task forward from Monday to Friday.
+	for d := start; d.Before(start.AddDate(0, 0, 4)); d = d.AddDate(0, 0, 1) {
+		if err := a.JumpToDate(d); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.MigrateIndex(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.Period = model.PeriodWeek
+	if err := a.JumpToDate(start); err != nil {
+		t.Fatal(err)
+	}
+	vis := a.Visible()
+	st.loads = 0
+	notes := a.LineageSummaries(vis)
+	if st.loads > 4 {
+		t.Errorf("summarizing %d entries loaded %d days, want at most 4", len(vis), st.loads)
+	}
+	for _, e := range vis {
+		if got, want := notes[e.Item.ID], a.LineageSummary(e); got != want {
+			t.Errorf("%s: summary %q, want %q", e.Date.Format("Mon"), got, want)
+		}
+	}
+	if got := notes[vis[len(vis)-1].Item.ID]; got != "migrated 4 times since Mar 2" {
+		t.Errorf("Friday's task: %q", got)
+	}
+}
+
+func TestDeleteCollection(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	st, err := store.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newTestApp(t, st, day)
+	for _, text := range []string{"read", "write"} {
+		if _, err := a.AddToCollection("ideas", model.Bullet{Type: model.Task, Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state := func() string {
+		t.Helper()
+		cols, err := a.Collections()
+		if err != nil {
+			t.Fatal(err)
+		}
+		trash, err := a.Trash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var parts []string
+		for _, c := range cols {
+			parts = append(parts, fmt.Sprintf("%s:%d", c.Name, c.Count))
+		}
+		for _, b := range trash {
+			parts = append(parts, "trash:"+b.Text+"@"+DeletedFrom(b))
+		}
+		return strings.Join(parts, " ")
+	}
+	const kept, deleted = "ideas:2", "trash:read@collection ideas trash:write@collection ideas"
+	if err := a.DeleteCollection("ideas"); err != nil {
+		t.Fatal(err)
+	}
+	if got := state(); got != deleted {
+		t.Errorf("after delete: %q, want %q", got, deleted)
+	}
+	if _, err := a.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := state(); got != kept {
+		t.Errorf("after undo: %q, want %q", got, kept)
+	}
+
+	// Restoring a trashed bullet brings the collection back.
+	if err := a.DeleteCollection("ideas"); err != nil {
+		t.Fatal(err)
+	}
+	trash, err := a.Trash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Restore(trash[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := state(), "ideas:1 trash:write@collection ideas"; got != want {
+		t.Errorf("after restore: %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"time"
+
+	"github.com/rdo34/blt/internal/store"
+)
+
+// DefaultBackupKeep is how many snapshots are kept when the preferences do
+// not set a retention.
+const DefaultBackupKeep = 7
+
+// BackupKeep returns how many snapshots to keep from the preferences; zero
+// means daily snapshots are off.
+func BackupKeep() int {
+	p, _ := store.LoadPreferences()
+	switch {
+	case p.BackupKeep == 0:
+		return DefaultBackupKeep
+	case p.BackupKeep < 0:
+		return 0
+	}
+	return p.BackupKeep
+}
+
+// SetBackupKeep stores how many snapshots to keep; zero or less turns
+// daily snapshots off.
+func SetBackupKeep(n int) error {
+	if n <= 0 {
+		n = -1
+	}
+	return store.UpdatePreferences(func(p *store.Preferences) { p.BackupKeep = n })
+}
+
+// DailyBackup snapshots st's data directory on startup unless that was
+// already done today or daily snapshots are off, pruning snapshots beyond
+// the retention. It returns the new snapshot, if any.
+func DailyBackup(st *store.FSStore, now time.Time) (store.Backup, error) {
+	keep := BackupKeep()
+	if keep <= 0 {
+		return store.Backup{}, nil
+	}
+	return st.DailySnapshot(now, keep)
+}
@@ -0,0 +1,244 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// ErrNoCollections is returned when the store cannot hold named collections.
+var ErrNoCollections = errors.New("store does not support collections")
+
+// CollectionInfo summarizes a named collection for browsing.
+type CollectionInfo struct {
+	Name  string
+	Count int // bullets in the collection
+	Open  int // open tasks
+}
+
+// CollectionLog is the log name for a named collection.
+func CollectionLog(name string) string { return store.CollectionRef(name) }
+
+// CollectionName returns the collection named by log, if it is one.
+func CollectionName(log string) (string, bool) { return store.ParseCollectionRef(log) }
+
+func (a *App) collections() (store.CollectionStore, error) {
+	cs, ok := a.Store.(store.CollectionStore)
+	if !ok {
+		return nil, ErrNoCollections
+	}
+	return cs, nil
+}
+
+// Collections lists all named collections with item counts.
+func (a *App) Collections() ([]CollectionInfo, error) {
+	cs, err := a.collections()
+	if err != nil {
+		return nil, err
+	}
+	names, err := cs.Collections()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]CollectionInfo, 0, len(names))
+	for _, n := range names {
+		items, err := cs.Collection(n).LoadDay(time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		info := CollectionInfo{Name: n, Count: len(items)}
+		for _, it := range items {
+			if it.Type == model.Task {
+				info.Open++
+			}
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+// OpenCollection switches the view to the named collection.
+func (a *App) OpenCollection(name string) error {
+	if err := store.ValidateCollectionName(name); err != nil {
+		return err
+	}
+	a.Collection = name
+	return a.SetPeriod(model.PeriodCollection)
+}
+
+// CreateCollection creates an empty named collection.
+func (a *App) CreateCollection(name string) error {
+	cs, err := a.collections()
+	if err != nil {
+		return err
+	}
+	if err := cs.CreateCollection(name); err != nil {
+		return err
+	}
+	return a.commit("create collection: " + name)
+}
+
+// DeleteCollection removes a collection. Its bullets move to the trash,
+// from where they can be restored (recreating the collection) until they
+// expire, and the move can be undone; stores without a trash delete them
+// for good. When the collection is open, the view falls back to the day log.
+func (a *App) DeleteCollection(name string) error {
+	cs, err := a.collections()
+	if err != nil {
+		return err
+	}
+	log := CollectionLog(name)
+	items, err := a.loadLog(log, time.Time{})
+	if err != nil {
+		return err
+	}
+	if _, trash := a.Store.(store.TrashStore); !trash || len(items) == 0 {
+		if err := cs.DeleteCollection(name); err != nil {
+			return err
+		}
+		err = a.commit("delete collection: " + name)
+	} else {
+		err = a.record("delete collection: "+name, func() error {
+			now := time.Now()
+			// Children first so a partial failure never leaves orphans behind.
+			for i := len(items) - 1; i >= 0; i-- {
+				if err := a.add(LogTrash, time.Time{}, trashed(items[i], log, time.Time{}, now)); err != nil {
+					return err
+				}
+				if err := a.remove(log, time.Time{}, items[i].ID); err != nil {
+					return err
+				}
+			}
+			return cs.DeleteCollection(name)
+		})
+		if err == nil {
+			_, err = a.PurgeTrash(time.Now())
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if a.Period == model.PeriodCollection && a.Collection == name {
+		a.Collection = ""
+		return a.SetPeriod(model.PeriodDay)
+	}
+	return nil
+}
+
+// RenameCollection renames a collection, keeping it open if it was shown.
+func (a *App) RenameCollection(from, to string) error {
+	cs, err := a.collections()
+	if err != nil {
+		return err
+	}
+	if err := cs.RenameCollection(from, to); err != nil {
+		return err
+	}
+	if err := a.commit("rename collection: " + from + " -> " + to); err != nil {
+		return err
+	}
+	if a.Collection == from {
+		a.Collection = to
+		a.SavePrefs()
+	}
+	return a.Refresh()
+}
+
+// MoveToCollectionIndex migrates the visible item at index (and its open
+// sub-items) into the named collection, leaving a Migrated marker behind.
+// Migrating the marker again from its log undoes the move.
+func (a *App) MoveToCollectionIndex(index int, name string) error {
+	vis := a.Visible()
+	if index < 0 || index >= len(vis) {
+		return nil
+	}
+	e := vis[index]
+	return a.moveToCollection(e.Log, e.Date, e.Item, name)
+}
+
+// MoveIDToCollection is MoveToCollectionIndex for the bullet matching ref,
+// an ID or unique ID prefix.
+func (a *App) MoveIDToCollection(ref, name string) error {
+	return a.byID(ref, func(e Entry) error { return a.moveToCollection(e.Log, e.Date, e.Item, name) })
+}
+
+func (a *App) moveToCollection(log string, d time.Time, it model.Bullet, name string) error {
+	if err := store.ValidateCollectionName(name); err != nil {
+		return err
+	}
+	if it.Type != model.Task && it.Type != model.Event {
+		return nil
+	}
+	err := a.record(opLabel("move to "+name, it), func() error {
+		return a.link(log, d, it, model.Migrated, CollectionLog(name), time.Time{})
+	})
+	if err != nil {
+		return err
+	}
+	return a.Refresh()
+}
+
+// MoveFromCollection migrates the collection item matching ref (an ID or
+// unique prefix) into the daily log on date.
+func (a *App) MoveFromCollection(name, ref string, date time.Time) error {
+	log := CollectionLog(name)
+	items, err := a.loadLog(log, time.Time{})
+	if err != nil {
+		return err
+	}
+	i, err := findRef(items, ref)
+	if err != nil {
+		return err
+	}
+	it := items[i]
+	if it.Type != model.Task && it.Type != model.Event {
+		return nil
+	}
+	err = a.record(opLabel("migrate", it), func() error {
+		return a.link(log, time.Time{}, it, model.Migrated, LogDaily, dateOnly(date))
+	})
+	if err != nil {
+		return err
+	}
+	return a.Refresh()
+}
+
+// AddToCollection appends b to the named collection.
+func (a *App) AddToCollection(name string, b model.Bullet) (model.Bullet, error) {
+	if err := store.ValidateCollectionName(name); err != nil {
+		return model.Bullet{}, err
+	}
+	if b.ID == "" {
+		b.ID = store.NewID()
+	}
+	if err := a.record(opLabel("add", b), func() error { return a.add(CollectionLog(name), time.Time{}, b) }); err != nil {
+		return model.Bullet{}, err
+	}
+	return b, a.Refresh()
+}
+
+// findRef returns the index of the bullet whose ID equals ref, or the only
+// one it prefixes.
+func findRef(items []model.Bullet, ref string) (int, error) {
+	ref = strings.TrimSpace(ref)
+	var matches []int
+	for i, it := range items {
+		if it.ID == ref {
+			return i, nil
+		}
+		if ref != "" && strings.HasPrefix(it.ID, ref) {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf("%w: %q", store.ErrNotFound, ref)
+	case 1:
+		return matches[0], nil
+	}
+	return -1, fmt.Errorf("%w: %q matches %d bullets", store.ErrAmbiguousID, ref, len(matches))
+}
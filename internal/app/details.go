@@ -0,0 +1,152 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rdo34/blt/internal/store"
+)
+
+// Ago describes how long before now t was, e.g. "2h ago"; times more than
+// a month back are given as a date.
+func Ago(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+	return "on " + t.Format("Jan 2, 2006")
+}
+
+// Details describes e for a detail view as "Label  value" lines: where it
+// lives, its timestamps relative to now (e.g. "edited 2h ago"), its lineage
+// and its earlier revisions, newest first.
+func (a *App) Details(e Entry, now time.Time) []string {
+	b := e.Item
+	var out []string
+	add := func(label, value string) {
+		if value != "" {
+			out = append(out, fmt.Sprintf("%-10s %s", label, value))
+		}
+	}
+	stamp := func(t time.Time) string {
+		return t.Local().Format("2006-01-02 15:04") + " (" + Ago(t, now) + ")"
+	}
+
+	add("ID", b.ID)
+	add("Type", string(b.Type))
+	add("Text", b.Text)
+	add("Tags", hashTags(b.Tags))
+	add("Log", logName(e))
+	if !b.CreatedAt.IsZero() {
+		add("Created", stamp(b.CreatedAt))
+	}
+	if b.UpdatedAt != nil {
+		add("Edited", stamp(*b.UpdatedAt))
+	} else {
+		add("Edited", "never")
+	}
+	if b.CompletedAt != nil {
+		add("Completed", stamp(*b.CompletedAt))
+	}
+	if b.ScheduledFor != nil {
+		add("Scheduled", b.ScheduledFor.Format("2006-01-02"))
+	}
+	add("Lineage", a.LineageSummary(e))
+	if len(b.Revisions) > 0 {
+		out = append(out, "", "Earlier versions:")
+		for i := len(b.Revisions) - 1; i >= 0; i-- {
+			r := b.Revisions[i]
+			line := fmt.Sprintf("  until %s  %s  %s", r.At.Local().Format("2006-01-02 15:04"), r.Type, r.Text)
+			if len(r.Tags) > 0 {
+				line += "  " + hashTags(r.Tags)
+			}
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// hashTags renders tags as "#a #b".
+func hashTags(tags []string) string {
+	parts := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t = strings.TrimPrefix(strings.TrimSpace(t), "#"); t != "" {
+			parts = append(parts, "#"+t)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// logName names the log holding e, e.g. "day 2024-03-01" or "collection
+// ideas".
+func logName(e Entry) string {
+	if name, ok := CollectionName(e.Log); ok {
+		return "collection " + name
+	}
+	switch e.Log {
+	case LogMonthly:
+		return "monthly log " + e.Date.Format("2006-01")
+	case LogFuture:
+		return "future log " + e.Date.Format("2006-01")
+	}
+	return "day " + e.Date.Format("2006-01-02")
+}
+
+// LocateEntry resolves a bullet ID (or unique ID prefix) to its entry,
+// looking in the daily log, the monthly and future logs and every
+// collection the store keeps. Trashed bullets are not found.
+func (a *App) LocateEntry(ref string) (Entry, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return Entry{}, store.ErrNotFound
+	}
+	logs := []string{LogDaily}
+	if _, ok := a.Store.(store.LogStore); ok {
+		logs = append(logs, LogMonthly, LogFuture)
+	}
+	if cs, ok := a.Store.(store.CollectionStore); ok {
+		names, err := cs.Collections()
+		if err != nil {
+			return Entry{}, err
+		}
+		for _, name := range names {
+			logs = append(logs, CollectionLog(name))
+		}
+	}
+	var matches []Entry
+	for _, log := range logs {
+		st, err := a.logStore(log)
+		if err != nil {
+			return Entry{}, err
+		}
+		for d, err := range st.Range(time.Time{}, time.Time{}) {
+			if err != nil {
+				return Entry{}, err
+			}
+			for _, it := range d.Items {
+				e := Entry{Date: dateOnly(d.Date), Item: it, Log: log}
+				if it.ID == ref {
+					return e, nil
+				}
+				if strings.HasPrefix(it.ID, ref) {
+					matches = append(matches, e)
+				}
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Entry{}, fmt.Errorf("%w: %q", store.ErrNotFound, ref)
+	case 1:
+		return matches[0], nil
+	}
+	return Entry{}, fmt.Errorf("%w: %q matches %d bullets", store.ErrAmbiguousID, ref, len(matches))
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// Index is the journal's table of contents: collections, tags and months.
+type Index struct {
+	Collections []CollectionInfo
+	Tags        []TagInfo
+	Months      []MonthInfo
+}
+
+// TagInfo counts the bullets carrying a tag in the daily logs and the days
+// it first and last appeared.
+type TagInfo struct {
+	Tag   string
+	Count int
+	First time.Time
+	Last  time.Time
+}
+
+// MonthInfo summarizes the daily logs of one month.
+type MonthInfo struct {
+	Month     time.Time // first day of the month
+	Days      int       // days with at least one bullet
+	Total     int
+	Open      int // open tasks
+	Done      int
+	Migrated  int // migrated or scheduled away
+	Cancelled int
+	Events    int
+}
+
+// BuildIndex scans every day and collection to build the journal index.
+// Tags are normalized to lower case without a leading '#'.
+func (a *App) BuildIndex() (Index, error) {
+	var idx Index
+	tags := map[string]*TagInfo{}
+	var month *MonthInfo
+	for day, err := range a.Store.Range(time.Time{}, time.Time{}) {
+		if err != nil {
+			return idx, err
+		}
+		d, items := day.Date, day.Items
+		if m := monthStart(d); month == nil || !month.Month.Equal(m) {
+			idx.Months = append(idx.Months, MonthInfo{Month: m})
+			month = &idx.Months[len(idx.Months)-1]
+		}
+		month.Days++
+		for _, it := range items {
+			month.Total++
+			switch it.Type {
+			case model.Task:
+				month.Open++
+			case model.Done:
+				month.Done++
+			case model.Migrated, model.Scheduled:
+				month.Migrated++
+			case model.Cancelled:
+				month.Cancelled++
+			case model.Event:
+				month.Events++
+			}
+			seen := map[string]bool{}
+			for _, t := range it.Tags {
+				t = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(t)), "#")
+				if t == "" || seen[t] {
+					continue
+				}
+				seen[t] = true
+				ti := tags[t]
+				if ti == nil {
+					ti = &TagInfo{Tag: t, First: d}
+					tags[t] = ti
+				}
+				ti.Count++
+				ti.Last = d
+			}
+		}
+	}
+	for _, ti := range tags {
+		idx.Tags = append(idx.Tags, *ti)
+	}
+	sort.Slice(idx.Tags, func(i, j int) bool { return idx.Tags[i].Tag < idx.Tags[j].Tag })
+	if _, ok := a.Store.(store.CollectionStore); ok {
+		cols, err := a.Collections()
+		if err != nil {
+			return idx, err
+		}
+		idx.Collections = cols
+	}
+	return idx, nil
+}
+
+// ShowTag switches to the month view of the tag's latest use, filtered to
+// that tag.
+func (a *App) ShowTag(t TagInfo) error {
+	a.Period = model.PeriodMonth
+	a.SetTagFilter([]string{t.Tag})
+	return a.JumpToDate(t.Last)
+}
+
+// ShowMonth switches to the month view of m.
+func (a *App) ShowMonth(m time.Time) error {
+	a.Period = model.PeriodMonth
+	return a.JumpToDate(m)
+}
+
+// Search finds bullets across the daily logs using the store's index.
+func (a *App) Search(q store.Query) ([]Entry, error) {
+	sr, ok := a.Store.(store.Searcher)
+	if !ok {
+		return nil, errors.New("store does not support search")
+	}
+	hits, err := sr.Search(q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Entry, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, Entry{Date: h.Date, Item: h.Bullet, Log: LogDaily})
+	}
+	return out, nil
+}
+
+// Reindex rebuilds the store's search index from the day files.
+func (a *App) Reindex() error {
+	sr, ok := a.Store.(store.Searcher)
+	if !ok {
+		return errors.New("store does not support search")
+	}
+	return sr.Reindex()
+}
@@ -0,0 +1,330 @@
+package app
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// maxUndo bounds the number of operations kept on the undo stack.
+const maxUndo = 200
+
+// opLabel builds a short, human-readable operation label such as
+// "complete: write report".
+func opLabel(verb string, b model.Bullet) string {
+	text := b.Text
+	if r := []rune(text); len(r) > 60 {
+		text = string(r[:57]) + "..."
+	}
+	return verb + ": " + text
+}
+
+// record runs fn as a single undoable operation. Store changes made through
+// put/add/remove while fn runs are grouped under label. Nested calls join the
+// outer operation. When fn fails, the changes it already made are reverted
+// and nothing is logged.
+func (a *App) record(label string, fn func() error) error {
+	if a.pending != nil {
+		return fn()
+	}
+	a.pending = &store.Op{Label: label, At: time.Now()}
+	err := fn()
+	op := a.pending
+	a.pending = nil
+	if err != nil {
+		_ = a.replay(inverse(op.Changes), false)
+		return err
+	}
+	if len(op.Changes) == 0 {
+		return nil
+	}
+	err = a.updateLog(func(l *store.OpLog) {
+		l.Undo = append(l.Undo, *op)
+		if len(l.Undo) > maxUndo {
+			l.Undo = l.Undo[len(l.Undo)-maxUndo:]
+		}
+		l.Redo = nil
+	})
+	if cerr := a.commit(a.commitMessage(op.Label)); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// commit records a finished operation in version control when the store
+// supports it.
+func (a *App) commit(message string) error {
+	if c, ok := a.Store.(store.Committer); ok {
+		return c.Commit(message)
+	}
+	return nil
+}
+
+// encrypted reports whether the store seals bullet contents. Commit messages
+// are written in plain text, so they must not quote bullets then.
+func (a *App) encrypted() bool {
+	e, ok := a.Store.(store.Encrypter)
+	return ok && e.Encrypted()
+}
+
+// commitMessage is the commit message for an operation labelled label,
+// such as "complete: write report", cut down to the verb when the store is
+// encrypted.
+func (a *App) commitMessage(label string) string {
+	if verb, _, ok := strings.Cut(label, ": "); ok && a.encrypted() {
+		return verb
+	}
+	return label
+}
+
+// put updates b on day d of log, stamping it as modified and recording the
+// previous state.
+func (a *App) put(log string, d time.Time, b model.Bullet) error {
+	st, err := a.logStore(log)
+	if err != nil {
+		return err
+	}
+	items, err := st.LoadDay(d)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if items[i].ID == b.ID {
+			b.Touch(items[i], time.Now())
+			before, after := items[i], b
+			if err := st.Update(d, b); err != nil {
+				return err
+			}
+			a.note(store.Change{Log: log, Date: dateOnly(d), Index: i, Before: &before, After: &after})
+			return nil
+		}
+	}
+	return nil
+}
+
+// add appends b to day d of log, recording the insertion.
+func (a *App) add(log string, d time.Time, b model.Bullet) error {
+	if b.ID == "" {
+		b.ID = store.NewID()
+	}
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = time.Now()
+	}
+	st, err := a.logStore(log)
+	if err != nil {
+		return err
+	}
+	items, err := st.LoadDay(d)
+	if err != nil {
+		return err
+	}
+	if err := st.Append(d, b); err != nil {
+		return err
+	}
+	after := b
+	a.note(store.Change{Log: log, Date: dateOnly(d), Index: len(items), After: &after})
+	return nil
+}
+
+// insert puts b at position index of day d of log, saving the whole day,
+// and records the insertion.
+func (a *App) insert(log string, d time.Time, index int, b model.Bullet) error {
+	if err := a.apply(log, d, index, nil, &b); err != nil {
+		return err
+	}
+	after := b
+	a.note(store.Change{Log: log, Date: dateOnly(d), Index: index, After: &after})
+	return nil
+}
+
+// remove deletes the bullet with id from day d of log, recording its position.
+func (a *App) remove(log string, d time.Time, id string) error {
+	st, err := a.logStore(log)
+	if err != nil {
+		return err
+	}
+	items, err := st.LoadDay(d)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if items[i].ID == id {
+			before := items[i]
+			if err := st.Delete(d, id); err != nil {
+				return err
+			}
+			a.note(store.Change{Log: log, Date: dateOnly(d), Index: i, Before: &before})
+			return nil
+		}
+	}
+	return nil
+}
+
+func (a *App) note(c store.Change) {
+	if a.pending != nil {
+		a.pending.Changes = append(a.pending.Changes, c)
+	}
+}
+
+// Undo reverts the most recent operation and returns its label; the label is
+// empty when there is nothing to undo. The operation moves to the redo stack
+// only once all of its changes are reverted, so a failed undo can be retried.
+func (a *App) Undo() (string, error) {
+	l, err := a.loadOpLog()
+	if err != nil || len(l.Undo) == 0 {
+		return "", err
+	}
+	op := l.Undo[len(l.Undo)-1]
+	if err := a.replay(inverse(op.Changes), true); err != nil {
+		return op.Label, err
+	}
+	if err := a.updateLog(func(l *store.OpLog) { l.Undo, l.Redo = shiftOp(op, l.Undo, l.Redo) }); err != nil {
+		return op.Label, err
+	}
+	if err := a.commit("undo " + a.commitMessage(op.Label)); err != nil {
+		return op.Label, err
+	}
+	return op.Label, a.Refresh()
+}
+
+// Redo re-applies the most recently undone operation and returns its label.
+func (a *App) Redo() (string, error) {
+	l, err := a.loadOpLog()
+	if err != nil || len(l.Redo) == 0 {
+		return "", err
+	}
+	op := l.Redo[len(l.Redo)-1]
+	if err := a.replay(op.Changes, true); err != nil {
+		return op.Label, err
+	}
+	if err := a.updateLog(func(l *store.OpLog) { l.Redo, l.Undo = shiftOp(op, l.Redo, l.Undo) }); err != nil {
+		return op.Label, err
+	}
+	if err := a.commit("redo " + a.commitMessage(op.Label)); err != nil {
+		return op.Label, err
+	}
+	return op.Label, a.Refresh()
+}
+
+// shiftOp moves op from the top of from to the top of to. It leaves the
+// stacks alone when another process changed from in the meantime.
+func shiftOp(op store.Op, from, to []store.Op) ([]store.Op, []store.Op) {
+	if n := len(from); n > 0 && from[n-1].At.Equal(op.At) && from[n-1].Label == op.Label {
+		return from[:n-1], append(to, from[n-1])
+	}
+	return from, to
+}
+
+// replay applies changes in order, stamping the bullets it writes as
+// modified now when stamp is set, as undo and redo are changes of their own.
+// When one fails, the changes already applied are reverted again so the logs
+// are left as they were.
+func (a *App) replay(changes []store.Change, stamp bool) error {
+	for n, c := range changes {
+		var err error
+		if stamp {
+			c, err = a.stamp(c)
+		}
+		if err == nil {
+			err = a.apply(c.Log, c.Date, c.Index, c.Before, c.After)
+		}
+		if err != nil {
+			_ = a.replay(inverse(changes[:n]), false)
+			return err
+		}
+	}
+	return nil
+}
+
+// stamp returns c with the bullet it writes marked as modified now. A
+// replacement is touched against the bullet currently stored, so that
+// bullet's state is kept among the revisions.
+func (a *App) stamp(c store.Change) (store.Change, error) {
+	if c.After == nil {
+		return c, nil
+	}
+	b := *c.After
+	now := time.Now()
+	b.UpdatedAt = &now
+	if c.Before != nil {
+		st, err := a.logStore(c.Log)
+		if err != nil {
+			return c, err
+		}
+		items, err := st.LoadDay(c.Date)
+		if err != nil {
+			return c, err
+		}
+		if i := indexOfID(items, b.ID); i >= 0 {
+			b.Revisions = items[i].Revisions
+			b.Touch(items[i], now)
+		}
+	}
+	c.After = &b
+	return c, nil
+}
+
+// inverse returns the changes that revert changes, last first.
+func inverse(changes []store.Change) []store.Change {
+	out := make([]store.Change, len(changes))
+	for i, c := range changes {
+		c.Before, c.After = c.After, c.Before
+		out[len(changes)-1-i] = c
+	}
+	return out
+}
+
+// apply moves a bullet on day d of log from state from to state to: an
+// insertion at index when from is nil, a deletion when to is nil, otherwise a
+// replacement.
+func (a *App) apply(log string, d time.Time, index int, from, to *model.Bullet) error {
+	st, err := a.logStore(log)
+	if err != nil {
+		return err
+	}
+	switch {
+	case from == nil && to != nil:
+		if ins, ok := st.(store.Inserter); ok {
+			return ins.InsertAt(d, index, *to)
+		}
+		items, err := st.LoadDay(d)
+		if err != nil {
+			return err
+		}
+		if index < 0 || index > len(items) {
+			index = len(items)
+		}
+		return st.SaveDay(d, slices.Insert(items, index, *to))
+	case from != nil && to == nil:
+		return st.Delete(d, from.ID)
+	case to != nil:
+		return st.Update(d, *to)
+	}
+	return nil
+}
+
+// loadOpLog returns the persisted journal when the store supports it,
+// otherwise the in-memory one.
+func (a *App) loadOpLog() (store.OpLog, error) {
+	if lg, ok := a.Store.(store.OpLogger); ok {
+		return lg.LoadOpLog()
+	}
+	return a.log, nil
+}
+
+// updateLog applies fn to the persisted journal when the store supports it,
+// otherwise to an in-memory journal for the lifetime of the App.
+func (a *App) updateLog(fn func(l *store.OpLog)) error {
+	lg, ok := a.Store.(store.OpLogger)
+	if !ok {
+		fn(&a.log)
+		return nil
+	}
+	return lg.UpdateOpLog(func(l *store.OpLog) error {
+		fn(l)
+		return nil
+	})
+}
@@ -0,0 +1,300 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// newTestApp returns an App over an empty MemStore showing day, with
+// preferences kept in a temporary directory.
+func newTestApp(t *testing.T, st store.Store, day time.Time) *App {
+	t.Helper()
+	store.SetPreferencesDir(t.TempDir())
+	t.Cleanup(func() { store.SetPreferencesDir("") })
+	a := New(st)
+	if err := a.LoadDay(day); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// dump describes the bullets stored from day to the day after, one per
+// line, ignoring modification times.
+func dump(t *testing.T, st store.Store, day time.Time) string {
+	t.Helper()
+	days, err := st.LoadRange(day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	for _, d := range days {
+		for _, it := range d.Items {
+			fmt.Fprintf(&b, "%s %s %s %q parent=%s order=%d\n", d.Date.Format("01-02"), it.ID, it.Type, it.Text, it.ParentID, it.Order)
+		}
+	}
+	return b.String()
+}
+
+func TestUndoRedoRoundTrip(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		do   func(a *App) error
+	}{
+		{"add", func(a *App) error { _, err := a.Add("new"); return err }},
+		{"edit", func(a *App) error { return a.UpdateText(1, "second, edited") }},
+		{"complete", func(a *App) error { return a.CompleteIndex(0) }},
+		{"delete", func(a *App) error { return a.DeleteIndex(0) }},
+		{"migrate", func(a *App) error { return a.MigrateIndex(1) }},
+		{"add child", func(a *App) error { _, err := a.AddChild(0, "child"); return err }},
+		{"move down", func(a *App) error { _, err := a.MoveIndex(0, 1); return err }},
+		{"type", func(a *App) error { return a.ChangeTypeIndex(2, model.Event) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := store.NewMemStore()
+			a := newTestApp(t, st, day)
+			for _, text := range []string{"first", "second", "third"} {
+				if _, err := a.Add(text); err != nil {
+					t.Fatal(err)
+				}
+			}
+			before := dump(t, st, day)
+			if err := tt.do(a); err != nil {
+				t.Fatal(err)
+			}
+			after := dump(t, st, day)
+			if after == before {
+				t.Fatal("operation changed nothing")
+			}
+			if _, err := a.Undo(); err != nil {
+				t.Fatal(err)
+			}
+			if got := dump(t, st, day); got != before {
+				t.Errorf("after undo:\n%s\nwant:\n%s", got, before)
+			}
+			if _, err := a.Redo(); err != nil {
+				t.Fatal(err)
+			}
+			if got := dump(t, st, day); got != after {
+				t.Errorf("after redo:\n%s\nwant:\n%s", got, after)
+			}
+		})
+	}
+}
+
+func TestUndoNothing(t *testing.T) {
+	a := newTestApp(t, store.NewMemStore(), time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local))
+	for name, fn := range map[string]func() (string, error){"undo": a.Undo, "redo": a.Redo} {
+		if label, err := fn(); label != "" || err != nil {
+			t.Errorf("%s on an empty journal = %q, %v", name, label, err)
+		}
+	}
+}
+
+func TestRecordRollsBackOnError(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	st := store.NewMemStore()
+	a := newTestApp(t, st, day)
+	if _, err := a.Add("kept"); err != nil {
+		t.Fatal(err)
+	}
+	want := dump(t, st, day)
+	boom := errors.New("boom")
+	err := a.record("two adds", func() error {
+		if err := a.add(LogDaily, day, model.Bullet{Text: "dropped", Type: model.Task}); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("record = %v, want %v", err, boom)
+	}
+	if got := dump(t, st, day); got != want {
+		t.Errorf("after failed record:\n%s\nwant:\n%s", got, want)
+	}
+	if label, _ := a.Undo(); label != "add: kept" {
+		t.Errorf("undo after failed record undid %q, want the earlier add", label)
+	}
+}
+
+// flakyStore fails a Delete once deletes more have succeeded; it never
+// fails while deletes is negative.
+type flakyStore struct {
+	*store.MemStore
+	deletes int
+}
+
+func (s *flakyStore) Delete(date time.Time, id string) error {
+	if s.deletes == 0 {
+		s.deletes = -1
+		return errors.New("disk full")
+	}
+	if s.deletes > 0 {
+		s.deletes--
+	}
+	return s.MemStore.Delete(date, id)
+}
+
+func TestFailedUndoRedoCanBeRetried(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	st := &flakyStore{MemStore: store.NewMemStore(), deletes: -1}
+	a := newTestApp(t, st, day)
+	if _, err := a.Add("first"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AddChild(0, "child"); err != nil {
+		t.Fatal(err)
+	}
+	added := dump(t, st, day)
+	if err := a.DeleteIndex(0); err != nil {
+		t.Fatal(err)
+	}
+	deleted := dump(t, st, day)
+	if _, err := a.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Redoing the delete removes child, then first; fail on first.
+	st.deletes = 1
+	if _, err := a.Redo(); err == nil {
+		t.Fatal("redo succeeded despite the failing store")
+	}
+	if got := dump(t, st, day); got != added {
+		t.Errorf("after failed redo:\n%s\nwant:\n%s", got, added)
+	}
+	if _, err := a.Redo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := dump(t, st, day); got != deleted {
+		t.Errorf("after retried redo:\n%s\nwant:\n%s", got, deleted)
+	}
+
+	// Undoing the delete and then the child's add; fail the latter.
+	if _, err := a.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	st.deletes = 0
+	if _, err := a.Undo(); err == nil {
+		t.Fatal("undo succeeded despite the failing store")
+	}
+	if got := dump(t, st, day); got != added {
+		t.Errorf("after failed undo:\n%s\nwant:\n%s", got, added)
+	}
+	if label, err := a.Undo(); err != nil || label != "add: child" {
+		t.Fatalf("retried undo = %q, %v", label, err)
+	}
+}
+
+// commitStore records the messages committed to it, and reports itself as
+// encrypted when asked to.
+type commitStore struct {
+	*store.MemStore
+	encrypted bool
+	messages  []string
+}
+
+func (s *commitStore) Commit(message string) error {
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func (s *commitStore) Encrypted() bool { return s.encrypted }
+
+func TestCommitMessages(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	for _, encrypted := range []bool{false, true} {
+		st := &commitStore{MemStore: store.NewMemStore(), encrypted: encrypted}
+		a := newTestApp(t, st, day)
+		if _, err := a.Add("secret plans"); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.CompleteIndex(0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := a.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := a.Redo(); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"add: secret plans", "complete: secret plans", "undo complete: secret plans", "redo complete: secret plans"}
+		if encrypted {
+			want = []string{"add", "complete", "undo complete", "redo complete"}
+		}
+		if got := strings.Join(st.messages, "|"); got != strings.Join(want, "|") {
+			t.Errorf("encrypted %v: committed %q, want %q", encrypted, st.messages, want)
+		}
+	}
+}
+
+func TestUndoRedoStampBullets(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	st := store.NewMemStore()
+	a := newTestApp(t, st, day)
+	if _, err := a.Add("draft"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.UpdateText(0, "final"); err != nil {
+		t.Fatal(err)
+	}
+	stored := func() model.Bullet {
+		t.Helper()
+		items, err := st.LoadDay(day)
+		if err != nil || len(items) != 1 {
+			t.Fatalf("day holds %d bullets, %v", len(items), err)
+		}
+		return items[0]
+	}
+	revisions := func(b model.Bullet) string {
+		var texts []string
+		for _, r := range b.Revisions {
+			texts = append(texts, r.Text)
+		}
+		return strings.Join(texts, ",")
+	}
+	edited := *stored().UpdatedAt
+
+	steps := []struct {
+		name      string
+		do        func() (string, error)
+		text      string
+		revisions string
+	}{
+		{"undo", a.Undo, "draft", "draft,final"},
+		{"redo", a.Redo, "final", "draft,final,draft"},
+	}
+	last := edited
+	for _, s := range steps {
+		time.Sleep(time.Millisecond)
+		if _, err := s.do(); err != nil {
+			t.Fatal(err)
+		}
+		b := stored()
+		if b.Text != s.text || revisions(b) != s.revisions {
+			t.Errorf("after %s: %q with revisions %q, want %q with %q", s.name, b.Text, revisions(b), s.text, s.revisions)
+		}
+		if b.UpdatedAt == nil || !b.UpdatedAt.After(last) {
+			t.Errorf("after %s: updated at %v, want after %v", s.name, b.UpdatedAt, last)
+		} else {
+			last = *b.UpdatedAt
+		}
+	}
+
+	// A bullet brought back by undoing its deletion is stamped too.
+	if err := a.DeleteIndex(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if b := stored(); b.UpdatedAt == nil || !b.UpdatedAt.After(last) {
+		t.Errorf("restored bullet updated at %v, want after %v", b.UpdatedAt, last)
+	}
+}
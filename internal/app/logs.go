@@ -0,0 +1,171 @@
+package app
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// Log names identify the collection a bullet lives in. Named collections use
+// store.CollectionRef(name).
+const (
+	LogDaily   = ""
+	LogMonthly = "month"
+	LogFuture  = "future"
+	LogTrash   = "trash"
+)
+
+// ErrNoLogs is returned when the store cannot hold monthly or future logs.
+var ErrNoLogs = errors.New("store does not support monthly or future logs")
+
+// logStore returns the store backing the named log.
+func (a *App) logStore(log string) (store.Store, error) {
+	if log == LogDaily {
+		return a.Store, nil
+	}
+	if name, ok := CollectionName(log); ok {
+		cs, ok := a.Store.(store.CollectionStore)
+		if !ok {
+			return nil, ErrNoCollections
+		}
+		return cs.Collection(name), nil
+	}
+	if log == LogTrash {
+		ts, ok := a.Store.(store.TrashStore)
+		if !ok {
+			return nil, ErrNoTrash
+		}
+		return ts.Trash(), nil
+	}
+	ls, ok := a.Store.(store.LogStore)
+	if !ok {
+		return nil, ErrNoLogs
+	}
+	switch log {
+	case LogMonthly:
+		return ls.MonthLog(), nil
+	case LogFuture:
+		return ls.FutureLog(), nil
+	}
+	return nil, errors.New("unknown log " + log)
+}
+
+func (a *App) loadLog(log string, d time.Time) ([]model.Bullet, error) {
+	st, err := a.logStore(log)
+	if err != nil {
+		return nil, err
+	}
+	return st.LoadDay(d)
+}
+
+// refreshLog loads the monthly log for the current month, the open
+// collection, or the whole future log grouped by target month.
+func (a *App) refreshLog() error {
+	log, d := LogMonthly, monthStart(a.CurrentDate)
+	switch a.Period {
+	case model.PeriodFuture:
+		log = LogFuture
+	case model.PeriodCollection:
+		log, d = CollectionLog(a.Collection), time.Time{}
+	}
+	items, err := a.loadLog(log, d)
+	if err != nil {
+		return err
+	}
+	var entries []Entry
+	if log == LogFuture {
+		months := map[string][]model.Bullet{}
+		var keys []string
+		for _, it := range items {
+			if _, ok := months[it.Month]; !ok {
+				keys = append(keys, it.Month)
+			}
+			months[it.Month] = append(months[it.Month], it)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			// Children carry their parent's month, so each group is a whole tree.
+			for _, e := range treeOrder(log, d, months[k]) {
+				if t, err := time.ParseInLocation("2006-01", k, time.Local); err == nil {
+					e.Date = t
+				}
+				entries = append(entries, e)
+			}
+		}
+	} else {
+		entries = treeOrder(log, d, items)
+	}
+	a.Items = sortEntries(entries, a.Sort)
+	return nil
+}
+
+// AddToLog adds b to the monthly or future log for month. Future-log items
+// record the month they are due in.
+func (a *App) AddToLog(log string, month time.Time, b model.Bullet) (model.Bullet, error) {
+	m := monthStart(month)
+	if b.ID == "" {
+		b.ID = store.NewID()
+	}
+	if log == LogFuture {
+		b.Month = m.Format("2006-01")
+	}
+	if err := a.record(opLabel("add", b), func() error { return a.add(log, m, b) }); err != nil {
+		return model.Bullet{}, err
+	}
+	return b, a.Refresh()
+}
+
+// LoadLog returns the bullets stored for day d of the named log.
+func (a *App) LoadLog(log string, d time.Time) ([]model.Bullet, error) {
+	return a.loadLog(log, d)
+}
+
+// PullFuture migrates open future-log items due in or before month into that
+// month's log, as done when setting up a new month. It returns the number of
+// items pulled.
+func (a *App) PullFuture(month time.Time) (int, error) {
+	m := monthStart(month)
+	key := m.Format("2006-01")
+	items, err := a.loadLog(LogFuture, m)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = a.record("pull future log: "+key, func() error {
+		for _, it := range items {
+			if it.ParentID != "" || it.Month > key {
+				continue
+			}
+			if it.Type != model.Task && it.Type != model.Event {
+				continue
+			}
+			if err := a.link(LogFuture, m, it, model.Migrated, LogMonthly, m); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, a.Refresh()
+}
+
+func monthStart(d time.Time) time.Time {
+	return time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, d.Location())
+}
+
+// futureMonth is the monthly log a future-log item migrates into: its target
+// month, or now if that has already passed or is unset.
+func futureMonth(it model.Bullet, now time.Time) time.Time {
+	cur := monthStart(dateOnly(now))
+	t, err := time.ParseInLocation("2006-01", it.Month, time.Local)
+	if err != nil || t.Before(cur) {
+		return cur
+	}
+	return t
+}
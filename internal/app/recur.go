@@ -0,0 +1,203 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+var errNoRecurrences = errors.New("store does not support recurring items")
+
+// Recurrences returns all recurrence rules.
+func (a *App) Recurrences() ([]model.Recurrence, error) {
+	rs, ok := a.Store.(store.RecurrenceStore)
+	if !ok {
+		return nil, errNoRecurrences
+	}
+	return rs.LoadRecurrences()
+}
+
+// AddRecurrence stores a new rule, assigning an ID and defaulting the type to
+// Task and the start to today, then generates its instances up to today.
+func (a *App) AddRecurrence(r model.Recurrence) (model.Recurrence, error) {
+	rs, ok := a.Store.(store.RecurrenceStore)
+	if !ok {
+		return model.Recurrence{}, errNoRecurrences
+	}
+	if strings.TrimSpace(r.Text) == "" {
+		return model.Recurrence{}, errors.New("recurrence text is empty")
+	}
+	if r.ID == "" {
+		r.ID = store.NewID()
+	}
+	if r.Type == "" {
+		r.Type = model.Task
+	}
+	if r.Start.IsZero() {
+		r.Start = time.Now()
+	}
+	r.Start = dateOnly(r.Start)
+	err := rs.UpdateRecurrences(func(list []model.Recurrence) ([]model.Recurrence, []store.Day, error) {
+		return append(list, r), nil, nil
+	})
+	if err != nil {
+		return model.Recurrence{}, err
+	}
+	if err := a.commit(a.commitMessage("add recurrence: " + r.Text)); err != nil {
+		return r, err
+	}
+	return r, a.Refresh()
+}
+
+// RemoveRecurrence deletes the rule with the given ID or unique ID prefix.
+// Instances already generated stay in their day logs.
+func (a *App) RemoveRecurrence(ref string) error {
+	rs, ok := a.Store.(store.RecurrenceStore)
+	if !ok {
+		return errNoRecurrences
+	}
+	var text string
+	err := rs.UpdateRecurrences(func(list []model.Recurrence) ([]model.Recurrence, []store.Day, error) {
+		match := -1
+		for i, r := range list {
+			if r.ID == ref {
+				match = i
+				break
+			}
+			if strings.HasPrefix(r.ID, ref) {
+				if match >= 0 {
+					return nil, nil, fmt.Errorf("%w: %q", store.ErrAmbiguousID, ref)
+				}
+				match = i
+			}
+		}
+		if ref == "" || match < 0 {
+			return nil, nil, fmt.Errorf("no recurrence matches id %q", ref)
+		}
+		text = list[match].Text
+		return slices.Delete(list, match, match+1), nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	return a.commit(a.commitMessage("remove recurrence: " + text))
+}
+
+// materialize generates the instances each rule has due from the day after
+// its latest instance up to today, including days that were never viewed.
+// Future days are left alone until they come, and an instance that was
+// deleted is not brought back. Instances are plain bullets tagged with their
+// rule's RecurrenceID; they are not recorded in the undo journal. The rules
+// are checked without the lock first, so a refresh with nothing due writes
+// nothing.
+func (a *App) materialize() error {
+	rs, ok := a.Store.(store.RecurrenceStore)
+	if !ok {
+		return nil
+	}
+	list, err := rs.LoadRecurrences()
+	if err != nil || len(list) == 0 {
+		return err
+	}
+	today := dateOnly(time.Now())
+	if _, add, err := a.dueInstances(list, today); err != nil || len(add) == 0 {
+		return err
+	}
+	generated := false
+	err = rs.UpdateRecurrences(func(list []model.Recurrence) ([]model.Recurrence, []store.Day, error) {
+		list, add, err := a.dueInstances(list, today)
+		generated = len(add) > 0
+		return list, add, err
+	})
+	if err != nil || !generated {
+		return err
+	}
+	return a.commit("generate recurring items")
+}
+
+// dueInstances returns the instances of the rules in list that are due up
+// to today, one Day each, and a copy of list with each rule's latest
+// instance moved on to them.
+func (a *App) dueInstances(list []model.Recurrence, today time.Time) ([]model.Recurrence, []store.Day, error) {
+	list = slices.Clone(list)
+	var add []store.Day
+	for i := range list {
+		r := &list[i]
+		from := dateOnly(r.Start)
+		if r.LastDate != nil && !from.After(dateOnly(*r.LastDate)) {
+			from = dateOnly(*r.LastDate).AddDate(0, 0, 1)
+		}
+		var days []time.Time
+		if r.Freq == model.RecurAfter {
+			d, ok, err := a.dueAfterCompletion(r, from, today)
+			if err != nil {
+				return nil, nil, err
+			}
+			if ok {
+				days = append(days, d)
+			}
+		} else {
+			for d := from; !d.After(today); d = d.AddDate(0, 0, 1) {
+				if r.Occurs(d) {
+					days = append(days, d)
+				}
+			}
+		}
+		for _, d := range days {
+			b := model.Bullet{ID: store.NewID(), Type: r.Type, Text: r.Text, Tags: r.Tags, RecurrenceID: r.ID, CreatedAt: time.Now()}
+			add = append(add, store.Day{Date: d, Items: []model.Bullet{b}})
+			day := d
+			r.LastDate, r.LastID = &day, b.ID
+		}
+	}
+	return list, add, nil
+}
+
+// dueAfterCompletion returns the day from which on an "after N days" rule
+// has its next instance due, no earlier than from, and whether that day has
+// come by today: the first instance is due on its start date, later ones
+// once the previous instance is completed and N days have passed.
+func (a *App) dueAfterCompletion(r *model.Recurrence, from, today time.Time) (time.Time, bool, error) {
+	due := func(d time.Time) (time.Time, bool, error) {
+		if d.Before(from) {
+			d = from
+		}
+		return d, !d.After(today), nil
+	}
+	if r.LastDate == nil {
+		return due(from)
+	}
+	// Follow migrations/schedulings to wherever the instance lives now.
+	day, id := dateOnly(*r.LastDate), r.LastID
+	for hops := 0; hops < 100; hops++ {
+		items, err := a.Store.LoadDay(day)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		var it *model.Bullet
+		for i := range items {
+			if items[i].ID == id {
+				it = &items[i]
+				break
+			}
+		}
+		if it == nil {
+			// Previous instance deleted: continue the cadence from its date.
+			return due(day.AddDate(0, 0, r.Interval))
+		}
+		if (it.Type == model.Migrated || it.Type == model.Scheduled) && it.CloneID != "" && it.ScheduledFor != nil {
+			day, id = dateOnly(*it.ScheduledFor), it.CloneID
+			continue
+		}
+		if it.Type != model.Done || it.CompletedAt == nil {
+			return time.Time{}, false, nil
+		}
+		return due(dateOnly(*it.CompletedAt).AddDate(0, 0, r.Interval))
+	}
+	return time.Time{}, false, nil
+}
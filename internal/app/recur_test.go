@@ -0,0 +1,125 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// recurStore keeps recurrence rules in memory next to a MemStore and counts
+// how often they are saved.
+type recurStore struct {
+	*store.MemStore
+	rules []model.Recurrence
+	saves int
+}
+
+func (s *recurStore) LoadRecurrences() ([]model.Recurrence, error) {
+	return append([]model.Recurrence(nil), s.rules...), nil
+}
+
+func (s *recurStore) SaveRecurrences(rs []model.Recurrence) error {
+	s.rules = append([]model.Recurrence(nil), rs...)
+	s.saves++
+	return nil
+}
+
+func (s *recurStore) UpdateRecurrences(fn func(rs []model.Recurrence) ([]model.Recurrence, []store.Day, error)) error {
+	rs, add, err := fn(append([]model.Recurrence(nil), s.rules...))
+	if err != nil {
+		return err
+	}
+	for _, d := range add {
+		for _, b := range d.Items {
+			if err := s.Append(d.Date, b); err != nil {
+				return err
+			}
+		}
+	}
+	return s.SaveRecurrences(rs)
+}
+
+// instances counts the bullets generated by rule id from start to end.
+func instances(t *testing.T, st store.Store, start, end time.Time, id string) int {
+	t.Helper()
+	days, err := st.LoadRange(start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, d := range days {
+		for _, b := range d.Items {
+			if b.RecurrenceID == id {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestMaterialize(t *testing.T) {
+	today := dateOnly(time.Now())
+	first := monthStart(today)
+	st := &recurStore{MemStore: store.NewMemStore()}
+	a := newTestApp(t, st, today.AddDate(0, 2, 0))
+	r, err := a.AddRecurrence(model.Recurrence{Text: "water plants", Freq: model.RecurDaily, Start: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Adding the rule generates the days up to today even while a future
+	// day is viewed, and none after.
+	far := today.AddDate(1, 0, 0)
+	if n, want := instances(t, st, first, far, r.ID), today.Day(); n != want {
+		t.Fatalf("adding the rule generated %d instances, want %d", n, want)
+	}
+	saves := st.saves
+
+	// A deleted instance stays deleted, and browsing changes nothing.
+	if err := a.JumpToDate(today); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.DeleteIndex(0); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []time.Time{today, first, today.AddDate(0, 0, 7), today.AddDate(0, 3, 0)} {
+		if err := a.JumpToDate(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, want := instances(t, st, first, far, r.ID), today.Day()-1; n != want {
+		t.Errorf("after deleting today's instance there are %d, want %d", n, want)
+	}
+	if st.saves != saves {
+		t.Errorf("browsing saved the rules %d more times", st.saves-saves)
+	}
+	if got := st.rules[0]; got.LastDate == nil || !got.LastDate.Equal(today) {
+		t.Errorf("rule after generating: last %v", got.LastDate)
+	}
+}
+
+func TestMaterializeCatchesUp(t *testing.T) {
+	today := dateOnly(time.Now())
+	start := today.AddDate(0, 0, -20)
+	last := today.AddDate(0, 0, -10)
+	st := &recurStore{MemStore: store.NewMemStore(), rules: []model.Recurrence{
+		// A rule whose days were never viewed, and one last generated ten days ago.
+		{ID: "new", Text: "stretch", Type: model.Task, Freq: model.RecurDaily, Start: start},
+		{ID: "old", Text: "review", Type: model.Task, Freq: model.RecurDaily, Start: start, LastDate: &last},
+	}}
+	a := newTestApp(t, st, today.AddDate(1, 0, 0))
+	if err := a.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	far := today.AddDate(1, 0, 0)
+	if n := instances(t, st, start, far, "new"); n != 21 {
+		t.Errorf("never viewed rule has %d instances, want 21", n)
+	}
+	if n := instances(t, st, start, far, "old"); n != 10 {
+		t.Errorf("rule generated up to ten days ago has %d new instances, want 10", n)
+	}
+	if n := instances(t, st, start, last, "old"); n != 0 {
+		t.Errorf("%d instances regenerated on or before the last date", n)
+	}
+}
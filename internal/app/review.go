@@ -0,0 +1,126 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// ReviewDecision is what to do with an open task during a migration review.
+type ReviewDecision string
+
+const (
+	ReviewMigrate  ReviewDecision = "migrate"  // carry forward to the next day (at least today)
+	ReviewSchedule ReviewDecision = "schedule" // schedule for a given day
+	ReviewFuture   ReviewDecision = "future"   // move to the future log for a given month
+	ReviewCancel   ReviewDecision = "cancel"   // no longer relevant
+	ReviewDone     ReviewDecision = "done"
+	ReviewSkip     ReviewDecision = "skip"
+)
+
+// reviewOrder lists decisions in the order used for summaries.
+var reviewOrder = []ReviewDecision{ReviewMigrate, ReviewSchedule, ReviewFuture, ReviewCancel, ReviewDone, ReviewSkip}
+
+// ReviewCounts tallies the decisions taken during a review.
+type ReviewCounts map[ReviewDecision]int
+
+func (c ReviewCounts) String() string {
+	var parts []string
+	for _, d := range reviewOrder {
+		if n := c[d]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, reviewPhrase[d]))
+		}
+	}
+	if len(parts) == 0 {
+		return "nothing decided"
+	}
+	return strings.Join(parts, ", ")
+}
+
+var reviewPhrase = map[ReviewDecision]string{
+	ReviewMigrate:  "migrated",
+	ReviewSchedule: "scheduled",
+	ReviewFuture:   "to future log",
+	ReviewCancel:   "cancelled",
+	ReviewDone:     "done",
+	ReviewSkip:     "skipped",
+}
+
+// ReviewItems returns the open tasks in the daily logs of rng, oldest first.
+// Sub-tasks of open tasks are left out: they follow their parent's decision.
+func (a *App) ReviewItems(rng model.DateRange) ([]Entry, error) {
+	days, err := a.Store.LoadRange(rng.Start, rng.End)
+	if err != nil {
+		return nil, err
+	}
+	var out []Entry
+	for _, day := range days {
+		d, items := day.Date, day.Items
+		open := map[string]bool{}
+		for _, it := range items {
+			if it.Type == model.Task {
+				open[it.ID] = true
+			}
+		}
+		for _, e := range treeOrder(LogDaily, d, items) {
+			if e.Item.Type == model.Task && !open[e.Item.ParentID] {
+				out = append(out, e)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Range returns the dates covered by the current period.
+func (a *App) Range() model.DateRange { return a.dateRange() }
+
+// Review applies decision to the open task in e. target is the day for
+// ReviewSchedule and the month for ReviewFuture; it is ignored otherwise.
+// Items that are no longer open tasks are left alone.
+func (a *App) Review(e Entry, decision ReviewDecision, target time.Time) error {
+	items, err := a.loadLog(e.Log, e.Date)
+	if err != nil {
+		return err
+	}
+	i, err := findRef(items, e.Item.ID)
+	if err != nil || items[i].Type != model.Task {
+		return nil
+	}
+	it := items[i]
+	switch decision {
+	case ReviewMigrate:
+		next := e.Date.AddDate(0, 0, 1)
+		if today := dateOnly(time.Now()); next.Before(today) {
+			next = today
+		}
+		return a.record(opLabel("migrate", it), func() error {
+			return a.link(e.Log, e.Date, it, model.Migrated, LogDaily, next)
+		})
+	case ReviewSchedule:
+		return a.schedule(e.Log, e.Date, it, target)
+	case ReviewFuture:
+		m := monthStart(target)
+		return a.record(opLabel("migrate", it), func() error {
+			return a.link(e.Log, e.Date, it, model.Migrated, LogFuture, m)
+		})
+	case ReviewCancel:
+		return a.cancel(e.Log, e.Date, it)
+	case ReviewDone:
+		return a.complete(e.Log, e.Date, it)
+	}
+	return nil
+}
+
+// FinishReview records the outcome of a review of title (e.g. "October
+// 2026") as a note in today's log and returns its text.
+func (a *App) FinishReview(title string, counts ReviewCounts) (string, error) {
+	text := "Review of " + title + ": " + counts.String()
+	b := model.Bullet{ID: store.NewID(), Type: model.Note, Text: text, CreatedAt: time.Now()}
+	if err := a.record(opLabel("add", b), func() error { return a.add(LogDaily, dateOnly(time.Now()), b) }); err != nil {
+		return "", err
+	}
+	return text, a.Refresh()
+}
@@ -0,0 +1,227 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// SortMode orders the bullets of each day in the views. Sorting only
+// affects display: siblings are sorted together with their sub-items, and
+// ties keep the manual order.
+type SortMode string
+
+const (
+	SortManual  SortMode = ""        // file order, changed with MoveIndex
+	SortType    SortMode = "type"    // tasks first, then events, notes, and closed items
+	SortCreated SortMode = "created" // oldest first
+	SortTag     SortMode = "tag"     // by first tag, untagged last
+	SortOpen    SortMode = "open"    // open tasks first
+)
+
+// SortModes lists the modes in the order the TUI cycles through them.
+var SortModes = []SortMode{SortManual, SortType, SortCreated, SortTag, SortOpen}
+
+// ParseSortMode parses a --sort value; "manual" and "" are the manual order.
+func ParseSortMode(s string) (SortMode, error) {
+	switch m := SortMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "manual", SortManual:
+		return SortManual, nil
+	case SortType, SortCreated, SortTag, SortOpen:
+		return m, nil
+	}
+	return SortManual, fmt.Errorf("unknown sort %q (manual|type|created|tag|open)", s)
+}
+
+// String names the mode as accepted by ParseSortMode.
+func (m SortMode) String() string {
+	if m == SortManual {
+		return "manual"
+	}
+	return string(m)
+}
+
+// SetSort changes the sort mode of the views.
+func (a *App) SetSort(m SortMode) error {
+	a.Sort = m
+	a.SavePrefs()
+	return a.Refresh()
+}
+
+// typeRank orders bullet types for SortType.
+var typeRank = map[model.BulletType]int{
+	model.HighlightImportant:   0,
+	model.Task:                 1,
+	model.Event:                2,
+	model.Note:                 3,
+	model.HighlightInspiration: 4,
+	model.Scheduled:            5,
+	model.Migrated:             6,
+	model.Done:                 7,
+	model.Cancelled:            8,
+}
+
+// sortLess reports whether a sorts before b under mode.
+func sortLess(a, b model.Bullet, mode SortMode) bool {
+	switch mode {
+	case SortType:
+		return typeRank[a.Type] < typeRank[b.Type]
+	case SortCreated:
+		return a.CreatedAt.Before(b.CreatedAt)
+	case SortTag:
+		ta, tb := firstTag(a), firstTag(b)
+		if ta == "" || tb == "" {
+			return ta != "" && tb == ""
+		}
+		return ta < tb
+	case SortOpen:
+		return a.Type == model.Task && b.Type != model.Task
+	}
+	return false
+}
+
+// firstTag returns b's alphabetically first tag, normalized.
+func firstTag(b model.Bullet) string {
+	first := ""
+	for _, t := range b.Tags {
+		t = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(t)), "#")
+		if t != "" && (first == "" || t < first) {
+			first = t
+		}
+	}
+	return first
+}
+
+// sortEntries sorts entries, made of runs of one day's bullets in tree
+// order, by mode within each run.
+func sortEntries(entries []Entry, mode SortMode) []Entry {
+	if mode == SortManual {
+		return entries
+	}
+	out := make([]Entry, 0, len(entries))
+	for start := 0; start < len(entries); {
+		end := start + 1
+		for end < len(entries) && entries[end].Log == entries[start].Log && entries[end].Date.Equal(entries[start].Date) {
+			end++
+		}
+		out = append(out, sortSiblings(entries[start:end], mode)...)
+		start = end
+	}
+	return out
+}
+
+// sortSiblings sorts the bullets at the depth of entries[0], each followed
+// by its sub-items, which are sorted in turn.
+func sortSiblings(entries []Entry, mode SortMode) []Entry {
+	if len(entries) == 0 {
+		return nil
+	}
+	depth := entries[0].Depth
+	var blocks [][]Entry
+	for _, e := range entries {
+		if e.Depth <= depth || len(blocks) == 0 {
+			blocks = append(blocks, []Entry{e})
+			continue
+		}
+		blocks[len(blocks)-1] = append(blocks[len(blocks)-1], e)
+	}
+	sort.SliceStable(blocks, func(i, j int) bool { return sortLess(blocks[i][0].Item, blocks[j][0].Item, mode) })
+	out := make([]Entry, 0, len(entries))
+	for _, b := range blocks {
+		out = append(out, b[0])
+		out = append(out, sortSiblings(b[1:], mode)...)
+	}
+	return out
+}
+
+// MoveIndex moves the visible item at index, with its sub-items, delta
+// places among its siblings on the same day (-1 up, +1 down) and returns
+// its new visible index. It swaps the bullet's file position and Order with
+// the sibling it passes and saves the day, as one undoable operation.
+// Moving past the first or last sibling does nothing.
+func (a *App) MoveIndex(index, delta int) (int, error) {
+	vis := a.Visible()
+	if index < 0 || index >= len(vis) || delta == 0 {
+		return index, nil
+	}
+	e := vis[index]
+	items, err := a.loadLog(e.Log, e.Date)
+	if err != nil {
+		return index, err
+	}
+	// Siblings share the parent (top-level bullets include orphans, as in
+	// treeOrder) and, in the future log, the month.
+	tree := treeOrder(e.Log, e.Date, items)
+	parentOf := func(te Entry) string {
+		if te.Depth == 0 {
+			return ""
+		}
+		return te.Item.ParentID
+	}
+	parent := ""
+	for _, te := range tree {
+		if te.Item.ID == e.Item.ID {
+			parent = parentOf(te)
+		}
+	}
+	var sibs []model.Bullet
+	at := -1
+	for _, te := range tree {
+		if parentOf(te) != parent || te.Item.Month != e.Item.Month {
+			continue
+		}
+		if te.Item.ID == e.Item.ID {
+			at = len(sibs)
+		}
+		sibs = append(sibs, te.Item)
+	}
+	to := at + delta
+	if at < 0 || to < 0 || to >= len(sibs) {
+		return index, nil
+	}
+	i, j := indexOfID(items, e.Item.ID), indexOfID(items, sibs[to].ID)
+	if i > j {
+		i, j = j, i
+	}
+	first, second := items[j], items[i] // the bullets that end up at i and j
+	first.Order, second.Order = items[i].Order, items[j].Order
+	label := opLabel("move up", e.Item)
+	if delta > 0 {
+		label = opLabel("move down", e.Item)
+	}
+	err = a.record(label, func() error {
+		d := dateOnly(e.Date)
+		for _, id := range []string{items[j].ID, items[i].ID} {
+			if err := a.remove(e.Log, d, id); err != nil {
+				return err
+			}
+		}
+		if err := a.insert(e.Log, d, i, first); err != nil {
+			return err
+		}
+		return a.insert(e.Log, d, j, second)
+	})
+	if err != nil {
+		return index, err
+	}
+	if err := a.Refresh(); err != nil {
+		return index, err
+	}
+	for k, v := range a.Visible() {
+		if v.Item.ID == e.Item.ID {
+			return k, nil
+		}
+	}
+	return index, nil
+}
+
+func indexOfID(items []model.Bullet, id string) int {
+	for i := range items {
+		if items[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+func TestParseSortMode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want SortMode
+		ok   bool
+	}{
+		{"", SortManual, true},
+		{"manual", SortManual, true},
+		{" Type ", SortType, true},
+		{"created", SortCreated, true},
+		{"tag", SortTag, true},
+		{"OPEN", SortOpen, true},
+		{"alpha", SortManual, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSortMode(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseSortMode(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	mar10, mar11 := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local), time.Date(2026, 3, 11, 0, 0, 0, 0, time.Local)
+	e := func(d time.Time, depth int, text string, typ model.BulletType, created int, tags ...string) Entry {
+		return Entry{Date: d, Log: LogDaily, Depth: depth, Item: model.Bullet{
+			Text: text, Type: typ, Tags: tags, CreatedAt: mar10.Add(time.Duration(created) * time.Minute),
+		}}
+	}
+	// One day's tree followed by a second day, in file order.
+	entries := []Entry{
+		e(mar10, 0, "done", model.Done, 4, "zeta"),
+		e(mar10, 0, "note", model.Note, 1),
+		e(mar10, 1, "note-task", model.Task, 6, "beta"),
+		e(mar10, 1, "note-event", model.Event, 0, "#Alpha"),
+		e(mar10, 0, "task", model.Task, 3, "beta", "alpha"),
+		e(mar10, 0, "event", model.Event, 2),
+		e(mar11, 0, "next-note", model.Note, 5),
+		e(mar11, 0, "next-task", model.Task, 7),
+	}
+	tests := []struct {
+		mode SortMode
+		want string
+	}{
+		{SortManual, "done note >note-task >note-event task event | next-note next-task"},
+		{SortType, "task event note >note-task >note-event done | next-task next-note"},
+		{SortCreated, "note >note-event >note-task event task done | next-note next-task"},
+		{SortTag, "task done note >note-event >note-task event | next-note next-task"},
+		{SortOpen, "task done note >note-task >note-event event | next-task next-note"},
+	}
+	for _, tt := range tests {
+		sorted := sortEntries(entries, tt.mode)
+		var parts []string
+		for i, it := range sorted {
+			if i > 0 && !it.Date.Equal(sorted[i-1].Date) {
+				parts = append(parts, "|")
+			}
+			parts = append(parts, strings.Repeat(">", it.Depth)+it.Item.Text)
+		}
+		if got := strings.Join(parts, " "); got != tt.want {
+			t.Errorf("sortEntries(%s) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMoveIndex(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	// a, b with children b1 and b2, c; visible as a b >b1 >b2 c.
+	setup := func(t *testing.T) *App {
+		a := newTestApp(t, store.NewMemStore(), day)
+		for _, step := range []struct {
+			parent int // visible index, -1 for top-level
+			text   string
+		}{{-1, "a"}, {-1, "b"}, {1, "b1"}, {1, "b2"}, {-1, "c"}} {
+			var err error
+			if step.parent < 0 {
+				_, err = a.Add(step.text)
+			} else {
+				_, err = a.AddChild(step.parent, step.text)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return a
+	}
+	show := func(a *App) string {
+		var parts []string
+		for _, e := range a.Visible() {
+			parts = append(parts, strings.Repeat(">", e.Depth)+e.Item.Text)
+		}
+		return strings.Join(parts, " ")
+	}
+	const start = "a b >b1 >b2 c"
+	tests := []struct {
+		name         string
+		index, delta int
+		want         string
+		at           int
+	}{
+		{"first down", 0, 1, "b >b1 >b2 a c", 3},
+		{"last up", 4, -1, "a c b >b1 >b2", 1},
+		{"parent down with children", 1, 1, "a c b >b1 >b2", 2},
+		{"parent up with children", 1, -1, "b >b1 >b2 a c", 0},
+		{"child down", 2, 1, "a b >b2 >b1 c", 3},
+		{"child up", 3, -1, "a b >b2 >b1 c", 2},
+		{"first up", 0, -1, start, 0},
+		{"last down", 4, 1, start, 4},
+		{"first child up", 2, -1, start, 2},
+		{"last child down", 3, 1, start, 3},
+		{"no delta", 1, 0, start, 1},
+		{"out of range", 9, 1, start, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := setup(t)
+			at, err := a.MoveIndex(tt.index, tt.delta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := show(a); got != tt.want || at != tt.at {
+				t.Errorf("MoveIndex(%d, %d) = %q at %d, want %q at %d", tt.index, tt.delta, got, at, tt.want, tt.at)
+			}
+			if tt.want == start {
+				return
+			}
+			if _, err := a.Undo(); err != nil {
+				t.Fatal(err)
+			}
+			if got := show(a); got != start {
+				t.Errorf("after undo = %q, want %q", got, start)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// ErrNoTrash is returned when the store deletes bullets outright.
+var ErrNoTrash = errors.New("store does not keep a trash")
+
+// DefaultTrashDays is how long deleted bullets stay in the trash when the
+// preferences do not set a retention.
+const DefaultTrashDays = 30
+
+// trashed returns b as stored in the trash after deleting it from day d of
+// log at now.
+func trashed(b model.Bullet, log string, d time.Time, now time.Time) model.Bullet {
+	del := &model.Deletion{At: now, Log: log}
+	if !d.IsZero() {
+		day := dateOnly(d)
+		del.Date = &day
+	}
+	b.Deleted = del
+	return b
+}
+
+// DeletedFrom names where a trashed bullet was deleted from, e.g. "day
+// 2024-03-01" or "collection ideas".
+func DeletedFrom(b model.Bullet) string {
+	if b.Deleted == nil {
+		return "unknown"
+	}
+	e := Entry{Log: b.Deleted.Log}
+	if b.Deleted.Date != nil {
+		e.Date = *b.Deleted.Date
+	}
+	return logName(e)
+}
+
+// Trash returns the bullets in the trash, most recently deleted first.
+func (a *App) Trash() ([]model.Bullet, error) {
+	items, err := a.loadLog(LogTrash, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	return items, nil
+}
+
+// Restore moves the trashed bullet with the given ID (or unique ID prefix),
+// and any of its sub-items in the trash, back to the end of the day or log
+// it was deleted from. The restore is undoable.
+func (a *App) Restore(ref string) (model.Bullet, error) {
+	items, err := a.loadLog(LogTrash, time.Time{})
+	if err != nil {
+		return model.Bullet{}, err
+	}
+	idx, err := findRef(items, ref)
+	if err != nil {
+		return model.Bullet{}, err
+	}
+	it := items[idx]
+	err = a.record(opLabel("restore", it), func() error {
+		for _, b := range subtree(items, it) {
+			del := b.Deleted
+			b.Deleted = nil
+			var d time.Time
+			log := LogDaily
+			if del != nil {
+				log = del.Log
+				if del.Date != nil {
+					d = *del.Date
+				}
+			}
+			if err := a.add(log, d, b); err != nil {
+				return err
+			}
+			if err := a.remove(LogTrash, time.Time{}, b.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return it, err
+	}
+	return it, a.Refresh()
+}
+
+// EmptyTrash deletes everything in the trash for good and returns how many
+// bullets it held. This is not undoable.
+func (a *App) EmptyTrash() (int, error) {
+	return a.dropTrash("empty trash", func(model.Bullet) bool { return true })
+}
+
+// TrashDays returns the trash retention in days from the preferences;
+// zero or less means deleted bullets are kept until the trash is emptied.
+func TrashDays() int {
+	p, _ := store.LoadPreferences()
+	switch {
+	case p.TrashDays == 0:
+		return DefaultTrashDays
+	case p.TrashDays < 0:
+		return 0
+	}
+	return p.TrashDays
+}
+
+// SetTrashDays stores the trash retention in days; zero or less keeps
+// deleted bullets until the trash is emptied.
+func SetTrashDays(days int) error {
+	if days <= 0 {
+		days = -1
+	}
+	return store.UpdatePreferences(func(p *store.Preferences) { p.TrashDays = days })
+}
+
+// PurgeTrash deletes for good the bullets deleted longer ago than the
+// retention and returns how many it removed. It is called after every
+// delete; stores without a trash have nothing to purge.
+func (a *App) PurgeTrash(now time.Time) (int, error) {
+	days := TrashDays()
+	if _, ok := a.Store.(store.TrashStore); !ok || days <= 0 {
+		return 0, nil
+	}
+	cutoff := now.AddDate(0, 0, -days)
+	return a.dropTrash(fmt.Sprintf("purge trash older than %d days", days), func(b model.Bullet) bool {
+		return b.Deleted != nil && b.Deleted.At.Before(cutoff)
+	})
+}
+
+// dropTrash removes the trashed bullets matching drop, committing the
+// change with message.
+func (a *App) dropTrash(message string, drop func(model.Bullet) bool) (int, error) {
+	st, err := a.logStore(LogTrash)
+	if err != nil {
+		return 0, err
+	}
+	items, err := st.LoadDay(time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	kept := items[:0:0]
+	for _, b := range items {
+		if !drop(b) {
+			kept = append(kept, b)
+		}
+	}
+	n := len(items) - len(kept)
+	if n == 0 {
+		return 0, nil
+	}
+	if err := st.SaveDay(time.Time{}, kept); err != nil {
+		return 0, err
+	}
+	return n, a.commit(message)
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// treeOrder arranges a day's bullets depth-first: each parent is followed by
+// its children, siblings sorted by Order and then by file position. Bullets
+// whose parent is missing, or that sit in a cycle of parents, are treated as
+// top-level.
+func treeOrder(log string, d time.Time, items []model.Bullet) []Entry {
+	ids := make(map[string]bool, len(items))
+	for _, it := range items {
+		ids[it.ID] = true
+	}
+	children := map[string][]int{}
+	for i, it := range items {
+		parent := it.ParentID
+		if parent == it.ID || !ids[parent] {
+			parent = ""
+		}
+		children[parent] = append(children[parent], i)
+	}
+	for _, idxs := range children {
+		sort.SliceStable(idxs, func(i, j int) bool { return items[idxs[i]].Order < items[idxs[j]].Order })
+	}
+	out := make([]Entry, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	var visit func(i, depth int)
+	visit = func(i, depth int) {
+		it := items[i]
+		if seen[it.ID] {
+			return
+		}
+		seen[it.ID] = true
+		out = append(out, Entry{Date: dateOnly(d), Item: it, Log: log, Depth: depth, Children: len(children[it.ID])})
+		for _, c := range children[it.ID] {
+			visit(c, depth+1)
+		}
+	}
+	for _, i := range children[""] {
+		visit(i, 0)
+	}
+	// Bullets whose parents form a cycle are never reached from a root. Each
+	// one still left is shown as top-level, which cuts the cycle there.
+	for i, it := range items {
+		if !seen[it.ID] {
+			children[it.ParentID] = slices.DeleteFunc(children[it.ParentID], func(j int) bool { return j == i })
+			visit(i, 0)
+		}
+	}
+	return out
+}
+
+// subtree returns root followed by all of its descendants in tree order.
+func subtree(items []model.Bullet, root model.Bullet) []model.Bullet {
+	out := []model.Bullet{root}
+	in := map[string]bool{root.ID: true}
+	for _, e := range treeOrder(LogDaily, time.Time{}, items) {
+		if !in[e.Item.ID] && in[e.Item.ParentID] {
+			in[e.Item.ID] = true
+			out = append(out, e.Item)
+		}
+	}
+	return out
+}
+
+// ToggleCollapsed hides or shows the children of the visible item at index.
+func (a *App) ToggleCollapsed(index int) {
+	vis := a.Visible()
+	if index < 0 || index >= len(vis) || vis[index].Children == 0 {
+		return
+	}
+	id := vis[index].Item.ID
+	if a.Collapsed[id] {
+		delete(a.Collapsed, id)
+	} else {
+		a.Collapsed[id] = true
+	}
+}
+
+// AddChild creates a Task nested under the visible item at index.
+func (a *App) AddChild(index int, text string) (model.Bullet, error) {
+	vis := a.Visible()
+	if index < 0 || index >= len(vis) {
+		return model.Bullet{}, nil
+	}
+	parent := vis[index]
+	b, err := a.addUnder(parent.Log, parent.Date, parent.Item.ID, model.Bullet{Text: text, Type: model.Task, Month: parent.Item.Month})
+	if err != nil {
+		return model.Bullet{}, err
+	}
+	// Expand the parent so the new child is visible.
+	delete(a.Collapsed, parent.Item.ID)
+	return b, a.Refresh()
+}
+
+// AddUnder appends b as the last child of parent, in parent's log and day.
+func (a *App) AddUnder(parent Entry, b model.Bullet) (model.Bullet, error) {
+	b.Month = parent.Item.Month
+	return a.addUnder(parent.Log, parent.Date, parent.Item.ID, b)
+}
+
+func (a *App) addUnder(log string, date time.Time, parentID string, b model.Bullet) (model.Bullet, error) {
+	d := dateOnly(date)
+	items, err := a.loadLog(log, d)
+	if err != nil {
+		return model.Bullet{}, err
+	}
+	found := false
+	for _, it := range items {
+		if it.ID == parentID {
+			found = true
+		}
+		if it.ParentID == parentID && it.Order >= b.Order {
+			b.Order = it.Order + 1
+		}
+	}
+	if !found {
+		return model.Bullet{}, store.ErrNotFound
+	}
+	b.ParentID = parentID
+	if b.ID == "" {
+		b.ID = store.NewID()
+	}
+	if err := a.record(opLabel("add", b), func() error { return a.add(log, d, b) }); err != nil {
+		return model.Bullet{}, err
+	}
+	return b, nil
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// outline renders entries as "text/depth/children" separated by spaces.
+func outline(entries []Entry) string {
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = fmt.Sprintf("%s/%d/%d", e.Item.Text, e.Depth, e.Children)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestTreeOrder(t *testing.T) {
+	b := func(id, parent string, order int) model.Bullet {
+		return model.Bullet{ID: id, Text: id, ParentID: parent, Order: order}
+	}
+	tests := []struct {
+		name  string
+		items []model.Bullet
+		want  string
+	}{
+		{"empty", nil, ""},
+		{"flat file order", []model.Bullet{b("a", "", 0), b("b", "", 0), b("c", "", 0)}, "a/0/0 b/0/0 c/0/0"},
+		{"order before file position", []model.Bullet{b("a", "", 2), b("b", "", 1), b("c", "", 0)}, "c/0/0 b/0/0 a/0/0"},
+		{"children follow parent", []model.Bullet{b("p", "", 0), b("q", "", 0), b("p1", "p", 0), b("p2", "p", 1)}, "p/0/2 p1/1/0 p2/1/0 q/0/0"},
+		{"child before parent in file", []model.Bullet{b("c", "p", 0), b("p", "", 0)}, "p/0/1 c/1/0"},
+		{"grandchildren", []model.Bullet{b("a", "", 0), b("a1", "a", 0), b("a11", "a1", 0), b("a2", "a", 1)}, "a/0/2 a1/1/1 a11/2/0 a2/1/0"},
+		{"sibling order", []model.Bullet{b("p", "", 0), b("x", "p", 1), b("y", "p", 0)}, "p/0/2 y/1/0 x/1/0"},
+		{"orphan is top-level", []model.Bullet{b("a", "", 0), b("o", "gone", 0)}, "a/0/0 o/0/0"},
+		{"self parent is top-level", []model.Bullet{b("s", "s", 0), b("a", "", 0)}, "s/0/0 a/0/0"},
+		{"cycle is cut at its first bullet", []model.Bullet{b("a", "", 0), b("x", "y", 0), b("y", "x", 0), b("y1", "y", 1)}, "a/0/0 x/0/1 y/1/1 y1/2/0"},
+		{"longer cycle", []model.Bullet{b("p", "r", 0), b("q", "p", 0), b("r", "q", 0)}, "p/0/1 q/1/1 r/2/0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := outline(treeOrder(LogDaily, time.Time{}, tt.items))
+			if got != tt.want {
+				t.Errorf("treeOrder = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	items := []model.Bullet{
+		{ID: "a", Text: "a"},
+		{ID: "b", Text: "b"},
+		{ID: "b2", Text: "b2", ParentID: "b", Order: 1},
+		{ID: "b1", Text: "b1", ParentID: "b"},
+		{ID: "b11", Text: "b11", ParentID: "b1"},
+		{ID: "a1", Text: "a1", ParentID: "a"},
+	}
+	tests := []struct {
+		root string
+		want string
+	}{
+		{"a", "a a1"},
+		{"b", "b b1 b11 b2"},
+		{"b1", "b1 b11"},
+		{"b11", "b11"},
+	}
+	for _, tt := range tests {
+		root := items[indexOfID(items, tt.root)]
+		var got []string
+		for _, it := range subtree(items, root) {
+			got = append(got, it.Text)
+		}
+		if g := strings.Join(got, " "); g != tt.want {
+			t.Errorf("subtree(%s) = %q, want %q", tt.root, g, tt.want)
+		}
+	}
+}
+
+func TestSubtreeMoves(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	next := day.AddDate(0, 0, 1)
+	setup := func(t *testing.T) (*App, store.Store) {
+		st := store.NewMemStore()
+		a := newTestApp(t, st, day)
+		for _, step := range []struct {
+			parent int // visible index, -1 for top-level
+			text   string
+		}{{-1, "parent"}, {0, "open child"}, {1, "grandchild"}, {0, "done child"}, {-1, "other"}} {
+			var err error
+			if step.parent < 0 {
+				_, err = a.Add(step.text)
+			} else {
+				_, err = a.AddChild(step.parent, step.text)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// parent/open child/grandchild/done child/other
+		if err := a.CompleteIndex(3); err != nil {
+			t.Fatal(err)
+		}
+		return a, st
+	}
+	show := func(t *testing.T, a *App, d time.Time) string {
+		if err := a.JumpToDate(d); err != nil {
+			t.Fatal(err)
+		}
+		var parts []string
+		for _, e := range a.Items {
+			parts = append(parts, strings.Repeat(">", e.Depth)+e.Item.Text+":"+string(e.Item.Type))
+		}
+		return strings.Join(parts, " ")
+	}
+
+	t.Run("migrate carries open descendants", func(t *testing.T) {
+		a, _ := setup(t)
+		if err := a.MigrateIndex(0); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := show(t, a, day), "parent:migrated >open child:migrated >>grandchild:migrated >done child:done other:task"; got != want {
+			t.Errorf("source day = %q, want %q", got, want)
+		}
+		if got, want := show(t, a, next), "parent:task >open child:task >>grandchild:task"; got != want {
+			t.Errorf("target day = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("delete takes descendants", func(t *testing.T) {
+		a, st := setup(t)
+		if err := a.DeleteIndex(1); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := show(t, a, day), "parent:task >done child:done other:task"; got != want {
+			t.Errorf("after delete = %q, want %q", got, want)
+		}
+		days, err := st.LoadRange(day, day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(days[0].Items); n != 3 {
+			t.Errorf("day holds %d bullets after delete, want 3", n)
+		}
+	})
+
+	t.Run("move keeps children attached", func(t *testing.T) {
+		a, _ := setup(t)
+		if _, err := a.MoveIndex(0, 1); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := show(t, a, day), "other:task parent:task >open child:task >>grandchild:task >done child:done"; got != want {
+			t.Errorf("after move = %q, want %q", got, want)
+		}
+	})
+}
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 type BulletType string
 
@@ -9,6 +12,7 @@ const (
 	Done                 BulletType = "done"
 	Migrated             BulletType = "migrated"
 	Scheduled            BulletType = "scheduled"
+	Cancelled            BulletType = "cancelled" // task no longer relevant
 	Event                BulletType = "event"
 	Note                 BulletType = "note"
 	HighlightImportant   BulletType = "highlight_important"
@@ -23,5 +27,78 @@ type Bullet struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
 	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"` // last change after creation
 	Highlight    string     `json:"highlight,omitempty"`
+
+	// Revisions keeps the earlier text, type and tags, oldest first, up to
+	// MaxRevisions entries.
+	Revisions []Revision `json:"revisions,omitempty"`
+
+	// Nesting: ParentID refers to a bullet on the same day; Order sorts
+	// siblings (ties keep file order).
+	ParentID string `json:"parent_id,omitempty"`
+	Order    int    `json:"order,omitempty"`
+
+	// RecurrenceID is set on instances generated from a recurrence rule.
+	RecurrenceID string `json:"recurrence_id,omitempty"`
+
+	// Migration/scheduling links. A Migrated or Scheduled bullet records the
+	// copy it produced in CloneID; the copy records where it came from. The
+	// *Log fields name the collection when it is not the daily log.
+	OriginID   string     `json:"origin_id,omitempty"`
+	OriginDate *time.Time `json:"origin_date,omitempty"`
+	OriginLog  string     `json:"origin_log,omitempty"`
+	CloneID    string     `json:"clone_id,omitempty"`
+	CloneLog   string     `json:"clone_log,omitempty"`
+
+	// Month is the target month (YYYY-MM) of a future-log item.
+	Month string `json:"month,omitempty"`
+
+	// Deleted is set on bullets in the trash.
+	Deleted *Deletion `json:"deleted,omitempty"`
+}
+
+// Deletion records when a bullet was moved to the trash and where from, so
+// it can be restored. Date is nil for undated logs (future log,
+// collections); Log names the collection when not the daily log.
+type Deletion struct {
+	At   time.Time  `json:"at"`
+	Date *time.Time `json:"date,omitempty"`
+	Log  string     `json:"log,omitempty"`
+}
+
+// Revision is a bullet's text, type and tags as they were until At.
+type Revision struct {
+	At   time.Time  `json:"at"`
+	Type BulletType `json:"type"`
+	Text string     `json:"text"`
+	Tags []string   `json:"tags,omitempty"`
+}
+
+// MaxRevisions bounds the revisions kept per bullet.
+const MaxRevisions = 20
+
+// Touch records b as an edit of prev made at now: UpdatedAt is set and,
+// when the text, type or tags differ, prev's values are kept as a revision.
+func (b *Bullet) Touch(prev Bullet, now time.Time) {
+	b.UpdatedAt = &now
+	if b.Text == prev.Text && b.Type == prev.Type && slices.Equal(b.Tags, prev.Tags) {
+		return
+	}
+	b.Revisions = append(slices.Clone(prev.Revisions), Revision{At: now, Type: prev.Type, Text: prev.Text, Tags: slices.Clone(prev.Tags)})
+	if n := len(b.Revisions); n > MaxRevisions {
+		b.Revisions = b.Revisions[n-MaxRevisions:]
+	}
+}
+
+// Modified is the latest time recorded on b: its creation, last update or
+// completion.
+func (b Bullet) Modified() time.Time {
+	t := b.CreatedAt
+	for _, p := range []*time.Time{b.UpdatedAt, b.CompletedAt} {
+		if p != nil && p.After(t) {
+			t = *p
+		}
+	}
+	return t
 }
@@ -8,6 +8,11 @@ const (
 	PeriodDay   Period = "day"
 	PeriodWeek  Period = "week"
 	PeriodMonth Period = "month"
+	// Collection views that are not backed by day files.
+	PeriodMonthLog Period = "monthlog"
+	PeriodFuture   Period = "future"
+	// PeriodCollection shows the named collection chosen in the app.
+	PeriodCollection Period = "collection"
 )
 
 type DateRange struct {
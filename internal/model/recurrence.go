@@ -0,0 +1,206 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RecurrenceFreq string
+
+const (
+	RecurDaily    RecurrenceFreq = "daily"
+	RecurWeekdays RecurrenceFreq = "weekdays"
+	RecurWeekly   RecurrenceFreq = "weekly"
+	RecurMonthly  RecurrenceFreq = "monthly"
+	// RecurAfter repeats Interval days after the previous instance is completed.
+	RecurAfter RecurrenceFreq = "after"
+)
+
+// Recurrence defines a bullet that is generated into day logs by rule.
+type Recurrence struct {
+	ID    string     `json:"id"`
+	Type  BulletType `json:"type"`
+	Text  string     `json:"text"`
+	Tags  []string   `json:"tags,omitempty"`
+	Start time.Time  `json:"start"`
+
+	Freq     RecurrenceFreq `json:"freq"`
+	Interval int            `json:"interval,omitempty"`  // RecurAfter: days after completion
+	Weekdays []time.Weekday `json:"weekdays,omitempty"`  // RecurWeekly
+	MonthDay int            `json:"month_day,omitempty"` // RecurMonthly: day N (clamped to month end)
+	Nth      int            `json:"nth,omitempty"`       // RecurMonthly: Nth Weekday (1..5, -1 = last)
+	Weekday  time.Weekday   `json:"weekday,omitempty"`   // RecurMonthly with Nth
+
+	// LastDate/LastID track the latest instance; days up to LastDate are
+	// never generated again, so deleted instances are not recreated.
+	LastDate *time.Time `json:"last_date,omitempty"`
+	LastID   string     `json:"last_id,omitempty"`
+}
+
+// Occurs reports whether a calendar rule falls on the given date. It is
+// always false for RecurAfter, which depends on completion state.
+func (r Recurrence) Occurs(d time.Time) bool {
+	y, m, day := d.Date()
+	sy, sm, sd := r.Start.Date()
+	if time.Date(y, m, day, 0, 0, 0, 0, time.UTC).Before(time.Date(sy, sm, sd, 0, 0, 0, 0, time.UTC)) {
+		return false
+	}
+	wd := d.Weekday()
+	switch r.Freq {
+	case RecurDaily:
+		return true
+	case RecurWeekdays:
+		return wd != time.Saturday && wd != time.Sunday
+	case RecurWeekly:
+		for _, w := range r.Weekdays {
+			if w == wd {
+				return true
+			}
+		}
+		return false
+	case RecurMonthly:
+		last := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		if r.Nth != 0 {
+			if wd != r.Weekday {
+				return false
+			}
+			if r.Nth < 0 {
+				return day+7 > last
+			}
+			return (day-1)/7+1 == r.Nth
+		}
+		target := r.MonthDay
+		if target > last {
+			target = last
+		}
+		return day == target
+	}
+	return false
+}
+
+// Rule formats the recurrence rule in the syntax accepted by ParseRule.
+func (r Recurrence) Rule() string {
+	switch r.Freq {
+	case RecurWeekly:
+		names := make([]string, 0, len(r.Weekdays))
+		for _, w := range r.Weekdays {
+			names = append(names, strings.ToLower(w.String()[:3]))
+		}
+		return "weekly " + strings.Join(names, ",")
+	case RecurMonthly:
+		if r.Nth != 0 {
+			wd := strings.ToLower(r.Weekday.String()[:3])
+			if r.Nth < 0 {
+				return "monthly last " + wd
+			}
+			return "monthly " + ordinal(r.Nth) + " " + wd
+		}
+		return "monthly " + strconv.Itoa(r.MonthDay)
+	case RecurAfter:
+		return "after " + strconv.Itoa(r.Interval)
+	}
+	return string(r.Freq)
+}
+
+// ParseRule parses a rule such as "daily", "weekdays", "weekly mon,thu",
+// "monthly 15", "monthly 2nd tue", "monthly last fri" or "after 3" (every 3
+// days after completion) into the rule fields of a Recurrence.
+func ParseRule(spec string) (Recurrence, error) {
+	var r Recurrence
+	fields := strings.Fields(strings.ToLower(strings.TrimSpace(spec)))
+	if len(fields) == 0 {
+		return r, errors.New("empty rule")
+	}
+	r.Freq = RecurrenceFreq(fields[0])
+	args := fields[1:]
+	switch r.Freq {
+	case RecurDaily, RecurWeekdays:
+		if len(args) != 0 {
+			return r, fmt.Errorf("%s takes no arguments", r.Freq)
+		}
+	case RecurWeekly:
+		if len(args) != 1 {
+			return r, errors.New("weekly needs days, e.g. \"weekly mon,thu\"")
+		}
+		for _, part := range strings.Split(args[0], ",") {
+			wd, ok := parseWeekday(part)
+			if !ok {
+				return r, fmt.Errorf("unknown weekday %q", part)
+			}
+			r.Weekdays = append(r.Weekdays, wd)
+		}
+	case RecurMonthly:
+		switch len(args) {
+		case 1:
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 || n > 31 {
+				return r, fmt.Errorf("invalid day of month %q", args[0])
+			}
+			r.MonthDay = n
+		case 2:
+			nth, ok := parseOrdinal(args[0])
+			if !ok {
+				return r, fmt.Errorf("invalid ordinal %q (use 1st..5th or last)", args[0])
+			}
+			wd, ok := parseWeekday(args[1])
+			if !ok {
+				return r, fmt.Errorf("unknown weekday %q", args[1])
+			}
+			r.Nth, r.Weekday = nth, wd
+		default:
+			return r, errors.New("monthly needs a day (\"monthly 15\") or weekday (\"monthly 2nd tue\")")
+		}
+	case RecurAfter:
+		if len(args) != 1 {
+			return r, errors.New("after needs a number of days, e.g. \"after 3\"")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return r, fmt.Errorf("invalid interval %q", args[0])
+		}
+		r.Interval = n
+	default:
+		return r, fmt.Errorf("unknown rule %q (daily|weekdays|weekly|monthly|after)", fields[0])
+	}
+	return r, nil
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return 0, false
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.HasPrefix(strings.ToLower(wd.String()), s) {
+			return wd, true
+		}
+	}
+	return 0, false
+}
+
+func parseOrdinal(s string) (int, bool) {
+	if s == "last" {
+		return -1, true
+	}
+	s = strings.TrimRight(s, "stndrh")
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 5 {
+		return 0, false
+	}
+	return n, true
+}
+
+func ordinal(n int) string {
+	switch n {
+	case 1:
+		return "1st"
+	case 2:
+		return "2nd"
+	case 3:
+		return "3rd"
+	}
+	return strconv.Itoa(n) + "th"
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string // Rule() of the result; empty when an error is expected
+		wantErr bool
+	}{
+		{spec: "daily", want: "daily"},
+		{spec: "  Weekdays ", want: "weekdays"},
+		{spec: "weekly mon,thu", want: "weekly mon,thu"},
+		{spec: "weekly tuesday", want: "weekly tue"},
+		{spec: "monthly 15", want: "monthly 15"},
+		{spec: "monthly 31", want: "monthly 31"},
+		{spec: "monthly 2nd tue", want: "monthly 2nd tue"},
+		{spec: "monthly 1 fri", want: "monthly 1st fri"},
+		{spec: "monthly last fri", want: "monthly last fri"},
+		{spec: "after 3", want: "after 3"},
+		{spec: "", wantErr: true},
+		{spec: "hourly", wantErr: true},
+		{spec: "daily 2", wantErr: true},
+		{spec: "weekly", wantErr: true},
+		{spec: "weekly mon,xyz", wantErr: true},
+		{spec: "weekly m", wantErr: true},
+		{spec: "monthly 0", wantErr: true},
+		{spec: "monthly 32", wantErr: true},
+		{spec: "monthly 6th mon", wantErr: true},
+		{spec: "monthly 2nd", wantErr: true},
+		{spec: "after", wantErr: true},
+		{spec: "after 0", wantErr: true},
+		{spec: "after x", wantErr: true},
+	}
+	for _, tt := range tests {
+		r, err := ParseRule(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRule(%q) = %q, want an error", tt.spec, r.Rule())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRule(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := r.Rule(); got != tt.want {
+			t.Errorf("ParseRule(%q).Rule() = %q, want %q", tt.spec, got, tt.want)
+		}
+		if again, err := ParseRule(r.Rule()); err != nil || again.Rule() != r.Rule() {
+			t.Errorf("ParseRule(%q) does not round-trip: %q, %v", r.Rule(), again.Rule(), err)
+		}
+	}
+}
+
+func TestRecurrenceOccurs(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local) // a Sunday
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.Local) }
+	tests := []struct {
+		rule string
+		on   []time.Time
+		off  []time.Time
+	}{
+		{"daily", []time.Time{start, day(3, 2), day(12, 31)}, []time.Time{day(2, 28)}},
+		{"weekdays", []time.Time{day(3, 2), day(3, 6)}, []time.Time{start, day(3, 7)}},
+		{"weekly mon,thu", []time.Time{day(3, 2), day(3, 5), day(3, 9)}, []time.Time{day(3, 3), day(3, 8), day(2, 26)}},
+		{"monthly 15", []time.Time{day(3, 15), day(4, 15)}, []time.Time{day(3, 14), day(2, 15)}},
+		{"monthly 31", []time.Time{day(3, 31), day(4, 30), day(6, 30)}, []time.Time{day(4, 29), day(5, 30)}},
+		{"monthly 2nd tue", []time.Time{day(3, 10), day(4, 14)}, []time.Time{day(3, 3), day(3, 17)}},
+		{"monthly last fri", []time.Time{day(3, 27), day(7, 31)}, []time.Time{day(3, 20), day(7, 24)}},
+		{"monthly 5th sun", []time.Time{day(3, 29)}, []time.Time{day(3, 22), day(4, 26)}},
+		{"after 3", nil, []time.Time{start, day(3, 4)}},
+	}
+	for _, tt := range tests {
+		r, err := ParseRule(tt.rule)
+		if err != nil {
+			t.Fatalf("ParseRule(%q): %v", tt.rule, err)
+		}
+		r.Start = start
+		for _, d := range tt.on {
+			if !r.Occurs(d) {
+				t.Errorf("%q does not occur on %s", tt.rule, d.Format("Mon 2006-01-02"))
+			}
+		}
+		for _, d := range tt.off {
+			if r.Occurs(d) {
+				t.Errorf("%q occurs on %s", tt.rule, d.Format("Mon 2006-01-02"))
+			}
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package store
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupDir holds archives of the data directory, e.g. those taken before
+// schema upgrades.
+const backupDir = "backups"
+
+// writeBackup archives the data directory to backups/<name>.tar.gz and
+// returns the archive's path. Backups themselves, hidden directories, temp
+// files and the lock file are left out.
+func (s *FSStore) writeBackup(name string) (string, error) {
+	dir := filepath.Join(s.root, backupDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name+".tar.gz")
+	return path, s.writeArchive(path)
+}
+
+// writeArchive atomically replaces path with an archive of the data
+// directory.
+func (s *FSStore) writeArchive(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "backup-*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := s.archive(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *FSStore) archive(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := s.rel(path)
+		if d.IsDir() {
+			if path != s.root && (rel == backupDir || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == lockName || strings.HasSuffix(d.Name(), ".tmp") || !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Backup is an archive in backups/.
+type Backup struct {
+	Path string    `json:"path"` // relative to the data directory
+	Time time.Time `json:"time"` // from the name's timestamp, else the file's
+	Size int64     `json:"size"`
+}
+
+// Name returns the archive's name without directory and extension, as
+// accepted by RestoreBackup.
+func (b Backup) Name() string {
+	return strings.TrimSuffix(path.Base(b.Path), ".tar.gz")
+}
+
+// Snapshot returns whether the archive is a rotating snapshot rather than
+// a backup taken before an upgrade or restore.
+func (b Backup) Snapshot() bool {
+	return strings.HasPrefix(b.Name(), snapshotPrefix)
+}
+
+// snapshotPrefix starts the names of the rotating snapshots; only these
+// are pruned.
+const snapshotPrefix = "snapshot-"
+
+// stampLayout is the timestamp ending every archive name.
+const stampLayout = "20060102-150405"
+
+// Backups lists the archives in backups/, oldest first.
+func (s *FSStore) Backups() ([]Backup, error) {
+	dir := filepath.Join(s.root, backupDir)
+	names, err := readNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []Backup
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".tar.gz") {
+			continue
+		}
+		fi, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		b := Backup{Path: backupDir + "/" + name, Time: fi.ModTime(), Size: fi.Size()}
+		if n := b.Name(); len(n) >= len(stampLayout) {
+			if t, err := time.ParseInLocation(stampLayout, n[len(n)-len(stampLayout):], time.Local); err == nil {
+				b.Time = t
+			}
+		}
+		out = append(out, b)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out, nil
+}
+
+// Snapshot archives the data directory to backups/snapshot-<time>.tar.gz
+// and returns the archive.
+func (s *FSStore) Snapshot(now time.Time) (Backup, error) {
+	var b Backup
+	err := s.locked(func() error {
+		var err error
+		b, err = s.snapshot(snapshotPrefix, now)
+		return err
+	})
+	return b, err
+}
+
+func (s *FSStore) snapshot(prefix string, now time.Time) (Backup, error) {
+	file, err := s.writeBackup(prefix + now.Format(stampLayout))
+	if err != nil {
+		return Backup{}, err
+	}
+	b := Backup{Path: s.rel(file), Time: now}
+	if fi, err := os.Stat(file); err == nil {
+		b.Size = fi.Size()
+	}
+	return b, nil
+}
+
+// DailySnapshot takes a snapshot unless one was already taken on now's
+// day or the journal is still empty, then prunes snapshots beyond the
+// newest keep. It returns the new snapshot, or a zero Backup when none was
+// due.
+func (s *FSStore) DailySnapshot(now time.Time, keep int) (Backup, error) {
+	var b Backup
+	err := s.locked(func() error {
+		all, err := s.Backups()
+		if err != nil {
+			return err
+		}
+		y, m, d := now.Date()
+		for _, prev := range all {
+			if py, pm, pd := prev.Time.Date(); prev.Snapshot() && py == y && pm == m && pd == d {
+				return nil
+			}
+		}
+		if empty, err := s.empty(); err != nil || empty {
+			return err
+		}
+		if b, err = s.snapshot(snapshotPrefix, now); err != nil {
+			return err
+		}
+		_, err = s.pruneSnapshots(keep)
+		return err
+	})
+	return b, err
+}
+
+// empty reports whether the data directory holds no journal files yet.
+func (s *FSStore) empty() (bool, error) {
+	errFound := errors.New("found")
+	err := s.eachDataFile(func(string, []byte) error { return errFound })
+	if err == errFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// PruneSnapshots deletes all but the newest keep snapshots and returns the
+// ones it deleted. Other archives are left alone.
+func (s *FSStore) PruneSnapshots(keep int) ([]Backup, error) {
+	var pruned []Backup
+	err := s.locked(func() error {
+		var err error
+		pruned, err = s.pruneSnapshots(keep)
+		return err
+	})
+	return pruned, err
+}
+
+func (s *FSStore) pruneSnapshots(keep int) ([]Backup, error) {
+	all, err := s.Backups()
+	if err != nil {
+		return nil, err
+	}
+	var snaps []Backup
+	for _, b := range all {
+		if b.Snapshot() {
+			snaps = append(snaps, b)
+		}
+	}
+	var pruned []Backup
+	for len(snaps) > max(keep, 0) {
+		if err := os.Remove(filepath.Join(s.root, snaps[0].Path)); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, snaps[0])
+		snaps = snaps[1:]
+	}
+	return pruned, nil
+}
+
+// FindBackup resolves ref, an archive's name (with or without the
+// .tar.gz extension or backups/ directory) or a unique prefix of it.
+func (s *FSStore) FindBackup(ref string) (Backup, error) {
+	all, err := s.Backups()
+	if err != nil {
+		return Backup{}, err
+	}
+	name := strings.TrimSuffix(path.Base(filepath.ToSlash(ref)), ".tar.gz")
+	var found []Backup
+	for _, b := range all {
+		if b.Name() == name {
+			return b, nil
+		}
+		if strings.HasPrefix(b.Name(), name) {
+			found = append(found, b)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Backup{}, fmt.Errorf("%w: %q", ErrNoBackup, ref)
+	case 1:
+		return found[0], nil
+	}
+	return Backup{}, fmt.Errorf("backup name is ambiguous: %q matches %d archives", ref, len(found))
+}
+
+// ErrNoBackup is returned when no archive matches a backup name.
+var ErrNoBackup = errors.New("no backup matches")
+
+// RestoreBackup replaces the journal with the contents of the archive at
+// path (relative to the data directory or absolute). The journal is first
+// snapshotted to backups/pre-restore-<time>.tar.gz, which is returned.
+// Backups, hidden files such as the git repository, prefs.json and
+// journals.json are kept; everything else the archive does not hold is
+// removed. The archive is read completely before anything is touched, so
+// a damaged one leaves the journal as it was.
+func (s *FSStore) RestoreBackup(archive string, now time.Time) (Backup, error) {
+	if !filepath.IsAbs(archive) {
+		archive = filepath.Join(s.root, filepath.FromSlash(archive))
+	}
+	var safety Backup
+	err := s.locked(func() error {
+		staged, err := os.MkdirTemp(s.root, ".restore-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(staged)
+		if err := extract(archive, staged); err != nil {
+			return fmt.Errorf("read %s: %w", filepath.Base(archive), err)
+		}
+		if safety, err = s.snapshot("pre-restore-", now); err != nil {
+			return fmt.Errorf("backup before restore: %w", err)
+		}
+		current, err := readNames(s.root)
+		if err != nil {
+			return err
+		}
+		for _, name := range current {
+			if keepOnRestore(name) {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(s.root, name)); err != nil {
+				return err
+			}
+		}
+		names, err := readNames(staged)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if keepOnRestore(name) {
+				continue
+			}
+			if err := os.Rename(filepath.Join(staged, name), filepath.Join(s.root, name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return safety, err
+}
+
+// keepOnRestore reports whether the top-level entry name is left as it is
+// by RestoreBackup.
+func keepOnRestore(name string) bool {
+	return name == backupDir || name == "prefs.json" || name == "journals.json" || strings.HasPrefix(name, ".")
+}
+
+// extract unpacks the tar.gz archive at path into dir, refusing entries
+// that would land outside it.
+func extract(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !filepath.IsLocal(hdr.Name) {
+			return fmt.Errorf("unsafe path in archive: %s", hdr.Name)
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, hdr.FileInfo().Mode().Perm()|0o600)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, tr)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		os.Chtimes(dst, hdr.ModTime, hdr.ModTime)
+	}
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCollectionExists is returned when creating or renaming onto a taken name.
+	ErrCollectionExists = errors.New("collection already exists")
+	// ErrNoCollection is returned when a named collection does not exist.
+	ErrNoCollection = errors.New("no such collection")
+)
+
+// CollectionStore is implemented by stores that keep named collections
+// (projects, lists) outside the date hierarchy. Each collection is exposed as
+// a Store whose date arguments are ignored.
+type CollectionStore interface {
+	Collections() ([]string, error)
+	Collection(name string) Store
+	CreateCollection(name string) error
+	DeleteCollection(name string) error
+	RenameCollection(from, to string) error
+}
+
+// collectionPrefix marks log names that refer to a named collection.
+const collectionPrefix = "collection:"
+
+// CollectionRef is the log name recorded in bullet links (OriginLog,
+// CloneLog) and undo journal entries for items in a named collection.
+func CollectionRef(name string) string { return collectionPrefix + name }
+
+// ParseCollectionRef returns the collection named by a log name.
+func ParseCollectionRef(log string) (string, bool) {
+	if !strings.HasPrefix(log, collectionPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(log, collectionPrefix), true
+}
+
+// ValidateCollectionName rejects names that cannot be used as a file name.
+func ValidateCollectionName(name string) error {
+	if strings.TrimSpace(name) != name || name == "" {
+		return fmt.Errorf("invalid collection name %q", name)
+	}
+	if strings.ContainsAny(name, `/\:`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid collection name %q", name)
+	}
+	return nil
+}
+
+func (s *FSStore) collectionPath(name string) string {
+	return filepath.Join(s.root, "collections", name+".jsonl")
+}
+
+// Collections lists collection names in alphabetical order.
+func (s *FSStore) Collections() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.root, "collections"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		if _, dup := conflictOriginal(e.Name()); dup {
+			continue
+		}
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".jsonl") {
+			out = append(out, strings.TrimSuffix(e.Name(), ".jsonl"))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i]) < strings.ToLower(out[j]) })
+	return out, nil
+}
+
+// Collection returns the named collection, stored as collections/<name>.jsonl.
+// Writing to a collection that does not exist yet creates it.
+func (s *FSStore) Collection(name string) Store {
+	return logStore{s: s, path: func(time.Time) string { return s.collectionPath(name) }}
+}
+
+// CreateCollection creates an empty collection.
+func (s *FSStore) CreateCollection(name string) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	path := s.collectionPath(name)
+	return s.locked(func() error {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%w: %q", ErrCollectionExists, name)
+		}
+		return s.saveFile(path, nil)
+	})
+}
+
+// DeleteCollection removes a collection and all of its bullets.
+func (s *FSStore) DeleteCollection(name string) error {
+	if err := ValidateCollectionName(name); err != nil {
+		return err
+	}
+	return s.locked(func() error {
+		if err := os.Remove(s.collectionPath(name)); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("%w: %q", ErrNoCollection, name)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// RenameCollection renames a collection, refusing to overwrite another.
+// Links and undo journal entries that refer to it are updated as well.
+func (s *FSStore) RenameCollection(from, to string) error {
+	return s.renameCollection(from, to, nil)
+}
+
+// renameCollection is RenameCollection for files sealed with key, or plain
+// ones when key is nil.
+func (s *FSStore) renameCollection(from, to string, key *Key) error {
+	if err := ValidateCollectionName(from); err != nil {
+		return err
+	}
+	if err := ValidateCollectionName(to); err != nil {
+		return err
+	}
+	return s.locked(func() error {
+		if _, err := os.Stat(s.collectionPath(from)); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("%w: %q", ErrNoCollection, from)
+			}
+			return err
+		}
+		if _, err := os.Stat(s.collectionPath(to)); err == nil {
+			return fmt.Errorf("%w: %q", ErrCollectionExists, to)
+		}
+		if err := os.Rename(s.collectionPath(from), s.collectionPath(to)); err != nil {
+			return err
+		}
+		return s.renameRefs(CollectionRef(from), CollectionRef(to), key)
+	})
+}
+
+// renameRefs rewrites link and journal references from one log name to
+// another across every bullet file under the root, opening and sealing
+// them again with key. Callers hold the lock.
+func (s *FSStore) renameRefs(from, to string, key *Key) error {
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".jsonl") {
+			return err
+		}
+		items, err := s.loadFile(path)
+		if err == nil {
+			items, err = key.openBullets(items)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.rel(path), err)
+		}
+		changed := false
+		for i := range items {
+			if items[i].OriginLog == from {
+				items[i].OriginLog, changed = to, true
+			}
+			if items[i].CloneLog == from {
+				items[i].CloneLog, changed = to, true
+			}
+		}
+		if !changed {
+			return nil
+		}
+		return s.saveFile(path, key.sealBullets(items))
+	})
+	if err != nil {
+		return err
+	}
+	var lg OpLog
+	if err := readJSONFile(s.opLogPath(), &lg); err != nil {
+		return err
+	}
+	lg, err = key.openOpLog(lg)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, ops := range [][]Op{lg.Undo, lg.Redo} {
+		for i := range ops {
+			for j := range ops[i].Changes {
+				if ops[i].Changes[j].Log == from {
+					ops[i].Changes[j].Log, changed = to, true
+				}
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	lg = key.sealOpLog(lg)
+	return writeJSONFile(s.opLogPath(), &lg)
+}
+
+var _ CollectionStore = (*FSStore)(nil)
@@ -10,17 +10,34 @@ import (
 
 type Preferences struct {
 	Period      model.Period       `json:"period"`
+	Collection  string             `json:"collection,omitempty"` // open collection for PeriodCollection
 	TextFilter  string             `json:"text_filter"`
 	Types       []model.BulletType `json:"types"`
 	Tags        []string           `json:"tags"`
-	LastDate    string             `json:"last_date"` // YYYY-MM-DD
+	Sort        string             `json:"sort,omitempty"` // view sort mode; empty for manual order
+	LastDate    string             `json:"last_date"`      // YYYY-MM-DD
 	CenterWidth int                `json:"center_width,omitempty"`
+	TrashDays   int                `json:"trash_days,omitempty"`  // 0: default retention, negative: keep forever
+	BackupKeep  int                `json:"backup_keep,omitempty"` // snapshots kept; 0: default, negative: no daily snapshots
 }
 
+// prefsDir is the data directory whose prefs.json is used; empty for the
+// default journal's.
+var prefsDir string
+
+// SetPreferencesDir makes LoadPreferences, SavePreferences and
+// UpdatePreferences use the prefs.json of the journal in dir, so each
+// journal keeps its own.
+func SetPreferencesDir(dir string) { prefsDir = dir }
+
 func prefsPath() (string, error) {
-	dir, err := ResolveDataDir()
-	if err != nil {
-		return "", err
+	dir := prefsDir
+	if dir == "" {
+		j, err := ResolveJournal("")
+		if err != nil {
+			return "", err
+		}
+		dir = j.Dir
 	}
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return "", err
@@ -49,6 +66,32 @@ func SavePreferences(p Preferences) error {
 	if err != nil {
 		return err
 	}
+	return withLock(filepath.Dir(path), func() error { return writePrefs(path, p) })
+}
+
+// UpdatePreferences applies fn to the stored preferences and saves them,
+// holding the data directory lock throughout so concurrent updates of
+// different fields are not lost. Missing or unreadable preferences start
+// from the zero value, as with LoadPreferences callers.
+func UpdatePreferences(fn func(p *Preferences)) error {
+	path, err := prefsPath()
+	if err != nil {
+		return err
+	}
+	return withLock(filepath.Dir(path), func() error {
+		var p Preferences
+		if data, err := os.ReadFile(path); err == nil {
+			_ = json.Unmarshal(data, &p)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		fn(&p)
+		return writePrefs(path, p)
+	})
+}
+
+// writePrefs atomically replaces path with p. Callers hold the lock.
+func writePrefs(path string, p Preferences) error {
 	tmp, err := os.CreateTemp(filepath.Dir(path), "prefs-*.tmp")
 	if err != nil {
 		return err
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// corruptSuffix names the sidecar that receives unreadable lines of a
+// bullet file, e.g. 2024/03/01.jsonl.corrupt.
+const corruptSuffix = ".corrupt"
+
+// Warner is implemented by stores that recover from problems while reading,
+// such as unreadable lines, and report them for display.
+type Warner interface {
+	Warnings() []string
+}
+
+// badLines tracks, per file, unreadable lines seen while loading and lines
+// moved to the quarantine sidecar during this session.
+type badLines struct {
+	mu    sync.Mutex
+	files map[string]badFile
+}
+
+type badFile struct {
+	skipped int // unreadable lines in the file as last loaded
+	moved   int // lines quarantined by this process
+}
+
+func (s *FSStore) noteBad(path string, n int) {
+	s.bad.mu.Lock()
+	defer s.bad.mu.Unlock()
+	f := s.bad.files[path]
+	if n == 0 && f == (badFile{}) {
+		return
+	}
+	f.skipped = n
+	s.setBad(path, f)
+}
+
+// setBad stores f for path, dropping files with nothing to report. Callers
+// hold s.bad.mu.
+func (s *FSStore) setBad(path string, f badFile) {
+	if s.bad.files == nil {
+		s.bad.files = map[string]badFile{}
+	}
+	if f == (badFile{}) {
+		delete(s.bad.files, path)
+		return
+	}
+	s.bad.files[path] = f
+}
+
+// Warnings describes the unreadable lines found so far, one message per
+// file with paths relative to the data directory, after any notices such as
+// a schema upgrade performed when the store was opened.
+func (s *FSStore) Warnings() []string {
+	s.bad.mu.Lock()
+	defer s.bad.mu.Unlock()
+	var out []string
+	for path, f := range s.bad.files {
+		rel := s.rel(path)
+		if f.skipped > 0 {
+			out = append(out, fmt.Sprintf("%s: skipped %s (run `blt doctor --fix`)", rel, plural(f.skipped, "unreadable line")))
+		}
+		if f.moved > 0 {
+			out = append(out, fmt.Sprintf("%s: moved %s to %s", rel, plural(f.moved, "unreadable line"), rel+corruptSuffix))
+		}
+	}
+	sort.Strings(out)
+	return append(append([]string(nil), s.notices...), out...)
+}
+
+// quarantine appends the unreadable lines of path to its sidecar before the
+// file is rewritten. Callers hold the lock.
+func (s *FSStore) quarantine(path string) error {
+	_, bad, err := s.readFile(path)
+	if err != nil || len(bad) == 0 {
+		return err
+	}
+	if err := appendData(path+corruptSuffix, append(bytes.Join(bad, []byte("\n")), '\n')); err != nil {
+		return err
+	}
+	s.bad.mu.Lock()
+	b := s.bad.files[path]
+	b.skipped, b.moved = 0, b.moved+len(bad)
+	s.setBad(path, b)
+	s.bad.mu.Unlock()
+	return nil
+}
+
+// rel returns path relative to the data directory, using forward slashes.
+func (s *FSStore) rel(path string) string {
+	if r, err := filepath.Rel(s.root, path); err == nil {
+		return filepath.ToSlash(r)
+	}
+	return path
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+var _ Warner = (*FSStore)(nil)
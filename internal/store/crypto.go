@@ -0,0 +1,489 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+var (
+	// ErrEncrypted is returned when opening an encrypted data directory
+	// without a passphrase.
+	ErrEncrypted = errors.New("data directory is encrypted")
+	// ErrBadPassphrase is returned when a passphrase does not unlock the
+	// data directory.
+	ErrBadPassphrase = errors.New("wrong passphrase")
+	// ErrNotEncrypted is returned when decrypting a plain data directory.
+	ErrNotEncrypted = errors.New("data directory is not encrypted")
+)
+
+// sealPrefix starts every sealed value so it can be told apart from plain
+// text. The rest is the base64 of a random GCM nonce followed by the sealed
+// bytes.
+const sealPrefix = "blt-enc2:"
+
+const (
+	kdfName       = "pbkdf2-sha256"
+	kdfIterations = 600_000
+	// sealScheme names how values are sealed, so directories encrypted by
+	// another scheme are refused instead of misread.
+	sealScheme = "aes-256-gcm"
+	// keyCheck is sealed into crypto.json to verify passphrases.
+	keyCheck = "blt"
+)
+
+// cryptoParams is crypto.json at the data directory root. Its presence marks
+// the directory as encrypted; it holds what is needed to derive the key
+// from the passphrase, never the key itself.
+type cryptoParams struct {
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Scheme     string `json:"scheme"`
+	Check      string `json:"check"`
+}
+
+// Key seals values with AES-256-GCM under a passphrase-derived key and a
+// random nonce, so sealing the same value twice gives different text.
+type Key struct {
+	aead cipher.AEAD
+}
+
+func deriveKey(passphrase string, p cryptoParams) (*Key, error) {
+	if p.KDF != kdfName {
+		return nil, fmt.Errorf("unsupported key derivation %q", p.KDF)
+	}
+	if p.Scheme != sealScheme {
+		return nil, fmt.Errorf("unsupported encryption scheme %q", p.Scheme)
+	}
+	raw, err := pbkdf2.Key(sha256.New, passphrase, p.Salt, p.Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{aead: aead}, nil
+}
+
+// newParams returns fresh key parameters for passphrase and the key they
+// derive.
+func newParams(passphrase string) (cryptoParams, *Key, error) {
+	p := cryptoParams{KDF: kdfName, Iterations: kdfIterations, Salt: make([]byte, 16), Scheme: sealScheme}
+	_, _ = rand.Read(p.Salt)
+	k, err := deriveKey(passphrase, p)
+	if err != nil {
+		return p, nil, err
+	}
+	p.Check = k.seal([]byte(keyCheck))
+	return p, k, nil
+}
+
+// NewKey derives a key from passphrase with a fresh salt, for wrapping
+// stores that do not keep key parameters themselves, such as MemStore.
+func NewKey(passphrase string) (*Key, error) {
+	_, k, err := newParams(passphrase)
+	return k, err
+}
+
+func (k *Key) seal(plain []byte) string {
+	n := k.aead.NonceSize()
+	out := make([]byte, n, n+len(plain)+k.aead.Overhead())
+	_, _ = rand.Read(out)
+	out = k.aead.Seal(out, out[:n], plain, nil)
+	return sealPrefix + base64.RawStdEncoding.EncodeToString(out)
+}
+
+// open returns the value sealed in s. A nil Key cannot open anything and
+// returns ErrEncrypted.
+func (k *Key) open(sealed string) ([]byte, error) {
+	if k == nil {
+		return nil, ErrEncrypted
+	}
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(sealed, sealPrefix))
+	n := k.aead.NonceSize()
+	if err != nil || len(data) < n {
+		return nil, errors.New("sealed value is truncated")
+	}
+	plain, err := k.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return nil, errors.New("sealed value is damaged or was written with another key")
+	}
+	return plain, nil
+}
+
+// sealJSON seals the JSON encoding of v.
+func (k *Key) sealJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return k.seal(data)
+}
+
+// openJSON decodes the JSON sealed in s into v.
+func (k *Key) openJSON(s string, v any) error {
+	data, err := k.open(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// isSealed reports whether s was produced by Key.seal.
+func isSealed(s string) bool { return strings.HasPrefix(s, sealPrefix) }
+
+func (s *FSStore) cryptoPath() string { return filepath.Join(s.root, "crypto.json") }
+
+// Encrypted reports whether the data directory is encrypted.
+func (s *FSStore) Encrypted() bool {
+	_, err := os.Stat(s.cryptoPath())
+	return err == nil
+}
+
+func (s *FSStore) cryptoParams() (cryptoParams, error) {
+	var p cryptoParams
+	if err := readJSONFile(s.cryptoPath(), &p); err != nil {
+		return p, err
+	}
+	if p.KDF == "" {
+		return p, ErrNotEncrypted
+	}
+	return p, nil
+}
+
+// Unlock derives the key of an encrypted data directory from passphrase,
+// for an EncryptedStore over s.
+func (s *FSStore) Unlock(passphrase string) (*Key, error) {
+	p, err := s.cryptoParams()
+	if err != nil {
+		return nil, err
+	}
+	k, err := deriveKey(passphrase, p)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := k.open(p.Check); err != nil || string(check) != keyCheck {
+		return nil, ErrBadPassphrase
+	}
+	return k, nil
+}
+
+// NewEncryptedFSStore opens an encrypted data directory with passphrase and
+// upgrades its schema like NewFSStore. It returns the file store, for
+// maintenance such as backups, and the EncryptedStore over it that reads
+// and writes the journal.
+func NewEncryptedFSStore(dir, passphrase string) (*FSStore, Store, error) {
+	s, err := OpenFSStore(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !s.Encrypted() {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotEncrypted, dir)
+	}
+	k, err := s.Unlock(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, NewEncryptedStore(s, k), s.upgradeOnOpen()
+}
+
+// Encrypt seals the journal in the data directory with a key derived from
+// passphrase, as an EncryptedStore would have written it, and drops the
+// search index, which holds the words of every bullet. Archives in
+// backups/ get the same treatment, so no plain copy is left behind; it
+// returns how many files and archives it sealed. It is safe to run again
+// after an interruption: an already encrypted directory must be unlocked
+// by the same passphrase, and whatever is still plain is sealed.
+// manifest.json, crypto.json, prefs.json, file names (dates and collection
+// names) and file sizes stay plain.
+func (s *FSStore) Encrypt(passphrase string) (files, backups int, err error) {
+	err = s.locked(func() error {
+		var k *Key
+		p, err := s.cryptoParams()
+		switch {
+		case err == nil:
+			if k, err = s.Unlock(passphrase); err != nil {
+				return err
+			}
+		case errors.Is(err, ErrNotEncrypted):
+			if p, k, err = newParams(passphrase); err != nil {
+				return err
+			}
+			if err := writeJSONFile(s.cryptoPath(), p); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+		if files, err = s.convert(k, k); err != nil {
+			return err
+		}
+		if err := s.dropIndex(); err != nil {
+			return err
+		}
+		backups, err = s.sealBackups(p, k)
+		return err
+	})
+	return files, backups, err
+}
+
+// Decrypt opens every sealed file in the data directory with key and
+// removes crypto.json, which is done last so an interrupted run can be
+// repeated. It returns how many files it rewrote. Backups stay encrypted;
+// restoring one brings its crypto.json back.
+func (s *FSStore) Decrypt(key *Key) (int, error) {
+	n := 0
+	err := s.locked(func() error {
+		if !s.Encrypted() {
+			return ErrNotEncrypted
+		}
+		var err error
+		if n, err = s.convert(key, nil); err != nil {
+			return err
+		}
+		if err := s.dropIndex(); err != nil {
+			return err
+		}
+		return os.Remove(s.cryptoPath())
+	})
+	return n, err
+}
+
+// convert rewrites the bullet files, quarantined lines, undo journal and
+// recurrence rules that are not yet sealed with to, or plain when to is
+// nil, opening what is sealed with from. It returns how many files
+// changed. Callers hold the lock.
+func (s *FSStore) convert(from, to *Key) (int, error) {
+	n := 0
+	err := s.eachDataFile(func(path string, data []byte) error {
+		var changed bool
+		var err error
+		switch {
+		case strings.HasSuffix(path, ".jsonl"):
+			if changed, err = s.convertBullets(path, data, from, to); err == nil && changed {
+				// Rewriting may have quarantined lines after the sidecar was
+				// visited.
+				err = convertSidecar(path+corruptSuffix, from, to)
+			}
+		case strings.HasSuffix(path, corruptSuffix):
+			changed, err = convertLines(path, data, from, to)
+		case path == s.opLogPath():
+			var l OpLog
+			if err := readJSONFile(path, &l); err != nil {
+				return err
+			}
+			if to == nil && l.Sealed == "" || to != nil && len(l.Undo)+len(l.Redo) == 0 {
+				return nil
+			}
+			if l, err = from.openOpLog(l); err == nil {
+				changed, err = true, writeJSONFile(path, to.sealOpLog(l))
+			}
+		case path == s.recurrencesPath():
+			var rs []model.Recurrence
+			if err := readJSONFile(path, &rs); err != nil {
+				return err
+			}
+			if sealed := countFunc(rs, isSealedRecurrence); to == nil && sealed == 0 || to != nil && sealed == len(rs) {
+				return nil
+			}
+			if rs, err = from.openRecurrences(rs); err == nil {
+				changed, err = true, writeJSONFile(path, to.sealRecurrences(rs))
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.rel(path), err)
+		}
+		if changed {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// convertBullets rewrites a bullet file holding any plain bullet as one
+// sealed envelope, or expands its envelopes when to is nil.
+func (s *FSStore) convertBullets(path string, data []byte, from, to *Key) (bool, error) {
+	items, _ := parseBullets(data)
+	if sealed := countFunc(items, isEnvelope); to == nil && sealed == 0 || to != nil && sealed == len(items) {
+		return false, nil
+	}
+	items, err := from.openBullets(items)
+	if err != nil {
+		return false, err
+	}
+	return true, s.saveFile(path, to.sealBullets(items))
+}
+
+// convertSidecar converts the quarantine sidecar at path, if there is one.
+func convertSidecar(path string, from, to *Key) error {
+	data, err := readData(path)
+	if err != nil || data == nil {
+		return err
+	}
+	_, err = convertLines(path, data, from, to)
+	return err
+}
+
+// convertLines seals each line of a quarantine sidecar on its own, or opens
+// the sealed ones when to is nil.
+func convertLines(path string, data []byte, from, to *Key) (bool, error) {
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	changed := false
+	for i, line := range lines {
+		switch {
+		case strings.TrimSpace(line) == "" || (to != nil) == isSealed(line):
+			continue
+		case to != nil:
+			lines[i] = to.seal([]byte(line))
+		default:
+			plain, err := from.open(line)
+			if err != nil {
+				return false, err
+			}
+			lines[i] = string(plain)
+		}
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, writeFileAtomic(path, "day-*.tmp", []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+// countFunc returns how many elements of s satisfy f.
+func countFunc[T any](s []T, f func(T) bool) int {
+	n := 0
+	for _, v := range s {
+		if f(v) {
+			n++
+		}
+	}
+	return n
+}
+
+// sealBackups seals the journal in every archive in backups/ that is not
+// encrypted yet, rewriting it in place. Callers hold the lock.
+func (s *FSStore) sealBackups(p cryptoParams, key *Key) (int, error) {
+	all, err := s.Backups()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, b := range all {
+		sealed, err := s.sealBackup(filepath.Join(s.root, filepath.FromSlash(b.Path)), p, key)
+		if err != nil {
+			return n, fmt.Errorf("%s: %w", b.Path, err)
+		}
+		if sealed {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *FSStore) sealBackup(path string, p cryptoParams, key *Key) (bool, error) {
+	staged, err := os.MkdirTemp(s.root, ".seal-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(staged)
+	if err := extract(path, staged); err != nil {
+		return false, err
+	}
+	old := &FSStore{root: staged}
+	if old.Encrypted() {
+		return false, nil
+	}
+	if err := writeJSONFile(old.cryptoPath(), p); err != nil {
+		return false, err
+	}
+	if _, err := old.convert(key, key); err != nil {
+		return false, err
+	}
+	if err := old.dropIndex(); err != nil {
+		return false, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if err := old.writeArchive(path); err != nil {
+		return false, err
+	}
+	return true, os.Chtimes(path, fi.ModTime(), fi.ModTime())
+}
+
+// plainFiles are the files at the root that are never encrypted: they are
+// read before unlocking, or (prefs.json, and journals.json where the config
+// directory is the data directory) outside the store.
+var plainFiles = map[string]bool{"manifest.json": true, "crypto.json": true, "prefs.json": true, "journals.json": true, ".gitignore": true, ".gitattributes": true, lockName: true}
+
+// eachDataFile calls fn with the raw contents of every journal file: all
+// regular files except plainFiles, temp files, backups and hidden
+// directories.
+func (s *FSStore) eachDataFile(fn func(path string, data []byte) error) error {
+	return filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != s.root && (s.rel(path) == backupDir || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(d.Name(), ".tmp") ||
+			(filepath.Dir(path) == filepath.Clean(s.root) && plainFiles[d.Name()]) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return fn(path, data)
+	})
+}
+
+// PassphraseFromEnv returns the passphrase set by BLT_PASSPHRASE or, failing
+// that, read from the keyfile named by BLT_KEYFILE. ok is false when
+// neither is set.
+func PassphraseFromEnv() (pass string, ok bool, err error) {
+	if p := os.Getenv("BLT_PASSPHRASE"); p != "" {
+		return p, true, nil
+	}
+	if path := os.Getenv("BLT_KEYFILE"); path != "" {
+		p, err := ReadKeyFile(path)
+		return p, err == nil, err
+	}
+	return "", false, nil
+}
+
+// ReadKeyFile reads a passphrase from the first line of a keyfile.
+func ReadKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	pass, _, _ := strings.Cut(string(data), "\n")
+	pass = strings.TrimRight(pass, "\r")
+	if pass == "" {
+		return "", fmt.Errorf("keyfile %s is empty", path)
+	}
+	return pass, nil
+}
@@ -0,0 +1,285 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// ProblemKind classifies an issue found by Check.
+type ProblemKind string
+
+const (
+	ProblemBadLine      ProblemKind = "bad-line"      // line that is not a valid bullet
+	ProblemDuplicateID  ProblemKind = "duplicate-id"  // ID already used by an earlier bullet
+	ProblemTempFile     ProblemKind = "temp-file"     // leftover from an interrupted write
+	ProblemDanglingLink ProblemKind = "dangling-link" // origin/clone ID that no bullet has
+	ProblemSyncConflict ProblemKind = "sync-conflict" // conflicting copy left by a file synchronizer
+)
+
+// Problem is one issue in the data directory. Path is relative to the data
+// directory and Line is 1-based (0 for whole-file problems).
+type Problem struct {
+	Kind   ProblemKind `json:"kind"`
+	Path   string      `json:"path"`
+	Line   int         `json:"line,omitempty"`
+	Detail string      `json:"detail"`
+	Fixed  bool        `json:"fixed,omitempty"`
+}
+
+// tempPatterns match the temp files the store, preferences, backups and the
+// git setup write before renaming them into place.
+var tempPatterns = []string{"day-*.tmp", "json-*.tmp", "prefs-*.tmp", "backup-*.tmp", "git-*.tmp"}
+
+// scannedFile is a bullet file as read by Check.
+type scannedFile struct {
+	path    string
+	items   []model.Bullet
+	lines   []int    // source line of each item
+	raw     [][]byte // source text of each item
+	changed bool
+}
+
+// Check scans every bullet file under the data directory for unreadable
+// lines, duplicate IDs, leftover temp files, migration links to bullets
+// that no longer exist and conflict copies left by file synchronizers. With
+// fix it repairs them under the lock: conflict copies are merged into their
+// originals first, bad lines move to the quarantine sidecar, exact duplicate
+// lines are dropped and other duplicates get fresh IDs, temp files are
+// removed and dangling links are cleared. The files of an encrypted
+// journal are opened and sealed again with key, which is nil for a plain
+// one.
+func (s *FSStore) Check(fix bool, key *Key) ([]Problem, error) {
+	if !fix {
+		return s.check(false, key)
+	}
+	var problems []Problem
+	err := s.locked(func() error {
+		var err error
+		problems, err = s.check(true, key)
+		return err
+	})
+	return problems, err
+}
+
+func (s *FSStore) check(fix bool, key *Key) ([]Problem, error) {
+	var problems []Problem
+	var files []*scannedFile
+	var temps []string
+	copies, err := s.ConflictCopies()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range copies {
+		problems = append(problems, Problem{Kind: ProblemSyncConflict, Path: c.Path,
+			Detail: "conflicting copy of " + c.Original})
+		if fix {
+			if err := s.resolveConflict(c, key); err != nil {
+				return problems, err
+			}
+		}
+	}
+	err = filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != s.root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := conflictOriginal(d.Name()); ok {
+			return nil
+		}
+		if strings.HasSuffix(path, ".jsonl") {
+			f, bad, err := s.scanFile(path, key)
+			if err != nil {
+				return err
+			}
+			for _, b := range bad {
+				problems = append(problems, Problem{Kind: ProblemBadLine, Path: s.rel(path), Line: b.line,
+					Detail: "unreadable: " + clip(string(b.raw), 60)})
+			}
+			f.changed = len(bad) > 0
+			files = append(files, f)
+			return nil
+		}
+		for _, pat := range tempPatterns {
+			if ok, _ := filepath.Match(pat, d.Name()); ok {
+				temps = append(temps, path)
+				problems = append(problems, Problem{Kind: ProblemTempFile, Path: s.rel(path),
+					Detail: "leftover temp file from an interrupted write"})
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Duplicate IDs: the first occurrence (in path order) keeps the ID.
+	type site struct {
+		f *scannedFile
+		i int
+	}
+	first := map[string]site{}
+	ids := map[string]bool{}
+	for _, f := range files {
+		kept := f.items[:0:0]
+		var lines []int
+		var raw [][]byte
+		for i, it := range f.items {
+			prev, dup := first[it.ID]
+			switch {
+			case it.ID == "":
+				// Missing IDs are assigned whenever the file is saved.
+			case !dup:
+				first[it.ID] = site{f, i}
+				ids[it.ID] = true
+			default:
+				identical := prev.f == f && bytes.Equal(prev.f.raw[prev.i], f.raw[i])
+				p := Problem{Kind: ProblemDuplicateID, Path: s.rel(f.path), Line: f.lines[i],
+					Detail: fmt.Sprintf("id %s also at %s:%d", it.ID, s.rel(prev.f.path), prev.f.lines[prev.i])}
+				if identical {
+					p.Detail += " (identical copy)"
+				}
+				problems = append(problems, p)
+				if fix && identical {
+					f.changed = true
+					continue
+				}
+				if fix {
+					it.ID = generateID()
+					ids[it.ID] = true
+					f.changed = true
+				}
+			}
+			kept = append(kept, it)
+			lines = append(lines, f.lines[i])
+			raw = append(raw, f.raw[i])
+		}
+		f.items, f.lines, f.raw = kept, lines, raw
+	}
+
+	// Dangling links: origin or clone IDs no bullet carries.
+	for _, f := range files {
+		for i := range f.items {
+			it := &f.items[i]
+			if it.OriginID != "" && !ids[it.OriginID] {
+				problems = append(problems, Problem{Kind: ProblemDanglingLink, Path: s.rel(f.path), Line: f.lines[i],
+					Detail: fmt.Sprintf("%s: origin %s not found", shown(*it), it.OriginID)})
+				if fix {
+					it.OriginID, it.OriginDate, it.OriginLog = "", nil, ""
+					f.changed = true
+				}
+			}
+			if it.CloneID != "" && !ids[it.CloneID] {
+				problems = append(problems, Problem{Kind: ProblemDanglingLink, Path: s.rel(f.path), Line: f.lines[i],
+					Detail: fmt.Sprintf("%s: copy %s not found", shown(*it), it.CloneID)})
+				if fix {
+					it.CloneID, it.CloneLog = "", ""
+					f.changed = true
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Path != problems[j].Path {
+			return problems[i].Path < problems[j].Path
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	if !fix {
+		return problems, nil
+	}
+
+	for _, f := range files {
+		if !f.changed {
+			continue
+		}
+		if err := s.saveFile(f.path, key.sealBullets(f.items)); err != nil {
+			return problems, err
+		}
+		if date, ok := s.dayFromPath(f.path); ok {
+			s.reindexDay(date)
+		}
+	}
+	for _, path := range temps {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return problems, err
+		}
+	}
+	for i := range problems {
+		problems[i].Fixed = true
+	}
+	return problems, nil
+}
+
+type badLine struct {
+	line int
+	raw  []byte
+}
+
+// scanFile reads a bullet file keeping source line numbers. The bullets of
+// an envelope, opened with key, all get the envelope's line.
+func (s *FSStore) scanFile(path string, key *Key) (*scannedFile, []badLine, error) {
+	data, err := readData(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	f := &scannedFile{path: path}
+	var bad []badLine
+	for n, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var b model.Bullet
+		if err := json.Unmarshal(line, &b); err != nil {
+			bad = append(bad, badLine{line: n + 1, raw: line})
+			continue
+		}
+		if !isEnvelope(b) {
+			f.items = append(f.items, b)
+			f.lines = append(f.lines, n+1)
+			f.raw = append(f.raw, line)
+			continue
+		}
+		items, err := key.openBullets([]model.Bullet{b})
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: %w", s.rel(path), n+1, err)
+		}
+		for _, it := range items {
+			raw, _ := json.Marshal(it)
+			f.items = append(f.items, it)
+			f.lines = append(f.lines, n+1)
+			f.raw = append(f.raw, raw)
+		}
+	}
+	return f, bad, nil
+}
+
+// shown is b's text as quoted in problem details.
+func shown(b model.Bullet) string {
+	return strconv.Quote(clip(b.Text, 40))
+}
+
+// clip shortens s to at most n runes for display.
+func clip(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
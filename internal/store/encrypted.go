@@ -0,0 +1,436 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"iter"
+	"slices"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// EncryptedStore wraps a Store, sealing each list it writes (a day, a
+// monthly log, a collection) as a whole with a Key and opening it again on
+// read. The wrapped store holds a single envelope record per list: a bullet
+// with ID envelopeID whose Text seals the list's bullets, IDs, times and
+// links included, under a fresh random nonce. Only which lists exist and
+// roughly how large they are can be told without the key. Bullets that are
+// not sealed, such as lines added by hand, are read as they are and sealed
+// with the rest on the next write.
+type EncryptedStore struct {
+	inner Store
+	key   *Key
+}
+
+// Encrypter is implemented by stores that may seal what they hold. Callers
+// keep bullet text out of what they write beside such a store in plain
+// text, such as commit messages.
+type Encrypter interface {
+	Encrypted() bool
+}
+
+// journalStore is the set of optional interfaces FSStore implements.
+type journalStore interface {
+	Store
+	Inserter
+	Updater
+	Finder
+	Searcher
+	Warner
+	Committer
+	OpLogger
+	RecurrenceStore
+	LogStore
+	CollectionStore
+	TrashStore
+}
+
+// sealableJournal is a journalStore that can also rename a collection when
+// the references to it are sealed, as FSStore can.
+type sealableJournal interface {
+	journalStore
+	renameCollection(from, to string, key *Key) error
+}
+
+// encryptedJournal is an EncryptedStore over a sealableJournal, passing the
+// optional interfaces through and sealing what they hold.
+type encryptedJournal struct {
+	*EncryptedStore
+	j sealableJournal
+}
+
+// NewEncryptedStore wraps inner in an EncryptedStore sealing with key. When
+// inner implements every optional interface FSStore does (logs,
+// collections, trash, undo journal, recurrences), so does the result;
+// otherwise it offers Store, Inserter, Updater, Finder and Searcher, plus
+// Warner and Committer passing through to inner.
+func NewEncryptedStore(inner Store, key *Key) Store {
+	e := &EncryptedStore{inner: inner, key: key}
+	if j, ok := inner.(sealableJournal); ok {
+		return &encryptedJournal{EncryptedStore: e, j: j}
+	}
+	return e
+}
+
+// KeyOf returns the key s seals with when it is an EncryptedStore, and nil
+// otherwise, for maintenance that reads the files below s directly, such
+// as FSStore.Check, MergeFiles and History.
+func KeyOf(s Store) *Key {
+	switch e := s.(type) {
+	case *EncryptedStore:
+		return e.key
+	case *encryptedJournal:
+		return e.key
+	}
+	return nil
+}
+
+// Encrypted reports true: everything written through e is sealed.
+func (e *EncryptedStore) Encrypted() bool { return true }
+
+func (e *EncryptedStore) LoadDay(date time.Time) ([]model.Bullet, error) {
+	items, err := e.inner.LoadDay(date)
+	if err != nil {
+		return nil, err
+	}
+	return e.key.openBullets(items)
+}
+
+func (e *EncryptedStore) SaveDay(date time.Time, items []model.Bullet) error {
+	return e.inner.SaveDay(date, e.key.sealBullets(items))
+}
+
+func (e *EncryptedStore) Append(date time.Time, b model.Bullet) error {
+	return e.UpdateDay(date, func(items []model.Bullet) ([]model.Bullet, error) {
+		return append(items, b), nil
+	})
+}
+
+func (e *EncryptedStore) InsertAt(date time.Time, index int, b model.Bullet) error {
+	return e.UpdateDay(date, func(items []model.Bullet) ([]model.Bullet, error) {
+		return insertBullet(items, index, b), nil
+	})
+}
+
+// Update stamps b against the stored bullet here, since the wrapped store
+// cannot compare sealed contents.
+func (e *EncryptedStore) Update(date time.Time, b model.Bullet) error {
+	return e.UpdateDay(date, func(items []model.Bullet) ([]model.Bullet, error) {
+		if i := indexOfID(items, b.ID); i >= 0 {
+			items[i] = touched(items[i], b)
+		}
+		return items, nil
+	})
+}
+
+func (e *EncryptedStore) Delete(date time.Time, id string) error {
+	return e.UpdateDay(date, func(items []model.Bullet) ([]model.Bullet, error) {
+		return slices.DeleteFunc(items, func(it model.Bullet) bool { return it.ID == id }), nil
+	})
+}
+
+// UpdateDay applies fn to date's opened bullets and seals the result as one
+// step when the wrapped store is an Updater, and by loading and saving the
+// day otherwise. A list fn leaves as it was is not sealed again.
+func (e *EncryptedStore) UpdateDay(date time.Time, fn func(items []model.Bullet) ([]model.Bullet, error)) error {
+	apply := func(sealed []model.Bullet) ([]model.Bullet, error) {
+		items, err := e.key.openBullets(sealed)
+		if err != nil {
+			return nil, err
+		}
+		before, _ := json.Marshal(items)
+		if items, err = fn(items); err != nil {
+			return nil, err
+		}
+		if after, _ := json.Marshal(items); bytes.Equal(before, after) {
+			return sealed, nil
+		}
+		return e.key.sealBullets(items), nil
+	}
+	if u, ok := e.inner.(Updater); ok {
+		return u.UpdateDay(date, apply)
+	}
+	sealed, err := e.inner.LoadDay(date)
+	if err != nil {
+		return err
+	}
+	if sealed, err = apply(sealed); err != nil {
+		return err
+	}
+	return e.inner.SaveDay(date, sealed)
+}
+
+func (e *EncryptedStore) LoadRange(start, end time.Time) ([]Day, error) {
+	return collectRange(e.Range(start, end))
+}
+
+func (e *EncryptedStore) Range(start, end time.Time) iter.Seq2[Day, error] {
+	return func(yield func(Day, error) bool) {
+		for d, err := range e.inner.Range(start, end) {
+			if err == nil {
+				d.Items, err = e.key.openBullets(d.Items)
+			}
+			if !yield(d, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// FindID scans every day: the wrapped store cannot see sealed IDs.
+func (e *EncryptedStore) FindID(ref string) (time.Time, model.Bullet, error) {
+	return findID(e.Range(time.Time{}, time.Time{}), ref)
+}
+
+// Search matches q against the opened bullets of every day in its range:
+// the wrapped store's index cannot see sealed contents.
+func (e *EncryptedStore) Search(q Query) ([]Hit, error) {
+	match := q.matcher()
+	var hits []Hit
+	for d, err := range e.Range(q.Start, q.End) {
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range d.Items {
+			if match(entryOf(it)) {
+				hits = append(hits, Hit{Date: d.Date, Bullet: it})
+			}
+		}
+	}
+	return hits, nil
+}
+
+func (e *EncryptedStore) DaysBetween(start, end time.Time) ([]time.Time, error) {
+	if s, ok := e.inner.(Searcher); ok {
+		return s.DaysBetween(start, end)
+	}
+	var out []time.Time
+	for d, err := range e.inner.Range(start, end) {
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d.Date)
+	}
+	return out, nil
+}
+
+func (e *EncryptedStore) Reindex() error {
+	if s, ok := e.inner.(Searcher); ok {
+		return s.Reindex()
+	}
+	return nil
+}
+
+func (e *EncryptedStore) Warnings() []string {
+	if w, ok := e.inner.(Warner); ok {
+		return w.Warnings()
+	}
+	return nil
+}
+
+func (e *EncryptedStore) Commit(message string) error {
+	if c, ok := e.inner.(Committer); ok {
+		return c.Commit(message)
+	}
+	return nil
+}
+
+func (e *encryptedJournal) wrap(s Store) Store { return &EncryptedStore{inner: s, key: e.key} }
+
+func (e *encryptedJournal) MonthLog() Store              { return e.wrap(e.j.MonthLog()) }
+func (e *encryptedJournal) FutureLog() Store             { return e.wrap(e.j.FutureLog()) }
+func (e *encryptedJournal) Trash() Store                 { return e.wrap(e.j.Trash()) }
+func (e *encryptedJournal) Collection(name string) Store { return e.wrap(e.j.Collection(name)) }
+
+func (e *encryptedJournal) Collections() ([]string, error)     { return e.j.Collections() }
+func (e *encryptedJournal) CreateCollection(name string) error { return e.j.CreateCollection(name) }
+func (e *encryptedJournal) DeleteCollection(name string) error { return e.j.DeleteCollection(name) }
+
+// RenameCollection relinks the references to the collection inside the
+// sealed files and undo journal.
+func (e *encryptedJournal) RenameCollection(from, to string) error {
+	return e.j.renameCollection(from, to, e.key)
+}
+
+func (e *encryptedJournal) LoadOpLog() (OpLog, error) {
+	l, err := e.j.LoadOpLog()
+	if err != nil {
+		return l, err
+	}
+	return e.key.openOpLog(l)
+}
+
+func (e *encryptedJournal) SaveOpLog(l OpLog) error {
+	return e.j.SaveOpLog(e.key.sealOpLog(l))
+}
+
+func (e *encryptedJournal) UpdateOpLog(fn func(l *OpLog) error) error {
+	return e.j.UpdateOpLog(func(l *OpLog) error {
+		open, err := e.key.openOpLog(*l)
+		if err != nil {
+			return err
+		}
+		if err := fn(&open); err != nil {
+			return err
+		}
+		*l = e.key.sealOpLog(open)
+		return nil
+	})
+}
+
+func (e *encryptedJournal) LoadRecurrences() ([]model.Recurrence, error) {
+	rs, err := e.j.LoadRecurrences()
+	if err != nil {
+		return nil, err
+	}
+	return e.key.openRecurrences(rs)
+}
+
+func (e *encryptedJournal) SaveRecurrences(rs []model.Recurrence) error {
+	return e.j.SaveRecurrences(e.key.sealRecurrences(rs))
+}
+
+// UpdateRecurrences seals the bullets fn generates for each day as an
+// envelope of their own, which the wrapped store appends after the day's
+// other envelopes.
+func (e *encryptedJournal) UpdateRecurrences(fn func(rs []model.Recurrence) ([]model.Recurrence, []Day, error)) error {
+	return e.j.UpdateRecurrences(func(rs []model.Recurrence) ([]model.Recurrence, []Day, error) {
+		rs, err := e.key.openRecurrences(rs)
+		if err != nil {
+			return nil, nil, err
+		}
+		rs, add, err := fn(rs)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range add {
+			add[i].Items = e.key.sealBullets(add[i].Items)
+		}
+		return e.key.sealRecurrences(rs), add, nil
+	})
+}
+
+// envelopeID is the ID of the records that stand for a sealed list, with
+// its contents sealed in Text and every other field left empty. They carry
+// a fixed creation time so the store below does not stamp them with the
+// time of the write.
+const envelopeID = "sealed"
+
+var envelopeTime = time.Unix(0, 0).UTC()
+
+func isEnvelope(b model.Bullet) bool {
+	return b.ID == envelopeID && b.Type == "" && isSealed(b.Text)
+}
+
+func isSealedRecurrence(r model.Recurrence) bool {
+	return r.ID == envelopeID && r.Type == "" && isSealed(r.Text)
+}
+
+// openBullets expands the envelopes in items, in order, keeping plain
+// bullets as they are. A nil Key returns ErrEncrypted for an envelope.
+func (k *Key) openBullets(items []model.Bullet) ([]model.Bullet, error) {
+	if !slices.ContainsFunc(items, isEnvelope) {
+		return items, nil
+	}
+	out := make([]model.Bullet, 0, len(items))
+	for _, b := range items {
+		if !isEnvelope(b) {
+			out = append(out, b)
+			continue
+		}
+		var list []model.Bullet
+		if err := k.openJSON(b.Text, &list); err != nil {
+			return nil, err
+		}
+		out = append(out, list...)
+	}
+	return out, nil
+}
+
+// sealBullets returns the envelope sealing items, after filling in the IDs
+// and creation times the store below can no longer assign. An empty list
+// stays empty, and a nil Key returns items as they are.
+func (k *Key) sealBullets(items []model.Bullet) []model.Bullet {
+	if k == nil {
+		return items
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	list := make([]model.Bullet, len(items))
+	for i, it := range items {
+		if it.ID == "" {
+			it.ID = generateID()
+		}
+		if it.CreatedAt.IsZero() {
+			it.CreatedAt = time.Now()
+		}
+		list[i] = it
+	}
+	return []model.Bullet{{ID: envelopeID, Text: k.sealJSON(list), CreatedAt: envelopeTime}}
+}
+
+// openRecurrences is openBullets for recurrence rules.
+func (k *Key) openRecurrences(rs []model.Recurrence) ([]model.Recurrence, error) {
+	if !slices.ContainsFunc(rs, isSealedRecurrence) {
+		return rs, nil
+	}
+	out := make([]model.Recurrence, 0, len(rs))
+	for _, r := range rs {
+		if !isSealedRecurrence(r) {
+			out = append(out, r)
+			continue
+		}
+		var list []model.Recurrence
+		if err := k.openJSON(r.Text, &list); err != nil {
+			return nil, err
+		}
+		out = append(out, list...)
+	}
+	return out, nil
+}
+
+// sealRecurrences is sealBullets for recurrence rules.
+func (k *Key) sealRecurrences(rs []model.Recurrence) []model.Recurrence {
+	if k == nil || len(rs) == 0 {
+		return rs
+	}
+	return []model.Recurrence{{ID: envelopeID, Text: k.sealJSON(rs)}}
+}
+
+// openOpLog returns l with the operations sealed in l.Sealed opened and
+// put before any plain ones.
+func (k *Key) openOpLog(l OpLog) (OpLog, error) {
+	if l.Sealed == "" {
+		return l, nil
+	}
+	var out OpLog
+	if err := k.openJSON(l.Sealed, &out); err != nil {
+		return l, err
+	}
+	out.Undo, out.Redo = append(out.Undo, l.Undo...), append(out.Redo, l.Redo...)
+	return out, nil
+}
+
+// sealOpLog returns l with all its operations sealed into Sealed.
+func (k *Key) sealOpLog(l OpLog) OpLog {
+	if k == nil || len(l.Undo)+len(l.Redo) == 0 {
+		return l
+	}
+	return OpLog{Sealed: k.sealJSON(OpLog{Undo: l.Undo, Redo: l.Redo})}
+}
+
+var (
+	_ Store    = (*EncryptedStore)(nil)
+	_ Finder   = (*EncryptedStore)(nil)
+	_ Searcher = (*EncryptedStore)(nil)
+	_ Inserter = (*EncryptedStore)(nil)
+	_ Updater  = (*EncryptedStore)(nil)
+
+	_ Encrypter = (*EncryptedStore)(nil)
+	_ Encrypter = (*FSStore)(nil)
+
+	_ journalStore    = (*encryptedJournal)(nil)
+	_ sealableJournal = (*FSStore)(nil)
+)
@@ -0,0 +1,354 @@
+package store_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+	"github.com/rdo34/blt/internal/store/storetest"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	key, err := store.NewKey("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("MemStore", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Store {
+			return store.NewEncryptedStore(store.NewMemStore(), key)
+		})
+	})
+	t.Run("FSStore", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Store {
+			s, err := store.NewFSStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return store.NewEncryptedStore(s, key)
+		})
+	})
+}
+
+func TestEncryptedStoreSealsContents(t *testing.T) {
+	key, err := store.NewKey("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := store.NewMemStore()
+	s := store.NewEncryptedStore(inner, key)
+	d := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	b := model.Bullet{ID: "bullet-1", Type: model.Task, Text: "secret plans", Tags: []string{"private"}, ParentID: "parent-1"}
+	if err := s.Append(d, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(d, model.Bullet{Type: model.Note, Text: "more"}); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := inner.LoadDay(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Quotes and dashes never occur in the sealed text.
+	data, _ := json.Marshal(raw)
+	for _, clear := range []string{"bullet-1", "parent-1", `"task"`, `"note"`, `"private"`} {
+		if len(raw) != 1 || strings.Contains(string(data), clear) {
+			t.Errorf("wrapped store holds %s", data)
+			break
+		}
+	}
+	items, err := s.LoadDay(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].ID != "bullet-1" || items[0].Text != "secret plans" || items[0].ParentID != "parent-1" || items[1].ID == "" {
+		t.Errorf("read back %+v", items)
+	}
+
+	// The same list seals to different text every time.
+	other := d.AddDate(0, 0, 1)
+	if err := s.SaveDay(other, items); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := inner.LoadDay(other)
+	if again[0].Text == raw[0].Text {
+		t.Error("the same list sealed to the same text twice")
+	}
+
+	// An update that changes nothing leaves the sealed list alone; an edit
+	// is stamped.
+	if err := s.Update(d, items[0]); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := inner.LoadDay(d); again[0].Text != raw[0].Text {
+		t.Error("no-op update sealed the day again")
+	}
+	items[0].Text = "secret plans, revised"
+	if err := s.Update(d, items[0]); err != nil {
+		t.Fatal(err)
+	}
+	items, _ = s.LoadDay(d)
+	if got := items[0]; got.UpdatedAt == nil || len(got.Revisions) != 1 || got.Revisions[0].Text != "secret plans" {
+		t.Errorf("edit recorded %v, %+v", got.UpdatedAt, got.Revisions)
+	}
+	if err := s.Delete(d, "bullet-1"); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ = s.LoadDay(d); len(items) != 1 || items[0].Text != "more" {
+		t.Errorf("after delete: %+v", items)
+	}
+
+	otherKey, err := store.NewKey("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.NewEncryptedStore(inner, otherKey).LoadDay(d); err == nil {
+		t.Error("read with another key")
+	}
+}
+
+func TestEncryptedJournalMaintenance(t *testing.T) {
+	dir := t.TempDir()
+	st, err := store.NewFSStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.Encrypt("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	st, data, err := store.NewEncryptedFSStore(dir, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := store.KeyOf(data)
+	d := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	texts := func() string {
+		t.Helper()
+		items, err := data.LoadDay(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, it := range items {
+			out = append(out, it.Text+"@"+it.OriginLog)
+		}
+		return strings.Join(out, " ")
+	}
+
+	// Renaming a collection relinks the bullets copied from it.
+	cs := data.(store.CollectionStore)
+	if err := cs.Collection("ideas").Append(time.Time{}, model.Bullet{ID: "i1", Type: model.Task, Text: "trip"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.Append(d, model.Bullet{Type: model.Task, Text: "trip", OriginID: "i1", OriginLog: store.CollectionRef("ideas")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.RenameCollection("ideas", "travel"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := texts(), "trip@"+store.CollectionRef("travel"); got != want {
+		t.Errorf("after rename: %q, want %q", got, want)
+	}
+
+	// Merging opens each side and seals the result.
+	path := filepath.Join(dir, "2026", "03", "10.jsonl")
+	read := func() []byte {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	write := func(name string, data []byte) string {
+		t.Helper()
+		p := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	base := read()
+	if err := data.Append(d, model.Bullet{Type: model.Task, Text: "ours"}); err != nil {
+		t.Fatal(err)
+	}
+	ours := write("ours", read())
+	if err := os.WriteFile(path, base, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.Append(d, model.Bullet{Type: model.Task, Text: "theirs"}); err != nil {
+		t.Fatal(err)
+	}
+	theirs := write("theirs", read())
+	if _, err := st.MergeFiles(write("base", base), ours, theirs, path, nil); !errors.Is(err, store.ErrEncrypted) {
+		t.Errorf("merge without the key: %v", err)
+	}
+	if _, err := st.MergeFiles(write("base", base), ours, theirs, path, key); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := texts(), "trip@"+store.CollectionRef("travel")+" theirs@ ours@"; got != want {
+		t.Errorf("after merge: %q, want %q", got, want)
+	}
+	if lines := strings.Count(string(read()), "\n"); lines != 1 || strings.Contains(string(read()), "ours") {
+		t.Errorf("merged file holds %d lines: %s", lines, read())
+	}
+
+	if problems, err := st.Check(false, key); err != nil || len(problems) != 0 {
+		t.Errorf("check: %+v, %v", problems, err)
+	}
+	if _, err := st.Check(false, nil); !errors.Is(err, store.ErrEncrypted) {
+		t.Errorf("check without the key: %v", err)
+	}
+}
+
+// plainHits returns the files under dir, backup archives included, that
+// hold word in plain text.
+func plainHits(t *testing.T, dir, word string) []string {
+	t.Helper()
+	var hits []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(path, ".tar.gz") {
+			data = untar(t, path)
+		}
+		if strings.Contains(string(data), word) {
+			hits = append(hits, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hits
+}
+
+// untar returns the concatenated contents of the files in a tar.gz archive.
+func untar(t *testing.T, path string) []byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []byte
+	tr := tar.NewReader(gz)
+	for {
+		if _, err := tr.Next(); err == io.EOF {
+			return out
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, data...)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	st, err := store.NewFSStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	// The IDs hold the word as well: they are sealed with the rest.
+	if err := st.Append(d, model.Bullet{ID: "zanzibar-1", Type: model.Task, Text: "zanzibar trip"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Collection("ideas").Append(time.Time{}, model.Bullet{ID: "zanzibar-2", Type: model.Note, Text: "zanzibar book"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveRecurrences([]model.Recurrence{{ID: "zanzibar-3", Type: model.Task, Text: "zanzibar call", Freq: model.RecurDaily}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveOpLog(store.OpLog{Undo: []store.Op{{Label: "add: zanzibar trip"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if hits, err := st.Search(store.Query{Text: "zanzibar"}); err != nil || len(hits) != 1 {
+		t.Fatalf("search before encrypting: %d hits, %v", len(hits), err)
+	}
+	if _, err := st.Snapshot(d); err != nil {
+		t.Fatal(err)
+	}
+
+	files, backups, err := st.Encrypt("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != 4 || backups != 1 {
+		t.Errorf("encrypted %d files and %d backups, want 4 and 1", files, backups)
+	}
+	if hits := plainHits(t, dir, "zanzibar"); len(hits) > 0 {
+		t.Errorf("plain text left in %v", hits)
+	}
+	if _, err := store.NewFSStore(dir); !errors.Is(err, store.ErrEncrypted) {
+		t.Errorf("NewFSStore on an encrypted dir: %v", err)
+	}
+	if _, _, err := store.NewEncryptedFSStore(dir, "wrong"); !errors.Is(err, store.ErrBadPassphrase) {
+		t.Errorf("wrong passphrase: %v", err)
+	}
+	if _, _, err := st.Encrypt("wrong"); !errors.Is(err, store.ErrBadPassphrase) {
+		t.Errorf("encrypting again with another passphrase: %v", err)
+	}
+
+	_, data, err := store.NewEncryptedFSStore(dir, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, err := data.(store.Searcher).Search(store.Query{Text: "zanzibar"})
+	if err != nil || len(hits) != 1 || hits[0].Bullet.Text != "zanzibar trip" {
+		t.Errorf("search after encrypting: %+v, %v", hits, err)
+	}
+	items, err := data.(store.CollectionStore).Collection("ideas").LoadDay(time.Time{})
+	if err != nil || len(items) != 1 || items[0].Text != "zanzibar book" {
+		t.Errorf("collection after encrypting: %+v, %v", items, err)
+	}
+	if rs, err := data.(store.RecurrenceStore).LoadRecurrences(); err != nil || rs[0].Text != "zanzibar call" {
+		t.Errorf("recurrences after encrypting: %+v, %v", rs, err)
+	}
+	if l, err := data.(store.OpLogger).LoadOpLog(); err != nil || l.Undo[0].Label != "add: zanzibar trip" {
+		t.Errorf("undo journal after encrypting: %+v, %v", l, err)
+	}
+	if err := data.Append(d, model.Bullet{Type: model.Task, Text: "zanzibar visa"}); err != nil {
+		t.Fatal(err)
+	}
+	if hits := plainHits(t, dir, "zanzibar"); len(hits) > 0 {
+		t.Errorf("plain text written to %v", hits)
+	}
+
+	key, err := st.Unlock("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Decrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	st, err = store.NewFSStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits, err := st.Search(store.Query{Text: "zanzibar"}); err != nil || len(hits) != 2 {
+		t.Errorf("search after decrypting: %d hits, %v", len(hits), err)
+	}
+}
@@ -1,15 +1,19 @@
 package store
 
 import (
-	"bufio"
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"io/fs"
+	"iter"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rdo34/blt/internal/model"
@@ -17,11 +21,42 @@ import (
 
 // FSStore implements Store using per-day JSONL files under a data root.
 type FSStore struct {
-	root string
+	root    string
+	ix      indexCache
+	bad     badLines
+	notices []string // one-off messages such as schema upgrades, shown with Warnings
 }
 
-// NewFSStore creates a store rooted at dir, creating it if needed.
+// NewFSStore creates a store rooted at dir, creating it if needed, and
+// upgrades its data to the current schema version. Encrypted directories
+// return ErrEncrypted and are opened with NewEncryptedFSStore, which puts
+// an EncryptedStore over the file store.
 func NewFSStore(dir string) (*FSStore, error) {
+	s, err := OpenFSStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	if s.Encrypted() {
+		return nil, fmt.Errorf("%w: %s (use NewEncryptedFSStore)", ErrEncrypted, dir)
+	}
+	return s, s.upgradeOnOpen()
+}
+
+// upgradeOnOpen runs pending schema upgrades, noting them for Warnings.
+func (s *FSStore) upgradeOnOpen() error {
+	rep, err := s.UpgradeSchema(false)
+	if err != nil {
+		return err
+	}
+	if rep.Backup != "" {
+		s.notices = append(s.notices, fmt.Sprintf("upgraded data from schema version %d to %d (backup: %s)", rep.From, rep.To, rep.Backup))
+	}
+	return nil
+}
+
+// OpenFSStore opens a store rooted at dir without upgrading its schema, for
+// inspecting or previewing upgrades with UpgradeSchema.
+func OpenFSStore(dir string) (*FSStore, error) {
 	if dir == "" {
 		return nil, errors.New("empty dir")
 	}
@@ -31,71 +66,127 @@ func NewFSStore(dir string) (*FSStore, error) {
 	return &FSStore{root: dir}, nil
 }
 
-// NewDefaultFSStore resolves the default data dir and returns a store.
+// NewDefaultFSStore resolves the default journal and returns a store.
 func NewDefaultFSStore() (*FSStore, error) {
-	dir, err := ResolveDataDir()
+	j, err := ResolveJournal("")
 	if err != nil {
 		return nil, err
 	}
-	return NewFSStore(dir)
+	return NewFSStore(j.Dir)
 }
 
+// Root returns the data directory the store reads and writes.
+func (s *FSStore) Root() string { return s.root }
+
 func (s *FSStore) dayPath(date time.Time) string {
 	y, m, d := date.Date()
 	return filepath.Join(s.root, fmt.Sprintf("%04d", y), fmt.Sprintf("%02d", m), fmt.Sprintf("%02d.jsonl", d))
 }
 
-func (s *FSStore) ensureDayDir(date time.Time) error {
-	y, m, _ := date.Date()
-	return os.MkdirAll(filepath.Join(s.root, fmt.Sprintf("%04d", y), fmt.Sprintf("%02d", m)), 0o755)
-}
-
 // LoadDay reads all bullets for the given date from the JSONL file.
 func (s *FSStore) LoadDay(date time.Time) ([]model.Bullet, error) {
-	path := s.dayPath(date)
-	f, err := os.Open(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []model.Bullet{}, nil
+	return s.loadFile(s.dayPath(date))
+}
+
+// SaveDay atomically writes all bullets for the given day.
+func (s *FSStore) SaveDay(date time.Time, items []model.Bullet) error {
+	return s.writeDay(date, func(path string) error { return s.saveFile(path, items) })
+}
+
+// Append adds a single bullet to the day file as one JSON line.
+func (s *FSStore) Append(date time.Time, b model.Bullet) error {
+	return s.writeDay(date, func(path string) error { return s.appendFile(path, b) })
+}
+
+// InsertAt inserts b at index in the day file under the lock; see Inserter.
+func (s *FSStore) InsertAt(date time.Time, index int, b model.Bullet) error {
+	return s.writeDay(date, func(path string) error { return s.insertFile(path, index, b) })
+}
+
+// Update replaces a bullet with matching ID for that day; no-op if not found.
+func (s *FSStore) Update(date time.Time, b model.Bullet) error {
+	return s.writeDay(date, func(path string) error { return s.updateFile(path, b) })
+}
+
+// Delete removes a bullet by ID for that day; no-op if not found.
+func (s *FSStore) Delete(date time.Time, id string) error {
+	return s.writeDay(date, func(path string) error { return s.deleteFile(path, id) })
+}
+
+// UpdateDay rewrites the day file with fn's result under the lock; see
+// Updater.
+func (s *FSStore) UpdateDay(date time.Time, fn func(items []model.Bullet) ([]model.Bullet, error)) error {
+	return s.writeDay(date, func(path string) error { return s.rewriteFile(path, fn) })
+}
+
+// writeDay runs write on date's file and refreshes its index entry, both
+// under the data directory lock.
+func (s *FSStore) writeDay(date time.Time, write func(path string) error) error {
+	return s.locked(func() error {
+		if err := write(s.dayPath(date)); err != nil {
+			return err
 		}
-		return nil, err
+		s.reindexDay(date)
+		return nil
+	})
+}
+
+// loadFile reads all bullets from a JSONL file; a missing file is empty.
+// Unreadable lines are skipped and reported through Warnings.
+func (s *FSStore) loadFile(path string) ([]model.Bullet, error) {
+	items, bad, err := s.readFile(path)
+	s.noteBad(path, len(bad))
+	return items, err
+}
+
+// readData returns the contents of path; a missing file is empty.
+func readData(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
 	}
-	defer f.Close()
+	return data, err
+}
 
-	var out []model.Bullet
-	r := bufio.NewReader(f)
-	for {
-		line, err := r.ReadBytes('\n')
-		if len(line) > 0 {
-			var b model.Bullet
-			if jerr := json.Unmarshal(bytesTrimRightNewline(line), &b); jerr == nil {
-				out = append(out, b)
-			}
-		}
-		if err == io.EOF {
-			break
+// readFile parses a JSONL file, returning the bullets and the raw lines
+// that failed to parse. Blank lines are ignored; a missing file is empty.
+func (s *FSStore) readFile(path string) ([]model.Bullet, [][]byte, error) {
+	data, err := readData(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, bad := parseBullets(data)
+	return out, bad, nil
+}
+
+// parseBullets decodes JSONL data, returning unparseable lines separately.
+func parseBullets(data []byte) ([]model.Bullet, [][]byte) {
+	out := []model.Bullet{}
+	var bad [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
 		}
-		if err != nil {
-			return out, err
+		var b model.Bullet
+		if err := json.Unmarshal(line, &b); err != nil {
+			bad = append(bad, line)
+			continue
 		}
+		out = append(out, b)
 	}
-	return out, nil
+	return out, bad
 }
 
-// SaveDay atomically writes all bullets for the given day.
-func (s *FSStore) SaveDay(date time.Time, items []model.Bullet) error {
-	if err := s.ensureDayDir(date); err != nil {
-		return err
-	}
-	path := s.dayPath(date)
-	dir := filepath.Dir(path)
-	tmp, err := os.CreateTemp(dir, "day-*.tmp")
-	if err != nil {
+// saveFile atomically replaces a JSONL file with items. Lines of the old
+// file that could not be parsed are first moved to the quarantine sidecar,
+// so rewriting a damaged file never loses them. Callers hold the lock.
+func (s *FSStore) saveFile(path string, items []model.Bullet) error {
+	if err := s.quarantine(path); err != nil {
 		return err
 	}
-	tmpPath := tmp.Name()
-	bw := bufio.NewWriter(tmp)
-	enc := json.NewEncoder(bw)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
 	for _, it := range items {
 		if it.ID == "" {
 			it.ID = generateID()
@@ -104,62 +195,78 @@ func (s *FSStore) SaveDay(date time.Time, items []model.Bullet) error {
 			it.CreatedAt = time.Now()
 		}
 		if err := enc.Encode(&it); err != nil {
-			tmp.Close()
-			os.Remove(tmpPath)
 			return err
 		}
 	}
-	if err := bw.Flush(); err != nil {
-		tmp.Close()
-		os.Remove(tmpPath)
-		return err
-	}
-	if err := tmp.Close(); err != nil {
-		os.Remove(tmpPath)
-		return err
-	}
-	return os.Rename(tmpPath, path)
+	return writeFileAtomic(path, "day-*.tmp", buf.Bytes())
 }
 
-// Append adds a single bullet to the day file as one JSON line.
-func (s *FSStore) Append(date time.Time, b model.Bullet) error {
-	if err := s.ensureDayDir(date); err != nil {
-		return err
-	}
+// appendFile adds a single bullet to a JSONL file as one line.
+func (s *FSStore) appendFile(path string, b model.Bullet) error {
 	if b.ID == "" {
 		b.ID = generateID()
 	}
 	if b.CreatedAt.IsZero() {
 		b.CreatedAt = time.Now()
 	}
-	path := s.dayPath(date)
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	line, err := json.Marshal(&b)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	enc := json.NewEncoder(f)
-	return enc.Encode(&b)
+	return appendData(path, append(line, '\n'))
 }
 
-// Update replaces a bullet with matching ID for that day; no-op if not found.
-func (s *FSStore) Update(date time.Time, b model.Bullet) error {
-	items, err := s.LoadDay(date)
+func (s *FSStore) insertFile(path string, index int, b model.Bullet) error {
+	items, err := s.loadFile(path)
+	if err != nil {
+		return err
+	}
+	return s.saveFile(path, insertBullet(items, index, b))
+}
+
+func (s *FSStore) rewriteFile(path string, fn func(items []model.Bullet) ([]model.Bullet, error)) error {
+	items, err := s.loadFile(path)
+	if err != nil {
+		return err
+	}
+	if items, err = fn(items); err != nil {
+		return err
+	}
+	return s.saveFile(path, items)
+}
+
+func (s *FSStore) updateFile(path string, b model.Bullet) error {
+	items, err := s.loadFile(path)
 	if err != nil {
 		return err
 	}
 	for i := range items {
 		if items[i].ID == b.ID {
-			items[i] = b
+			items[i] = touched(items[i], b)
 			break
 		}
 	}
-	return s.SaveDay(date, items)
+	return s.saveFile(path, items)
 }
 
-// Delete removes a bullet by ID for that day; no-op if not found.
-func (s *FSStore) Delete(date time.Time, id string) error {
-	items, err := s.LoadDay(date)
+// appendData adds data to the end of path, creating it if needed.
+func appendData(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *FSStore) deleteFile(path string, id string) error {
+	items, err := s.loadFile(path)
 	if err != nil {
 		return err
 	}
@@ -169,9 +276,247 @@ func (s *FSStore) Delete(date time.Time, id string) error {
 			filtered = append(filtered, it)
 		}
 	}
-	return s.SaveDay(date, filtered)
+	return s.saveFile(path, filtered)
 }
 
+// LoadRange returns the non-empty days in [start, end]; see Store.
+func (s *FSStore) LoadRange(start, end time.Time) ([]Day, error) {
+	return collectRange(s.Range(start, end))
+}
+
+// Range streams the non-empty days in [start, end], visiting only the
+// YYYY and MM directories that exist and overlap the range.
+func (s *FSStore) Range(start, end time.Time) iter.Seq2[Day, error] {
+	from, to := rangeKeys(start, end)
+	return func(yield func(Day, error) bool) {
+		err := walkMonths(s.root, from[:7], to[:7], func(dir string, y, m int) bool {
+			names, err := readNames(dir)
+			if err != nil {
+				return yield(Day{}, err)
+			}
+			for _, name := range names {
+				dd, ok := strings.CutSuffix(name, ".jsonl")
+				d, err := strconv.Atoi(dd)
+				if !ok || err != nil || len(dd) != 2 || d < 1 || d > 31 {
+					continue
+				}
+				if key := fmt.Sprintf("%04d-%02d-%s", y, m, dd); key < from || key > to {
+					continue
+				}
+				items, err := s.loadFile(filepath.Join(dir, name))
+				if err != nil {
+					return yield(Day{}, err)
+				}
+				if len(items) == 0 {
+					continue
+				}
+				if !yield(Day{Date: time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local), Items: items}, nil) {
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			yield(Day{}, err)
+		}
+	}
+}
+
+// rangeKeys converts range bounds to comparable YYYY-MM-DD keys, opening
+// zero bounds.
+func rangeKeys(start, end time.Time) (string, string) {
+	from, to := "0000-00-00", "9999-99-99"
+	if !start.IsZero() {
+		from = start.Format("2006-01-02")
+	}
+	if !end.IsZero() {
+		to = end.Format("2006-01-02")
+	}
+	return from, to
+}
+
+// walkMonths calls fn for each existing root/YYYY/MM directory whose
+// YYYY-MM key lies in [from, to], in order, until fn returns false.
+func walkMonths(root, from, to string, fn func(dir string, y, m int) bool) error {
+	years, err := readNames(root)
+	if err != nil {
+		return err
+	}
+	for _, yy := range years {
+		y, err := strconv.Atoi(yy)
+		if err != nil || len(yy) != 4 || yy < from[:4] || yy > to[:4] {
+			continue
+		}
+		months, err := readNames(filepath.Join(root, yy))
+		if err != nil {
+			return err
+		}
+		for _, mm := range months {
+			m, err := strconv.Atoi(mm)
+			if err != nil || len(mm) != 2 || m < 1 || m > 12 {
+				continue
+			}
+			if key := yy + "-" + mm; key < from || key > to {
+				continue
+			}
+			if !fn(filepath.Join(root, yy, mm), y, m) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// readNames lists a directory's entry names in sorted order; a missing
+// directory is empty.
+func readNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names, nil
+}
+
+// Days lists every date that has a day file, in chronological order.
+func (s *FSStore) Days() ([]time.Time, error) {
+	var out []time.Time
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if date, ok := s.dayFromPath(path); ok {
+			out = append(out, date)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out, nil
+}
+
+// dayFromPath parses a YYYY/MM/DD.jsonl path under the root back into a date.
+func (s *FSStore) dayFromPath(path string) (time.Time, bool) {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) != 3 || !strings.HasSuffix(parts[2], ".jsonl") {
+		return time.Time{}, false
+	}
+	y, yerr := strconv.Atoi(parts[0])
+	m, merr := strconv.Atoi(parts[1])
+	d, derr := strconv.Atoi(strings.TrimSuffix(parts[2], ".jsonl"))
+	if yerr != nil || merr != nil || derr != nil || len(parts[0]) != 4 || len(parts[1]) != 2 {
+		return time.Time{}, false
+	}
+	if m < 1 || m > 12 || d < 1 || d > 31 {
+		return time.Time{}, false
+	}
+	return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local), true
+}
+
+// FindID locates a bullet by exact ID or unique ID prefix across all days.
+func (s *FSStore) FindID(ref string) (time.Time, model.Bullet, error) {
+	return findID(s.Range(time.Time{}, time.Time{}), ref)
+}
+
+// findID is FindID over the days yielded by days.
+func findID(days iter.Seq2[Day, error], ref string) (time.Time, model.Bullet, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return time.Time{}, model.Bullet{}, ErrNotFound
+	}
+	type match struct {
+		date time.Time
+		item model.Bullet
+	}
+	var matches []match
+	for day, err := range days {
+		if err != nil {
+			return time.Time{}, model.Bullet{}, err
+		}
+		for _, it := range day.Items {
+			if it.ID == ref {
+				return day.Date, it, nil
+			}
+			if strings.HasPrefix(it.ID, ref) {
+				matches = append(matches, match{date: day.Date, item: it})
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return time.Time{}, model.Bullet{}, fmt.Errorf("%w: %q", ErrNotFound, ref)
+	case 1:
+		return matches[0].date, matches[0].item, nil
+	default:
+		return time.Time{}, model.Bullet{}, fmt.Errorf("%w: %q matches %d bullets", ErrAmbiguousID, ref, len(matches))
+	}
+}
+
+// readJSONFile decodes a plain JSON document at path into v. A missing
+// file is not an error and leaves v untouched.
+func readJSONFile(path string, v any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	return json.NewDecoder(f).Decode(v)
+}
+
+// writeJSONFile atomically replaces path with the indented JSON encoding of
+// v, in plain text.
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, "json-*.tmp", append(data, '\n'))
+}
+
+// writeFileAtomic replaces path with data via a temp file named after
+// pattern and a rename.
+func writeFileAtomic(path, pattern string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// NewID returns a fresh bullet ID in the same format the store assigns.
+func NewID() string { return generateID() }
+
 func generateID() string {
 	// 8 random bytes hex-encoded with time prefix for rough ordering.
 	var rb [8]byte
@@ -179,14 +524,9 @@ func generateID() string {
 	return fmt.Sprintf("%d-%s", time.Now().UnixNano(), hex.EncodeToString(rb[:]))
 }
 
-func bytesTrimRightNewline(b []byte) []byte {
-	if len(b) == 0 {
-		return b
-	}
-	if b[len(b)-1] == '\n' || b[len(b)-1] == '\r' {
-		return b[:len(b)-1]
-	}
-	return b
-}
-
-var _ Store = (*FSStore)(nil)
+var (
+	_ Store    = (*FSStore)(nil)
+	_ Finder   = (*FSStore)(nil)
+	_ Inserter = (*FSStore)(nil)
+	_ Updater  = (*FSStore)(nil)
+)
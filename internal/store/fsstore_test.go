@@ -0,0 +1,60 @@
+package store_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rdo34/blt/internal/store"
+	"github.com/rdo34/blt/internal/store/storetest"
+)
+
+func TestFSStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := store.NewFSStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+// TestConcurrentUpdates runs read-modify-write updates of the undo journal
+// and the preferences from several stores on one directory, as separate
+// processes would, and checks that none is lost.
+func TestConcurrentUpdates(t *testing.T) {
+	dir := t.TempDir()
+	store.SetPreferencesDir(dir)
+	t.Cleanup(func() { store.SetPreferencesDir("") })
+	const writers = 8
+	var wg sync.WaitGroup
+	for w := range writers {
+		s, err := store.NewFSStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.UpdateOpLog(func(l *store.OpLog) error {
+				l.Undo = append(l.Undo, store.Op{Label: fmt.Sprintf("op %d", w)})
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			err = store.UpdatePreferences(func(p *store.Preferences) { p.Tags = append(p.Tags, fmt.Sprint(w)) })
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	s, _ := store.NewFSStore(dir)
+	if l, err := s.LoadOpLog(); err != nil || len(l.Undo) != writers {
+		t.Errorf("undo journal holds %d ops (%v), want %d", len(l.Undo), err, writers)
+	}
+	if p, err := store.LoadPreferences(); err != nil || len(p.Tags) != writers {
+		t.Errorf("preferences hold %d tags (%v), want %d", len(p.Tags), err, writers)
+	}
+}
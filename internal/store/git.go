@@ -0,0 +1,453 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// Git integration: a data dir that is the root of a git repository with
+// `blt.autocommit` set in its git config commits after every operation, so
+// the repository holds the journal's history and can be synced to a remote.
+
+var (
+	// ErrNoGit is returned when git history is asked of a data dir that is
+	// not a git repository.
+	ErrNoGit = errors.New("data dir is not a git repository (run `blt git enable`)")
+	// ErrGitDisabled is returned by Sync when auto-commit is not enabled.
+	ErrGitDisabled = errors.New("git integration is not enabled (run `blt git enable`)")
+	// ErrSyncConflict is returned by Sync when local and remote commits
+	// conflict in a way the merge driver cannot resolve; the rebase is
+	// aborted and nothing is pushed.
+	ErrSyncConflict = errors.New("sync conflict: local and remote changes overlap")
+)
+
+// DefaultRemote is the git remote used by Sync when none is given.
+const DefaultRemote = "origin"
+
+// gitIgnored lists machine-local and derived files kept out of the
+// repository: they are rebuilt on each machine and would only conflict.
+var gitIgnored = []string{lockName, "*.tmp", indexDirName + "/", "index.json", "oplog.json", "prefs.json", "journals.json", backupDir + "/"}
+
+// Committer is implemented by stores that record each operation in version
+// control. Commit is a no-op when the integration is off.
+type Committer interface {
+	Commit(message string) error
+}
+
+// Revision is one committed state of a bullet. Bullet is nil from the
+// commit that removed it.
+type Revision struct {
+	Commit  string        `json:"commit"`
+	At      time.Time     `json:"at"`
+	Message string        `json:"message"`
+	File    string        `json:"file"`
+	Bullet  *model.Bullet `json:"bullet,omitempty"`
+}
+
+// SyncResult counts the commits Sync brought in and sent out.
+type SyncResult struct {
+	Pulled int
+	Pushed int
+}
+
+// git runs a git command in the data dir, returning its standard output.
+// Errors carry git's own message.
+func (s *FSStore) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.root
+	var out, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		name := args[0]
+		for i := 0; i+1 < len(args) && args[i] == "-c"; i += 2 {
+			name = args[i+2]
+		}
+		return out.String(), fmt.Errorf("git %s: %s", name, msg)
+	}
+	return out.String(), nil
+}
+
+// isRepo reports whether the data dir is the root of a git repository.
+func (s *FSStore) isRepo() bool {
+	_, err := os.Stat(filepath.Join(s.root, ".git"))
+	return err == nil
+}
+
+// GitEnabled reports whether the store commits its changes to git.
+func (s *FSStore) GitEnabled() bool {
+	if !s.isRepo() {
+		return false
+	}
+	out, err := s.git("config", "--bool", "blt.autocommit")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// EnableGit turns on auto-commit, initializing a repository in the data dir
+// if needed. Derived files are added to .gitignore (and untracked if an
+// earlier manual setup committed them), remote, when set, becomes the
+// DefaultRemote, mergeDriver, when set, is registered as the merge driver
+// for bullet files (see SetMergeDriver), and the current state is committed.
+func (s *FSStore) EnableGit(remote, mergeDriver string) error {
+	return s.locked(func() error {
+		if !s.isRepo() {
+			if top, err := s.git("rev-parse", "--show-toplevel"); err == nil {
+				return fmt.Errorf("data dir is inside the git repository at %s; blt needs its own repository", strings.TrimSpace(top))
+			}
+			if _, err := s.git("init", "-q"); err != nil {
+				return err
+			}
+		}
+		if err := s.ignoreDerived(); err != nil {
+			return err
+		}
+		untrack := append([]string{"rm", "-r", "-q", "--cached", "--ignore-unmatch"}, lockName, indexDirName, "index.json", "oplog.json", "prefs.json", "journals.json", backupDir)
+		if _, err := s.git(untrack...); err != nil {
+			return err
+		}
+		if remote != "" {
+			verb := "add"
+			if _, err := s.git("remote", "get-url", DefaultRemote); err == nil {
+				verb = "set-url"
+			}
+			if _, err := s.git("remote", verb, DefaultRemote, remote); err != nil {
+				return err
+			}
+		}
+		if mergeDriver != "" {
+			if err := s.setMergeDriver(mergeDriver); err != nil {
+				return err
+			}
+		}
+		if _, err := s.git("config", "blt.autocommit", "true"); err != nil {
+			return err
+		}
+		return s.commit("enable git history")
+	})
+}
+
+// setMergeDriver registers command (a git merge driver command line using
+// %O, %A and %B) as the "blt" driver in the repository's config and assigns
+// it to bullet files in .gitattributes.
+func (s *FSStore) setMergeDriver(command string) error {
+	if _, err := s.git("config", "merge.blt.name", "blt per-bullet merge"); err != nil {
+		return err
+	}
+	if _, err := s.git("config", "merge.blt.driver", command); err != nil {
+		return err
+	}
+	path := filepath.Join(s.root, ".gitattributes")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	const attr = "*.jsonl merge=blt"
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == attr {
+			return nil
+		}
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, attr+"\n"...)
+	return writeFileAtomic(path, "git-*.tmp", data)
+}
+
+// DisableGit turns auto-commit off, leaving the repository in place.
+func (s *FSStore) DisableGit() error {
+	if !s.GitEnabled() {
+		return nil
+	}
+	_, err := s.git("config", "--unset", "blt.autocommit")
+	return err
+}
+
+// ignoreDerived appends the gitIgnored patterns missing from .gitignore.
+func (s *FSStore) ignoreDerived() error {
+	path := filepath.Join(s.root, ".gitignore")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	have := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		have[strings.TrimSpace(line)] = true
+	}
+	var add []string
+	for _, p := range gitIgnored {
+		if !have[p] {
+			add = append(add, p)
+		}
+	}
+	if len(add) == 0 {
+		return nil
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, strings.Join(add, "\n")+"\n"...)
+	return writeFileAtomic(path, "git-*.tmp", data)
+}
+
+// Commit stages every change in the data dir and commits it with message
+// when git integration is enabled. Nothing is committed when nothing
+// changed.
+func (s *FSStore) Commit(message string) error {
+	if !s.GitEnabled() {
+		return nil
+	}
+	return s.locked(func() error { return s.commit(message) })
+}
+
+// commit is Commit without the lock or the enabled check. Patterns added
+// to gitIgnored since the repository was enabled are ignored first.
+func (s *FSStore) commit(message string) error {
+	if err := s.ignoreDerived(); err != nil {
+		return err
+	}
+	if _, err := s.git("add", "-A"); err != nil {
+		return err
+	}
+	if _, err := s.git("diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	_, err := s.git(s.identity("commit", "-q", "-m", message)...)
+	return err
+}
+
+// identity prefixes args with a fallback author when git has none
+// configured, so commits work on fresh machines and in scripts.
+func (s *FSStore) identity(args ...string) []string {
+	if out, err := s.git("config", "user.email"); err == nil && strings.TrimSpace(out) != "" {
+		return args
+	}
+	return append([]string{"-c", "user.name=blt", "-c", "user.email=blt@localhost"}, args...)
+}
+
+// Sync commits pending changes, rebases local commits onto the current
+// branch of remote (DefaultRemote when empty) and pushes the result. When
+// the rebase conflicts it is aborted, leaving the local branch as it was,
+// and ErrSyncConflict is returned.
+func (s *FSStore) Sync(remote string) (SyncResult, error) {
+	var res SyncResult
+	if !s.GitEnabled() {
+		return res, ErrGitDisabled
+	}
+	if remote == "" {
+		remote = DefaultRemote
+	}
+	err := s.locked(func() error {
+		if err := s.commit("sync: local changes"); err != nil {
+			return err
+		}
+		out, err := s.git("symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			return err
+		}
+		branch := strings.TrimSpace(out)
+		if _, err := s.git("fetch", "-q", remote); err != nil {
+			return err
+		}
+		upstream := remote + "/" + branch
+		if _, err := s.git("rev-parse", "-q", "--verify", "refs/remotes/"+upstream); err != nil {
+			// The remote does not have the branch yet: push everything.
+			if res.Pushed, err = s.countCommits("HEAD"); err != nil {
+				return err
+			}
+		} else {
+			if res.Pulled, err = s.countCommits("HEAD.." + upstream); err != nil {
+				return err
+			}
+			if res.Pushed, err = s.countCommits(upstream + "..HEAD"); err != nil {
+				return err
+			}
+			if res.Pulled > 0 {
+				if _, err := s.git(s.identity("rebase", "-q", upstream)...); err != nil {
+					files, _ := s.git("diff", "--name-only", "--diff-filter=U")
+					_, _ = s.git("rebase", "--abort")
+					if files = strings.Join(strings.Fields(files), ", "); files == "" {
+						return err
+					}
+					return fmt.Errorf("%w in %s; nothing was pushed", ErrSyncConflict, files)
+				}
+			}
+		}
+		if res.Pushed == 0 {
+			return nil
+		}
+		_, err = s.git("push", "-q", remote, "HEAD:refs/heads/"+branch)
+		return err
+	})
+	if err == nil && res.Pulled > 0 {
+		// Pulled commits rewrote day files behind the search index.
+		err = s.Reindex()
+	}
+	return res, err
+}
+
+func (s *FSStore) countCommits(rng string) (int, error) {
+	out, err := s.git("rev-list", "--count", rng)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+// History returns the committed states of the bullet with the given ID (or
+// unique ID prefix), oldest first, one per commit that changed it. The
+// bullet is looked up in the current files first and, for bullets deleted
+// since, in the history of all bullet files. The files of an encrypted
+// journal are opened with key (nil for a plain one); commits it cannot
+// open, such as those sealed under an earlier passphrase, are skipped.
+func (s *FSStore) History(ref string, key *Key) ([]Revision, error) {
+	if !s.isRepo() {
+		return nil, ErrNoGit
+	}
+	if ref == "" {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, ref)
+	}
+	files, err := s.filesHolding(ref, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		args := []string{"log", "--format=", "--name-only"}
+		if key == nil {
+			// Sealed IDs cannot be searched for, so an encrypted journal
+			// looks through every file.
+			args = append(args, "-S", ref)
+		}
+		out, err := s.git(append(args, "--", "*.jsonl")...)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range strings.Fields(out) {
+			if !slices.Contains(files, f) {
+				files = append(files, f)
+			}
+		}
+	}
+	var revs []Revision
+	id := ""
+	for _, f := range files {
+		fr, fid, err := s.fileHistory(f, ref, key)
+		if err != nil {
+			return nil, err
+		}
+		if fid == "" {
+			continue
+		}
+		if id != "" && fid != id {
+			return nil, fmt.Errorf("%w: %q", ErrAmbiguousID, ref)
+		}
+		id = fid
+		revs = append(revs, fr...)
+	}
+	if id == "" {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, ref)
+	}
+	sort.SliceStable(revs, func(i, j int) bool { return revs[i].At.Before(revs[j].At) })
+	return revs, nil
+}
+
+// filesHolding returns the data-dir relative paths of the current bullet
+// files holding an ID with prefix ref.
+func (s *FSStore) filesHolding(ref string, key *Key) ([]string, error) {
+	var files []string
+	err := s.eachDataFile(func(path string, data []byte) error {
+		if !strings.HasSuffix(path, ".jsonl") {
+			return nil
+		}
+		items, _ := parseBullets(data)
+		items, err := key.openBullets(items)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.rel(path), err)
+		}
+		for _, b := range items {
+			if strings.HasPrefix(b.ID, ref) {
+				files = append(files, filepath.ToSlash(s.rel(path)))
+				break
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// fileHistory walks the commits touching file (following renames) and
+// returns the revisions in which the bullet matching ref changed, with the
+// bullet's full ID. Two different IDs matching ref are ambiguous.
+func (s *FSStore) fileHistory(file, ref string, key *Key) ([]Revision, string, error) {
+	out, err := s.git("log", "--follow", "--name-only", "--format=%x1e%H%x1f%at%x1f%s", "--", file)
+	if err != nil {
+		return nil, "", err
+	}
+	type commit struct {
+		hash, subject, path string
+		at                  time.Time
+	}
+	var commits []commit
+	for _, rec := range strings.Split(out, "\x1e") {
+		head, paths, _ := strings.Cut(rec, "\n")
+		parts := strings.SplitN(head, "\x1f", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		sec, _ := strconv.ParseInt(parts[1], 10, 64)
+		c := commit{hash: parts[0], at: time.Unix(sec, 0), subject: parts[2], path: file}
+		if p := strings.Fields(paths); len(p) > 0 {
+			c.path = p[0]
+		}
+		commits = append(commits, c)
+	}
+	var revs []Revision
+	var prev []byte
+	id := ""
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		var cur *model.Bullet
+		if blob, err := s.git("show", c.hash+":"+c.path); err == nil {
+			items, _ := parseBullets([]byte(blob))
+			if items, err = key.openBullets(items); err != nil {
+				continue
+			}
+			for j := range items {
+				if !strings.HasPrefix(items[j].ID, ref) {
+					continue
+				}
+				if id != "" && items[j].ID != id {
+					return nil, "", fmt.Errorf("%w: %q", ErrAmbiguousID, ref)
+				}
+				id, cur = items[j].ID, &items[j]
+			}
+		}
+		var state []byte
+		if cur != nil {
+			state, _ = json.Marshal(cur)
+		}
+		if bytes.Equal(state, prev) {
+			continue
+		}
+		prev = state
+		revs = append(revs, Revision{Commit: c.hash, At: c.at, Message: c.subject, File: c.path, Bullet: cur})
+	}
+	return revs, id, nil
+}
+
+var _ Committer = (*FSStore)(nil)
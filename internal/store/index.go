@@ -0,0 +1,515 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// Query selects bullets from the daily logs. Empty fields match everything;
+// Text matches bullets containing every word of it as a word prefix, Types
+// and Tags match any of the listed values, and Start/End bound the dates
+// (inclusive) when non-zero.
+type Query struct {
+	Text  string
+	Types []model.BulletType
+	Tags  []string
+	Start time.Time
+	End   time.Time
+}
+
+// Hit is a bullet found by a search, with its owning day.
+type Hit struct {
+	Date   time.Time
+	Bullet model.Bullet
+}
+
+// Searcher is implemented by stores that keep a secondary index of the
+// daily logs for range queries and full-history search.
+type Searcher interface {
+	Search(q Query) ([]Hit, error)
+	DaysBetween(start, end time.Time) ([]time.Time, error)
+	Reindex() error
+}
+
+// indexVersion is bumped whenever the index layout or tokenizer changes;
+// older indexes are rebuilt on first use.
+const indexVersion = 2
+
+// The persistent index is sharded by month: index/YYYY-MM.json summarizes
+// the day files of one month directory, so a write rewrites only its
+// month's shard and a query reads only the shards of the months it covers.
+// Every day file of the month has an entry, empty ones included, so
+// comparing the shard with the month directory's names, sizes and mtimes
+// detects day files added, removed or changed behind the store's back (by a
+// sync tool, a merge or by hand), which are re-indexed when queried.
+const indexDirName = "index"
+
+// indexShard is one month's index file, keyed by YYYY-MM-DD. Shards are
+// never modified once cached, so queries can read them without locks.
+type indexShard struct {
+	Version int                 `json:"version"`
+	Days    map[string]indexDay `json:"days"`
+}
+
+type indexDay struct {
+	Size    int64        `json:"size"`
+	ModTime int64        `json:"mtime"`
+	Items   []indexEntry `json:"items"`
+}
+
+type indexEntry struct {
+	ID     string           `json:"id"`
+	Type   model.BulletType `json:"type"`
+	Tags   []string         `json:"tags,omitempty"`
+	Tokens []string         `json:"tokens,omitempty"`
+}
+
+// indexCache holds the loaded shards and the file mtimes they came from,
+// so changes written by another process are picked up before merging.
+type indexCache struct {
+	mu     sync.Mutex
+	shards map[string]cachedShard // by YYYY-MM
+}
+
+type cachedShard struct {
+	idx   *indexShard
+	mtime time.Time
+}
+
+func (s *FSStore) indexDir() string { return filepath.Join(s.root, indexDirName) }
+
+func (s *FSStore) shardPath(month string) string {
+	return filepath.Join(s.indexDir(), month+".json")
+}
+
+// legacyIndexPath is the single index file written before the index was
+// sharded; it is removed when shards are built.
+func (s *FSStore) legacyIndexPath() string { return filepath.Join(s.root, "index.json") }
+
+// readShard returns month's shard as last read or written by this store,
+// rereading it when it changed on disk. ok is false when the shard is
+// missing or outdated and must be built. Callers hold s.ix.mu.
+func (s *FSStore) readShard(month string) (idx *indexShard, ok bool, err error) {
+	path := s.shardPath(month)
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	if c, ok := s.ix.shards[month]; ok && fi.ModTime().Equal(c.mtime) {
+		return c.idx, true, nil
+	}
+	var shard indexShard
+	if err := readJSONFile(path, &shard); err != nil || shard.Version != indexVersion || shard.Days == nil {
+		return nil, false, nil
+	}
+	s.cacheShard(month, &shard, fi.ModTime())
+	return &shard, true, nil
+}
+
+func (s *FSStore) cacheShard(month string, idx *indexShard, mtime time.Time) {
+	if s.ix.shards == nil {
+		s.ix.shards = map[string]cachedShard{}
+	}
+	s.ix.shards[month] = cachedShard{idx: idx, mtime: mtime}
+}
+
+// saveShard writes month's shard. Callers hold the data directory lock and
+// s.ix.mu.
+func (s *FSStore) saveShard(month string, idx *indexShard) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.shardPath(month), "json-*.tmp", data); err != nil {
+		return err
+	}
+	fi, err := os.Stat(s.shardPath(month))
+	if err != nil {
+		delete(s.ix.shards, month)
+		return nil
+	}
+	s.cacheShard(month, idx, fi.ModTime())
+	return nil
+}
+
+// buildShard indexes every day file of month from scratch. Callers hold the
+// data directory lock and s.ix.mu.
+func (s *FSStore) buildShard(month string) (*indexShard, error) {
+	if err := os.Remove(s.legacyIndexPath()); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	idx := &indexShard{Version: indexVersion, Days: map[string]indexDay{}}
+	names, err := readNames(filepath.Join(s.root, month[:4], month[5:]))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		d, err := time.ParseInLocation("2006-01-02.jsonl", month+"-"+name, time.Local)
+		if err != nil {
+			continue
+		}
+		if err := s.indexDay(idx, d); err != nil {
+			return nil, err
+		}
+	}
+	return idx, s.saveShard(month, idx)
+}
+
+// clone returns a copy of idx that can be modified without affecting
+// readers of idx.
+func (idx *indexShard) clone() *indexShard {
+	out := &indexShard{Version: idx.Version, Days: make(map[string]indexDay, len(idx.Days)+1)}
+	for k, v := range idx.Days {
+		out.Days[k] = v
+	}
+	return out
+}
+
+// indexDay refreshes the entry for date from its day file; a missing file
+// drops the day, and a file without bullets keeps an entry without items.
+func (s *FSStore) indexDay(idx *indexShard, date time.Time) error {
+	key := date.Format("2006-01-02")
+	fi, err := os.Stat(s.dayPath(date))
+	if err != nil {
+		if os.IsNotExist(err) {
+			delete(idx.Days, key)
+			return nil
+		}
+		return err
+	}
+	items, err := s.LoadDay(date)
+	if err != nil {
+		return err
+	}
+	day := indexDay{Size: fi.Size(), ModTime: fi.ModTime().UnixNano(), Items: make([]indexEntry, 0, len(items))}
+	for _, it := range items {
+		day.Items = append(day.Items, entryOf(it))
+	}
+	idx.Days[key] = day
+	return nil
+}
+
+// entryOf indexes b; the envelopes of an encrypted journal are indexed by
+// ID only, which marks their day as holding bullets.
+func entryOf(b model.Bullet) indexEntry {
+	if isEnvelope(b) {
+		return indexEntry{ID: b.ID}
+	}
+	return indexEntry{ID: b.ID, Type: b.Type, Tags: normalizeTags(b.Tags), Tokens: tokenize(b.Text)}
+}
+
+// reindexDay updates date's month shard after its file was written; callers
+// hold the data directory lock. Index failures are not fatal to the write:
+// the JSONL file stays the source of truth, and a stale shard is repaired by
+// queries or `blt reindex`.
+func (s *FSStore) reindexDay(date time.Time) {
+	s.ix.mu.Lock()
+	defer s.ix.mu.Unlock()
+	month := date.Format("2006-01")
+	idx, ok, err := s.readShard(month)
+	if err != nil || !ok {
+		// Built lazily on first query.
+		return
+	}
+	idx = idx.clone()
+	if err := s.indexDay(idx, date); err != nil {
+		return
+	}
+	_ = s.saveShard(month, idx)
+}
+
+// stale lists the days of month whose files were added, removed or changed
+// since idx indexed them.
+func (s *FSStore) stale(month string, idx *indexShard) ([]time.Time, error) {
+	names, err := readNames(filepath.Join(s.root, month[:4], month[5:]))
+	if err != nil {
+		return nil, err
+	}
+	var out []time.Time
+	seen := make(map[string]bool, len(idx.Days))
+	for _, name := range names {
+		d, err := time.ParseInLocation("2006-01-02.jsonl", month+"-"+name, time.Local)
+		if err != nil {
+			continue
+		}
+		key := d.Format("2006-01-02")
+		seen[key] = true
+		fi, err := os.Stat(s.dayPath(d))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		day, ok := idx.Days[key]
+		if err != nil || !ok || fi.Size() != day.Size || fi.ModTime().UnixNano() != day.ModTime {
+			out = append(out, d)
+		}
+	}
+	for key := range idx.Days {
+		if seen[key] {
+			continue
+		}
+		if d, err := time.ParseInLocation("2006-01-02", key, time.Local); err == nil {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// refreshShard builds month's shard or re-indexes its stale days. Callers
+// hold the data directory lock and s.ix.mu.
+func (s *FSStore) refreshShard(month string) (*indexShard, error) {
+	idx, ok, err := s.readShard(month)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return s.buildShard(month)
+	}
+	days, err := s.stale(month, idx)
+	if err != nil || len(days) == 0 {
+		return idx, err
+	}
+	idx = idx.clone()
+	for _, d := range days {
+		if err := s.indexDay(idx, d); err != nil {
+			return nil, err
+		}
+	}
+	return idx, s.saveShard(month, idx)
+}
+
+// shards returns the up-to-date shards of the months with a directory
+// between the YYYY-MM-DD keys from and to. Only a shard that is missing,
+// outdated or stale is refreshed, briefly holding the data directory lock;
+// the shards returned are a snapshot that callers read without locks.
+func (s *FSStore) shards(from, to string) ([]*indexShard, error) {
+	var months []string
+	err := walkMonths(s.root, from[:7], to[:7], func(_ string, y, m int) bool {
+		months = append(months, fmt.Sprintf("%04d-%02d", y, m))
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*indexShard, 0, len(months))
+	for _, month := range months {
+		s.ix.mu.Lock()
+		idx, ok, err := s.readShard(month)
+		s.ix.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			days, err := s.stale(month, idx)
+			if err != nil {
+				return nil, err
+			}
+			ok = len(days) == 0
+		}
+		if !ok {
+			err := s.lockIndex(func() (err error) {
+				idx, err = s.refreshShard(month)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, idx)
+	}
+	return out, nil
+}
+
+// lockIndex runs fn holding the data directory lock and s.ix.mu, so a shard
+// is neither rewritten by another process nor by a concurrent write while
+// fn reads and updates it.
+func (s *FSStore) lockIndex(fn func() error) error {
+	return s.locked(func() error {
+		s.ix.mu.Lock()
+		defer s.ix.mu.Unlock()
+		return fn()
+	})
+}
+
+// dropIndex removes the index, to be rebuilt on next use. Callers hold the
+// data directory lock.
+func (s *FSStore) dropIndex() error {
+	s.ix.mu.Lock()
+	defer s.ix.mu.Unlock()
+	s.ix.shards = nil
+	if err := os.RemoveAll(s.indexDir()); err != nil {
+		return err
+	}
+	if err := os.Remove(s.legacyIndexPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Reindex rebuilds the index from the day files.
+func (s *FSStore) Reindex() error {
+	return s.locked(func() error {
+		if err := s.dropIndex(); err != nil {
+			return err
+		}
+		s.ix.mu.Lock()
+		defer s.ix.mu.Unlock()
+		var err error
+		werr := walkMonths(s.root, "0000-00", "9999-99", func(_ string, y, m int) bool {
+			_, err = s.buildShard(fmt.Sprintf("%04d-%02d", y, m))
+			return err == nil
+		})
+		if werr != nil {
+			return werr
+		}
+		return err
+	})
+}
+
+// DaysBetween lists the days in [start, end] that hold bullets.
+func (s *FSStore) DaysBetween(start, end time.Time) ([]time.Time, error) {
+	from, to := rangeKeys(start, end)
+	shards, err := s.shards(from, to)
+	if err != nil {
+		return nil, err
+	}
+	var out []time.Time
+	for _, idx := range shards {
+		for _, key := range sortedKeys(idx) {
+			if key < from || key > to || len(idx.Days[key].Items) == 0 {
+				continue
+			}
+			if d, err := time.ParseInLocation("2006-01-02", key, time.Local); err == nil {
+				out = append(out, d)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Search returns the bullets matching q in date and file order. Candidate
+// days come from a snapshot of the index and are read without holding the
+// data directory lock, so a search never blocks writers; bullets are matched
+// again as read, in case their day changed since the snapshot.
+func (s *FSStore) Search(q Query) ([]Hit, error) {
+	from, to := rangeKeys(q.Start, q.End)
+	shards, err := s.shards(from, to)
+	if err != nil {
+		return nil, err
+	}
+	match := q.matcher()
+	var hits []Hit
+	for _, idx := range shards {
+		for _, key := range sortedKeys(idx) {
+			if key < from || key > to || !slices.ContainsFunc(idx.Days[key].Items, match) {
+				continue
+			}
+			d, _ := time.ParseInLocation("2006-01-02", key, time.Local)
+			items, err := s.LoadDay(d)
+			if err != nil {
+				return nil, err
+			}
+			for _, it := range items {
+				if match(entryOf(it)) {
+					hits = append(hits, Hit{Date: d, Bullet: it})
+				}
+			}
+		}
+	}
+	return hits, nil
+}
+
+// matcher returns the test q applies to index entries, ignoring dates.
+func (q Query) matcher() func(indexEntry) bool {
+	words := tokenize(q.Text)
+	types := map[model.BulletType]bool{}
+	for _, t := range q.Types {
+		types[t] = true
+	}
+	tags := map[string]bool{}
+	for _, t := range normalizeTags(q.Tags) {
+		tags[t] = true
+	}
+	return func(e indexEntry) bool { return e.matches(words, types, tags) }
+}
+
+func (e indexEntry) matches(words []string, types map[model.BulletType]bool, tags map[string]bool) bool {
+	if len(types) > 0 && !types[e.Type] {
+		return false
+	}
+	if len(tags) > 0 {
+		ok := false
+		for _, t := range e.Tags {
+			if tags[t] {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, w := range words {
+		ok := false
+		for _, t := range e.Tokens {
+			if strings.HasPrefix(t, w) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(idx *indexShard) []string {
+	keys := make([]string, 0, len(idx.Days))
+	for k := range idx.Days {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// tokenize splits text into distinct lower-case words.
+func tokenize(text string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// normalizeTags lower-cases tags and strips a leading '#'.
+func normalizeTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		t = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(t)), "#")
+		if t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+var _ Searcher = (*FSStore)(nil)
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"Write report", []string{"write", "report"}},
+		{"write, WRITE; write!", []string{"write"}},
+		{"call @bob re: Q3-plan", []string{"call", "bob", "re", "q3", "plan"}},
+		{"#tag e-mail", []string{"tag", "e", "mail"}},
+		{"café über 2026", []string{"café", "über", "2026"}},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s, err := NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.Local) }
+	add := func(d time.Time, typ model.BulletType, text string, tags ...string) {
+		if err := s.Append(d, model.Bullet{Type: typ, Text: text, Tags: tags}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(day(1, 5), model.Task, "Write quarterly report", "#work")
+	add(day(1, 5), model.Note, "report looked fine")
+	add(day(2, 10), model.Done, "Call the plumber", "home")
+	add(day(3, 1), model.Event, "Report review meeting", "work", "meeting")
+	add(day(3, 1), model.Task, "buy milk")
+
+	tests := []struct {
+		name string
+		q    Query
+		want string
+	}{
+		{"everything", Query{}, "Write quarterly report|report looked fine|Call the plumber|Report review meeting|buy milk"},
+		{"word", Query{Text: "report"}, "Write quarterly report|report looked fine|Report review meeting"},
+		{"word prefix", Query{Text: "rep"}, "Write quarterly report|report looked fine|Report review meeting"},
+		{"all words", Query{Text: "report review"}, "Report review meeting"},
+		{"no prefix inside words", Query{Text: "port"}, ""},
+		{"type", Query{Types: []model.BulletType{model.Task}}, "Write quarterly report|buy milk"},
+		{"tag without hash", Query{Tags: []string{"#Work"}}, "Write quarterly report|Report review meeting"},
+		{"text and tag", Query{Text: "report", Tags: []string{"meeting"}}, "Report review meeting"},
+		{"date range", Query{Start: day(2, 1), End: day(3, 1)}, "Call the plumber|Report review meeting|buy milk"},
+		{"open start", Query{Text: "report", End: day(2, 28)}, "Write quarterly report|report looked fine"},
+		{"no match", Query{Text: "zebra"}, ""},
+	}
+	for _, tt := range tests {
+		hits, err := s.Search(tt.q)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, h := range hits {
+			got = append(got, h.Bullet.Text)
+		}
+		if g := strings.Join(got, "|"); g != tt.want {
+			t.Errorf("%s: Search = %q, want %q", tt.name, g, tt.want)
+		}
+	}
+}
+
+func TestSearchSeesChanges(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFSStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jan, feb := time.Date(2026, 1, 5, 0, 0, 0, 0, time.Local), time.Date(2026, 2, 5, 0, 0, 0, 0, time.Local)
+	for _, d := range []time.Time{jan, feb} {
+		if err := s.Append(d, model.Bullet{Type: model.Task, Text: "alpha"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func(text string) int {
+		t.Helper()
+		hits, err := s.Search(Query{Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(hits)
+	}
+	if n := count("alpha"); n != 2 {
+		t.Fatalf("found %d alphas, want 2", n)
+	}
+
+	// A write updates only its month's shard.
+	janShard, err := os.ReadFile(s.shardPath("2026-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(feb, model.Bullet{Type: model.Task, Text: "beta"}); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(s.shardPath("2026-01")); string(again) != string(janShard) {
+		t.Error("appending in February rewrote January's shard")
+	}
+	if n := count("beta"); n != 1 {
+		t.Errorf("found %d betas after append, want 1", n)
+	}
+
+	// Day files edited behind the store's back are re-indexed, also by
+	// another store on the same directory.
+	if err := os.WriteFile(s.dayPath(jan), []byte(`{"id":"x","type":"task","text":"gamma"}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewFSStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits, err := other.Search(Query{Text: "gamma"}); err != nil || len(hits) != 1 {
+		t.Errorf("other store found %d gammas (%v), want 1", len(hits), err)
+	}
+	if n := count("alpha"); n != 1 {
+		t.Errorf("found %d alphas after editing January, want 1", n)
+	}
+
+	// Day files created or removed outside the store, e.g. by a sync tool,
+	// are found without a reindex.
+	mar := time.Date(2026, 3, 7, 0, 0, 0, 0, time.Local)
+	for _, d := range []time.Time{feb.AddDate(0, 0, 1), mar} {
+		if err := os.MkdirAll(filepath.Dir(s.dayPath(d)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(s.dayPath(d), []byte(`{"id":"`+d.Format("0102")+`","type":"task","text":"delta"}`+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := count("delta"); n != 2 {
+		t.Errorf("found %d deltas in files written by hand, want 2", n)
+	}
+	if days, err := s.DaysBetween(feb, mar); err != nil || len(days) != 3 {
+		t.Errorf("DaysBetween = %v, %v; want 3 days", days, err)
+	}
+	if err := os.Remove(s.dayPath(mar)); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("delta"); n != 1 {
+		t.Errorf("found %d deltas after removing a day file, want 1", n)
+	}
+
+	// Rebuilding from scratch gives the same answers.
+	if err := s.Reindex(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("alpha") + count("beta") + count("gamma"); n != 3 {
+		t.Errorf("found %d bullets after reindex, want 3", n)
+	}
+}
+
+func TestSearchDoesNotWaitForTheLock(t *testing.T) {
+	s, err := NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := time.Date(2026, 1, 5, 0, 0, 0, 0, time.Local)
+	if err := s.Append(d, model.Bullet{Type: model.Task, Text: "alpha"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Search(Query{}); err != nil { // builds the index
+		t.Fatal(err)
+	}
+	defer func(old time.Duration) { lockTimeout = old }(lockTimeout)
+	lockTimeout = 100 * time.Millisecond
+	err = s.locked(func() error {
+		hits, err := s.Search(Query{Text: "alpha"})
+		if err == nil && len(hits) != 1 {
+			t.Errorf("found %d hits while locked, want 1", len(hits))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("search while another writer holds the lock: %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Journals are named data directories listed in journals.json in the
+// config directory, so one machine can keep e.g. a work and a personal
+// journal apart. The default journal is the data directory from
+// ResolveDataDir and is always available.
+
+// DefaultJournal names the journal in the default data directory.
+const DefaultJournal = "default"
+
+// ErrUnknownJournal is returned for journal names not in the registry.
+var ErrUnknownJournal = errors.New("unknown journal")
+
+// Journal is a named data directory.
+type Journal struct {
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+}
+
+// journalRegistry is the contents of journals.json.
+type journalRegistry struct {
+	Default  string    `json:"default,omitempty"` // journal opened without --journal
+	Journals []Journal `json:"journals"`
+}
+
+var journalName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ResolveConfigDir returns the directory holding settings shared by all
+// journals. Order: BLT_CONFIG_DIR env override, then the OS config
+// directory.
+func ResolveConfigDir() (string, error) {
+	if custom := os.Getenv("BLT_CONFIG_DIR"); custom != "" {
+		return custom, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "blt"), nil
+}
+
+func registryPath() (string, error) {
+	dir, err := ResolveConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "journals.json"), nil
+}
+
+func loadRegistry() (journalRegistry, error) {
+	var r journalRegistry
+	path, err := registryPath()
+	if err != nil {
+		return r, err
+	}
+	return r, readJSONFile(path, &r)
+}
+
+// updateRegistry applies fn to the registry under the config directory's
+// lock and saves it.
+func updateRegistry(fn func(r *journalRegistry) error) error {
+	path, err := registryPath()
+	if err != nil {
+		return err
+	}
+	return withLock(filepath.Dir(path), func() error {
+		var r journalRegistry
+		if err := readJSONFile(path, &r); err != nil {
+			return err
+		}
+		if err := fn(&r); err != nil {
+			return err
+		}
+		return writeJSONFile(path, r)
+	})
+}
+
+// Journals lists the default journal followed by the registered ones in
+// name order.
+func Journals() ([]Journal, error) {
+	dir, err := ResolveDataDir()
+	if err != nil {
+		return nil, err
+	}
+	r, err := loadRegistry()
+	if err != nil {
+		return nil, err
+	}
+	out := append([]Journal{{Name: DefaultJournal, Dir: dir}}, r.Journals...)
+	sort.SliceStable(out[1:], func(i, j int) bool { return out[1+i].Name < out[1+j].Name })
+	return out, nil
+}
+
+// FindJournal returns the journal called name.
+func FindJournal(name string) (Journal, error) {
+	all, err := Journals()
+	if err != nil {
+		return Journal{}, err
+	}
+	for _, j := range all {
+		if j.Name == name {
+			return j, nil
+		}
+	}
+	return Journal{}, fmt.Errorf("%w: %q (see `blt journal list`)", ErrUnknownJournal, name)
+}
+
+// ResolveJournal returns the journal called name or, when name is empty,
+// the one to open by default: the data directory from BLT_DATA_DIR when
+// set, else the registry's default journal.
+func ResolveJournal(name string) (Journal, error) {
+	if name == "" {
+		if os.Getenv("BLT_DATA_DIR") != "" {
+			dir, err := ResolveDataDir()
+			return Journal{Name: DefaultJournal, Dir: dir}, err
+		}
+		name = DefaultJournalName()
+	}
+	return FindJournal(name)
+}
+
+// DefaultJournalName returns the name of the journal opened without
+// --journal.
+func DefaultJournalName() string {
+	if r, err := loadRegistry(); err == nil && r.Default != "" {
+		return r.Default
+	}
+	return DefaultJournal
+}
+
+// AddJournal registers dir as the journal called name. The directory is
+// created when the journal is first opened. It must not be, contain or lie
+// within another journal's directory, the default one included, since each
+// journal locks, indexes and backs up its whole directory.
+func AddJournal(name, dir string) (Journal, error) {
+	if !journalName.MatchString(name) {
+		return Journal{}, fmt.Errorf("invalid journal name %q: use letters, digits, - and _", name)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return Journal{}, err
+	}
+	def, err := ResolveDataDir()
+	if err != nil {
+		return Journal{}, err
+	}
+	if def, err = filepath.Abs(def); err != nil {
+		return Journal{}, err
+	}
+	j := Journal{Name: name, Dir: abs}
+	err = updateRegistry(func(r *journalRegistry) error {
+		if name == DefaultJournal {
+			return fmt.Errorf("%q is the default data directory's journal", name)
+		}
+		for _, prev := range r.Journals {
+			if prev.Name == name {
+				return fmt.Errorf("journal %q already exists (%s)", name, prev.Dir)
+			}
+		}
+		for _, prev := range append([]Journal{{Name: DefaultJournal, Dir: def}}, r.Journals...) {
+			if overlaps(abs, prev.Dir) {
+				return fmt.Errorf("%s overlaps the directory of journal %q (%s)", abs, prev.Name, prev.Dir)
+			}
+		}
+		r.Journals = append(r.Journals, j)
+		return nil
+	})
+	return j, err
+}
+
+// overlaps reports whether directories a and b are the same or one lies
+// within the other.
+func overlaps(a, b string) bool {
+	within := func(dir, parent string) bool {
+		rel, err := filepath.Rel(parent, dir)
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+	return within(a, b) || within(b, a)
+}
+
+// RemoveJournal unregisters the journal called name, leaving its data
+// directory in place.
+func RemoveJournal(name string) (Journal, error) {
+	var gone Journal
+	err := updateRegistry(func(r *journalRegistry) error {
+		for i, j := range r.Journals {
+			if j.Name == name {
+				gone = j
+				r.Journals = append(r.Journals[:i], r.Journals[i+1:]...)
+				if r.Default == name {
+					r.Default = ""
+				}
+				return nil
+			}
+		}
+		if name == DefaultJournal {
+			return fmt.Errorf("the %q journal cannot be removed", name)
+		}
+		return fmt.Errorf("%w: %q", ErrUnknownJournal, name)
+	})
+	return gone, err
+}
+
+// SetDefaultJournal makes the journal called name the one opened without
+// --journal.
+func SetDefaultJournal(name string) error {
+	if _, err := FindJournal(name); err != nil {
+		return err
+	}
+	return updateRegistry(func(r *journalRegistry) error {
+		r.Default = name
+		if name == DefaultJournal {
+			r.Default = ""
+		}
+		return nil
+	})
+}
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddJournalRejectsOverlappingDirs(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("BLT_CONFIG_DIR", filepath.Join(root, "config"))
+	t.Setenv("BLT_DATA_DIR", filepath.Join(root, "default"))
+	if _, err := AddJournal("work", filepath.Join(root, "work")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, dir string
+		ok        bool
+	}{
+		{"same", "work", false},
+		{"same, unclean", "work/../work/", false},
+		{"nested", "work/sub", false},
+		{"parent", "", false},
+		{"in default", "default/sub", false},
+		{"default itself", "default", false},
+		{"sibling with shared prefix", "workshop", true},
+		{"sibling", "home", true},
+	}
+	for i, tt := range tests {
+		_, err := AddJournal("j"+string(rune('a'+i)), filepath.Join(root, tt.dir))
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: AddJournal = %v, want ok %v", tt.name, err, tt.ok)
+		}
+		if err != nil && !strings.Contains(err.Error(), "overlaps") {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked is returned when another process holds the data directory lock
+// for longer than the lock timeout.
+var ErrLocked = errors.New("data directory is locked by another process")
+
+// lockTimeout bounds how long a writer waits for the data directory lock.
+var lockTimeout = 5 * time.Second
+
+// lockName is the advisory lock file created in each locked directory.
+const lockName = ".lock"
+
+// withLock runs fn while holding an exclusive advisory lock on dir, so
+// read-modify-write cycles from the TUI, the CLI and other processes do not
+// interleave. The lock is not re-entrant: fn must not take it again.
+func withLock(dir string, fn func() error) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, lockName)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	deadline := time.Now().Add(lockTimeout)
+	wait := time.Millisecond
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			return fmt.Errorf("lock %s: %w", path, err)
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: %s held for more than %s", ErrLocked, path, lockTimeout)
+		}
+		time.Sleep(wait)
+		wait = min(wait*2, 50*time.Millisecond)
+	}
+	defer unlock(f)
+	return fn()
+}
+
+// locked runs fn under the data directory lock.
+func (s *FSStore) locked(fn func() error) error {
+	return withLock(s.root, fn)
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"fmt"
+	"iter"
+	"path/filepath"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// LogStore is implemented by stores that keep the Bullet Journal's monthly
+// and future logs alongside the daily log. Each log is exposed as a Store:
+// the monthly log keys files by month (any date within it), while the future
+// log is a single list and ignores dates entirely.
+type LogStore interface {
+	MonthLog() Store
+	FutureLog() Store
+}
+
+// logStore adapts a path mapping to the Store interface. Monthly logs set
+// root and keep one file per month under it; other logs are a single
+// undated file. Writes take the lock of the owning store s.
+type logStore struct {
+	s    *FSStore
+	path func(date time.Time) string
+	root string
+}
+
+func (l logStore) LoadDay(date time.Time) ([]model.Bullet, error) {
+	return l.s.loadFile(l.path(date))
+}
+
+func (l logStore) SaveDay(date time.Time, items []model.Bullet) error {
+	return l.s.locked(func() error { return l.s.saveFile(l.path(date), items) })
+}
+
+func (l logStore) Append(date time.Time, b model.Bullet) error {
+	return l.s.locked(func() error { return l.s.appendFile(l.path(date), b) })
+}
+
+func (l logStore) InsertAt(date time.Time, index int, b model.Bullet) error {
+	return l.s.locked(func() error { return l.s.insertFile(l.path(date), index, b) })
+}
+
+func (l logStore) Update(date time.Time, b model.Bullet) error {
+	return l.s.locked(func() error { return l.s.updateFile(l.path(date), b) })
+}
+
+func (l logStore) UpdateDay(date time.Time, fn func(items []model.Bullet) ([]model.Bullet, error)) error {
+	return l.s.locked(func() error { return l.s.rewriteFile(l.path(date), fn) })
+}
+
+func (l logStore) Delete(date time.Time, id string) error {
+	return l.s.locked(func() error { return l.s.deleteFile(l.path(date), id) })
+}
+
+// LoadRange returns the log's non-empty files overlapping [start, end]; see
+// Range.
+func (l logStore) LoadRange(start, end time.Time) ([]Day, error) {
+	return collectRange(l.Range(start, end))
+}
+
+// Range yields one Day per monthly log in the range, dated the first of the
+// month. Undated logs yield their single file (with a zero date) for any
+// range.
+func (l logStore) Range(start, end time.Time) iter.Seq2[Day, error] {
+	return func(yield func(Day, error) bool) {
+		if l.root == "" {
+			items, err := l.s.loadFile(l.path(time.Time{}))
+			if err != nil || len(items) > 0 {
+				yield(Day{Items: items}, err)
+			}
+			return
+		}
+		from, to := rangeKeys(start, end)
+		err := walkMonths(l.root, from[:7], to[:7], func(dir string, y, m int) bool {
+			items, err := l.s.loadFile(filepath.Join(dir, "month.jsonl"))
+			if err != nil {
+				return yield(Day{}, err)
+			}
+			if len(items) == 0 {
+				return true
+			}
+			return yield(Day{Date: time.Date(y, time.Month(m), 1, 0, 0, 0, 0, time.Local), Items: items}, nil)
+		})
+		if err != nil {
+			yield(Day{}, err)
+		}
+	}
+}
+
+// MonthLog returns the monthly logs, stored as YYYY/MM/month.jsonl.
+func (s *FSStore) MonthLog() Store {
+	return logStore{s: s, root: s.root, path: func(date time.Time) string {
+		y, m, _ := date.Date()
+		return filepath.Join(s.root, fmt.Sprintf("%04d", y), fmt.Sprintf("%02d", m), "month.jsonl")
+	}}
+}
+
+// FutureLog returns the future log, stored as future.jsonl.
+func (s *FSStore) FutureLog() Store {
+	return logStore{s: s, path: func(time.Time) string {
+		return filepath.Join(s.root, "future.jsonl")
+	}}
+}
+
+// TrashStore is implemented by stores that keep deleted bullets in a trash
+// instead of dropping them. The trash is a single undated list like the
+// future log; each bullet's Deleted field records its origin.
+type TrashStore interface {
+	Trash() Store
+}
+
+// Trash returns the trash, stored as trash.jsonl.
+func (s *FSStore) Trash() Store {
+	return logStore{s: s, path: func(time.Time) string {
+		return filepath.Join(s.root, "trash.jsonl")
+	}}
+}
+
+var (
+	_ Inserter   = logStore{}
+	_ Updater    = logStore{}
+	_ LogStore   = (*FSStore)(nil)
+	_ TrashStore = (*FSStore)(nil)
+)
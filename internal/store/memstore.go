@@ -0,0 +1,228 @@
+package store
+
+import (
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// MemStore implements Store in memory, for tests and for embedding BLT
+// without a data directory. It is safe for concurrent use and mirrors
+// FSStore: IDs and CreatedAt are filled in on write, updates and deletes of
+// unknown IDs are no-ops, and bullets are copied in and out so callers never
+// share state with the store.
+type MemStore struct {
+	mu   sync.RWMutex
+	days map[string][]model.Bullet // keyed by YYYY-MM-DD
+}
+
+// NewMemStore returns an empty in-memory store.
+func NewMemStore() *MemStore {
+	return &MemStore{days: map[string][]model.Bullet{}}
+}
+
+func dayKey(date time.Time) string {
+	y, m, d := date.Date()
+	return fmt.Sprintf("%04d-%02d-%02d", y, m, d)
+}
+
+// LoadDay returns a copy of the bullets stored for date.
+func (s *MemStore) LoadDay(date time.Time) ([]model.Bullet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return cloneBullets(s.days[dayKey(date)]), nil
+}
+
+// SaveDay replaces all bullets for date.
+func (s *MemStore) SaveDay(date time.Time, items []model.Bullet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(dayKey(date), cloneBullets(items))
+	return nil
+}
+
+// Append adds a bullet at the end of date's list.
+func (s *MemStore) Append(date time.Time, b model.Bullet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := dayKey(date)
+	s.put(key, append(s.days[key], cloneBullet(b)))
+	return nil
+}
+
+// InsertAt inserts b at index in date's list; see Inserter.
+func (s *MemStore) InsertAt(date time.Time, index int, b model.Bullet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := dayKey(date)
+	s.put(key, insertBullet(cloneBullets(s.days[key]), index, cloneBullet(b)))
+	return nil
+}
+
+// Update replaces the bullet with b's ID on date; no-op if not found.
+func (s *MemStore) Update(date time.Time, b model.Bullet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := s.days[dayKey(date)]
+	for i := range items {
+		if items[i].ID == b.ID {
+			items[i] = cloneBullet(touched(items[i], b))
+			break
+		}
+	}
+	return nil
+}
+
+// UpdateDay replaces date's list with fn's result; see Updater.
+func (s *MemStore) UpdateDay(date time.Time, fn func(items []model.Bullet) ([]model.Bullet, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := dayKey(date)
+	items, err := fn(cloneBullets(s.days[key]))
+	if err != nil {
+		return err
+	}
+	s.put(key, cloneBullets(items))
+	return nil
+}
+
+// Delete removes the bullet with id from date; no-op if not found.
+func (s *MemStore) Delete(date time.Time, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := dayKey(date)
+	items := s.days[key]
+	filtered := make([]model.Bullet, 0, len(items))
+	for _, it := range items {
+		if it.ID != id {
+			filtered = append(filtered, it)
+		}
+	}
+	s.put(key, filtered)
+	return nil
+}
+
+// put stores items under key, filling in IDs and creation times. Callers
+// hold s.mu.
+func (s *MemStore) put(key string, items []model.Bullet) {
+	if len(items) == 0 {
+		delete(s.days, key)
+		return
+	}
+	for i := range items {
+		if items[i].ID == "" {
+			items[i].ID = generateID()
+		}
+		if items[i].CreatedAt.IsZero() {
+			items[i].CreatedAt = time.Now()
+		}
+	}
+	s.days[key] = items
+}
+
+// LoadRange returns the non-empty days in [start, end]; see Store.
+func (s *MemStore) LoadRange(start, end time.Time) ([]Day, error) {
+	return collectRange(s.Range(start, end))
+}
+
+// Range streams the non-empty days in [start, end]. The days are
+// snapshotted when iteration starts.
+func (s *MemStore) Range(start, end time.Time) iter.Seq2[Day, error] {
+	return func(yield func(Day, error) bool) {
+		from, to := rangeKeys(start, end)
+		s.mu.RLock()
+		var days []Day
+		for key, items := range s.days {
+			if key < from || key > to {
+				continue
+			}
+			d, err := time.ParseInLocation("2006-01-02", key, time.Local)
+			if err != nil {
+				continue
+			}
+			days = append(days, Day{Date: d, Items: cloneBullets(items)})
+		}
+		s.mu.RUnlock()
+		sort.Slice(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+		for _, d := range days {
+			if !yield(d, nil) {
+				return
+			}
+		}
+	}
+}
+
+// FindID locates a bullet by exact ID or unique ID prefix across all days.
+func (s *MemStore) FindID(ref string) (time.Time, model.Bullet, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return time.Time{}, model.Bullet{}, ErrNotFound
+	}
+	var matches []Day
+	for day := range s.Range(time.Time{}, time.Time{}) {
+		for _, it := range day.Items {
+			if it.ID == ref {
+				return day.Date, it, nil
+			}
+			if strings.HasPrefix(it.ID, ref) {
+				matches = append(matches, Day{Date: day.Date, Items: []model.Bullet{it}})
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return time.Time{}, model.Bullet{}, fmt.Errorf("%w: %q", ErrNotFound, ref)
+	case 1:
+		return matches[0].Date, matches[0].Items[0], nil
+	default:
+		return time.Time{}, model.Bullet{}, fmt.Errorf("%w: %q matches %d bullets", ErrAmbiguousID, ref, len(matches))
+	}
+}
+
+func cloneBullets(items []model.Bullet) []model.Bullet {
+	out := make([]model.Bullet, len(items))
+	for i, b := range items {
+		out[i] = cloneBullet(b)
+	}
+	return out
+}
+
+// cloneBullet copies b's slices and pointers so the copy shares nothing.
+func cloneBullet(b model.Bullet) model.Bullet {
+	if b.Tags != nil {
+		b.Tags = append([]string(nil), b.Tags...)
+	}
+	if b.Revisions != nil {
+		b.Revisions = append([]model.Revision(nil), b.Revisions...)
+		for i := range b.Revisions {
+			b.Revisions[i].Tags = append([]string(nil), b.Revisions[i].Tags...)
+		}
+	}
+	if b.Deleted != nil {
+		del := *b.Deleted
+		if del.Date != nil {
+			d := *del.Date
+			del.Date = &d
+		}
+		b.Deleted = &del
+	}
+	for _, p := range []**time.Time{&b.ScheduledFor, &b.CompletedAt, &b.UpdatedAt, &b.OriginDate} {
+		if *p != nil {
+			t := **p
+			*p = &t
+		}
+	}
+	return b
+}
+
+var (
+	_ Store    = (*MemStore)(nil)
+	_ Finder   = (*MemStore)(nil)
+	_ Inserter = (*MemStore)(nil)
+	_ Updater  = (*MemStore)(nil)
+)
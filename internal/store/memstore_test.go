@@ -0,0 +1,12 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/rdo34/blt/internal/store"
+	"github.com/rdo34/blt/internal/store/storetest"
+)
+
+func TestMemStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store { return store.NewMemStore() })
+}
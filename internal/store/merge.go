@@ -0,0 +1,257 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// Bullet files are merged per bullet ID rather than per line: a bullet
+// changed on one side only takes that side's version, one changed on both
+// sides keeps the later modification, and additions from both sides are
+// kept in place.
+
+// ConflictCopy is a conflicting copy of a bullet file left by a file
+// synchronizer, with both paths relative to the data directory.
+type ConflictCopy struct {
+	Path     string `json:"path"`
+	Original string `json:"original"`
+}
+
+// conflictNames match the copies Syncthing ("17.sync-conflict-20240301-
+// 101500-ABCDEFG.jsonl") and Dropbox ("17 (Sam's conflicted copy
+// 2024-03-01).jsonl") write next to a file edited in two places. The
+// first group and the extension rebuild the original name.
+var conflictNames = []*regexp.Regexp{
+	regexp.MustCompile(`^(.+)\.sync-conflict-\d{8}-\d{6}-[A-Z0-9]{7}(\.jsonl)$`),
+	regexp.MustCompile(`^(.+) \([^()]*conflicted copy[^()]*\)(\.jsonl)$`),
+}
+
+// conflictOriginal returns the name of the bullet file name is a conflict
+// copy of.
+func conflictOriginal(name string) (string, bool) {
+	for _, re := range conflictNames {
+		if m := re.FindStringSubmatch(name); m != nil {
+			return m[1] + m[2], true
+		}
+	}
+	return "", false
+}
+
+// MergeBullets merges two versions of a bullet file by bullet ID against
+// their common ancestor base, which is nil when unknown (a bullet missing
+// from one side then counts as added on the other rather than deleted). It
+// returns the merged bullets in ours' order, with bullets only theirs has
+// placed after their predecessor in theirs, and the number of bullets both
+// sides changed differently.
+func MergeBullets(base, ours, theirs []model.Bullet) ([]model.Bullet, int) {
+	byID := func(items []model.Bullet) map[string]*model.Bullet {
+		m := make(map[string]*model.Bullet, len(items))
+		for i := range items {
+			m[items[i].ID] = &items[i]
+		}
+		return m
+	}
+	b, o, t := byID(base), byID(ours), byID(theirs)
+	both := 0
+	pick := func(id string) *model.Bullet {
+		v, overlap := mergeBullet(b[id], o[id], t[id])
+		if overlap {
+			both++
+		}
+		return v
+	}
+
+	out := make([]model.Bullet, 0, len(ours)+len(theirs))
+	seen := make(map[string]bool, len(ours)+len(theirs))
+	for _, it := range ours {
+		seen[it.ID] = true
+		if v := pick(it.ID); v != nil {
+			out = append(out, *v)
+		}
+	}
+	at := 0 // insertion point after the last of theirs' bullets placed
+	for _, it := range theirs {
+		if seen[it.ID] {
+			if i := indexOfID(out, it.ID); i >= 0 {
+				at = i + 1
+			}
+			continue
+		}
+		seen[it.ID] = true
+		if v := pick(it.ID); v != nil {
+			out = append(out[:at], append([]model.Bullet{*v}, out[at:]...)...)
+			at++
+		}
+	}
+	return out, both
+}
+
+// mergeBullet picks the merged state of one bullet from its base, ours and
+// theirs versions (nil when absent), reporting whether both sides changed
+// it. An edit wins over a deletion on the other side.
+func mergeBullet(base, ours, theirs *model.Bullet) (*model.Bullet, bool) {
+	switch {
+	case ours == nil && theirs == nil:
+		return nil, false
+	case ours == nil:
+		if base != nil && sameBullet(base, theirs) {
+			return nil, false
+		}
+		return theirs, false
+	case theirs == nil:
+		if base != nil && sameBullet(base, ours) {
+			return nil, false
+		}
+		return ours, false
+	case sameBullet(ours, theirs):
+		return ours, false
+	case base != nil && sameBullet(base, ours):
+		return theirs, false
+	case base != nil && sameBullet(base, theirs):
+		return ours, false
+	}
+	if theirs.Modified().After(ours.Modified()) {
+		return theirs, true
+	}
+	return ours, true
+}
+
+func sameBullet(a, b *model.Bullet) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func indexOfID(items []model.Bullet, id string) int {
+	for i := range items {
+		if items[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// MergeFiles merges the bullet files ours and theirs against base (empty
+// for none) and writes the result to out, which may be ours. Lines that do
+// not parse are carried over from both sides, unless one side removed a
+// line base had. The files of an encrypted journal are opened with key and
+// the result sealed again; key is nil for a plain one. It takes no lock: it
+// runs as a git merge driver while Sync holds it.
+func (s *FSStore) MergeFiles(base, ours, theirs, out string, key *Key) (int, error) {
+	var parsed [3][]model.Bullet
+	var bad [3][][]byte
+	for i, path := range []string{base, ours, theirs} {
+		if path == "" {
+			continue
+		}
+		data, err := readData(path)
+		if err != nil {
+			return 0, err
+		}
+		parsed[i], bad[i] = parseBullets(data)
+		if parsed[i], err = key.openBullets(parsed[i]); err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if base == "" {
+		parsed[0] = nil
+	}
+	items, both := MergeBullets(parsed[0], parsed[1], parsed[2])
+	items = key.sealBullets(items)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range items {
+		if err := enc.Encode(&items[i]); err != nil {
+			return 0, err
+		}
+	}
+	// A bad line base had is dropped only when one side removed it.
+	in := [3]map[string]bool{}
+	for i, lines := range bad {
+		in[i] = map[string]bool{}
+		for _, line := range lines {
+			in[i][string(line)] = true
+		}
+	}
+	kept := map[string]bool{}
+	for _, lines := range bad[1:] {
+		for _, line := range lines {
+			l := string(line)
+			if kept[l] || in[0][l] && !(in[1][l] && in[2][l]) {
+				continue
+			}
+			kept[l] = true
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+	return both, writeFileAtomic(out, "day-*.tmp", buf.Bytes())
+}
+
+// ConflictCopies lists the conflicting copies of bullet files that file
+// synchronizers left in the data directory.
+func (s *FSStore) ConflictCopies() ([]ConflictCopy, error) {
+	var out []ConflictCopy
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != s.root && (s.rel(path) == backupDir || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if orig, ok := conflictOriginal(d.Name()); ok {
+			out = append(out, ConflictCopy{Path: s.rel(path), Original: s.rel(filepath.Join(filepath.Dir(path), orig))})
+		}
+		return nil
+	})
+	return out, err
+}
+
+// ResolveConflicts merges every conflict copy into its original file and
+// removes the copy. Without a common ancestor, bullets deleted on one side
+// come back from the other. key opens and seals the files of an encrypted
+// journal, as for MergeFiles.
+func (s *FSStore) ResolveConflicts(key *Key) ([]ConflictCopy, error) {
+	var done []ConflictCopy
+	err := s.locked(func() error {
+		copies, err := s.ConflictCopies()
+		if err != nil {
+			return err
+		}
+		for _, c := range copies {
+			if err := s.resolveConflict(c, key); err != nil {
+				return err
+			}
+			done = append(done, c)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// resolveConflict merges one conflict copy into its original under the lock.
+func (s *FSStore) resolveConflict(c ConflictCopy, key *Key) error {
+	orig, cp := filepath.Join(s.root, c.Original), filepath.Join(s.root, c.Path)
+	if _, err := s.MergeFiles("", orig, cp, orig, key); err != nil {
+		return err
+	}
+	if err := os.Remove(cp); err != nil {
+		return err
+	}
+	if date, ok := s.dayFromPath(orig); ok {
+		s.reindexDay(date)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// bulletList parses a space-separated list of "id:text" bullets, each
+// optionally followed by "@n" to mark it modified n hours after creation.
+// "-" is the empty list and "nil" no list at all.
+func bulletList(t *testing.T, spec string) []model.Bullet {
+	t.Helper()
+	if spec == "nil" {
+		return nil
+	}
+	created := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	items := []model.Bullet{}
+	for _, f := range strings.Fields(spec) {
+		if f == "-" {
+			continue
+		}
+		f, hours, stamped := strings.Cut(f, "@")
+		id, text, _ := strings.Cut(f, ":")
+		b := model.Bullet{ID: id, Type: model.Task, Text: text, CreatedAt: created}
+		if stamped {
+			n, err := strconv.Atoi(hours)
+			if err != nil {
+				t.Fatalf("bad spec %q", spec)
+			}
+			at := created.Add(time.Duration(n) * time.Hour)
+			b.UpdatedAt = &at
+		}
+		items = append(items, b)
+	}
+	return items
+}
+
+func TestMergeBullets(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		both               int
+	}{
+		{"unchanged", "a:x b:y", "a:x b:y", "a:x b:y", "a:x b:y", 0},
+		{"ours edited", "a:x b:y", "a:x2@1 b:y", "a:x b:y", "a:x2 b:y", 0},
+		{"theirs edited", "a:x b:y", "a:x b:y", "a:x b:y2@1", "a:x b:y2", 0},
+		{"each edited another", "a:x b:y", "a:x2@1 b:y", "a:x b:y2@1", "a:x2 b:y2", 0},
+		{"same edit on both", "a:x", "a:x2@1", "a:x2@1", "a:x2", 0},
+		{"both edited, theirs later", "a:x", "a:ours@1", "a:theirs@2", "a:theirs", 1},
+		{"both edited, ours later", "a:x", "a:ours@3", "a:theirs@2", "a:ours", 1},
+		{"ours deleted", "a:x b:y", "b:y", "a:x b:y", "b:y", 0},
+		{"theirs deleted", "a:x b:y", "a:x b:y", "a:x", "a:x", 0},
+		{"both deleted", "a:x b:y", "b:y", "b:y", "b:y", 0},
+		{"edit beats deletion", "a:x b:y", "b:y", "a:x2@1 b:y", "a:x2 b:y", 0},
+		{"deletion loses to edit", "a:x b:y", "a:x2@1 b:y", "b:y", "a:x2 b:y", 0},
+		{"both added", "a:x b:y", "a:x o:new b:y", "a:x b:y t:new", "a:x o:new b:y t:new", 0},
+		{"theirs added first", "a:x", "a:x", "t:new a:x", "t:new a:x", 0},
+		{"theirs added after its predecessor", "a:x b:y", "a:x o:new b:y", "a:x t:new b:y", "a:x t:new o:new b:y", 0},
+		{"theirs added in a run", "a:x", "a:x o:new", "a:x t1:new t2:new", "a:x t1:new t2:new o:new", 0},
+		{"ours' order kept", "a:x b:y", "b:y a:x", "a:x b:y", "b:y a:x", 0},
+		{"no base keeps one-sided bullets", "nil", "a:x b:y", "a:x c:z", "a:x c:z b:y", 0},
+		{"no base, both changed", "nil", "a:ours@1", "a:theirs@2", "a:theirs", 1},
+		{"empty sides", "a:x", "-", "-", "", 0},
+	}
+	for _, tt := range tests {
+		got, both := MergeBullets(bulletList(t, tt.base), bulletList(t, tt.ours), bulletList(t, tt.theirs))
+		var ids []string
+		for _, b := range got {
+			ids = append(ids, b.ID+":"+b.Text)
+		}
+		if g := strings.Join(ids, " "); g != tt.want || both != tt.both {
+			t.Errorf("%s: MergeBullets = %q, %d; want %q, %d", tt.name, g, both, tt.want, tt.both)
+		}
+	}
+}
+
+func TestMergeFilesUnparseableLines(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+	}{
+		{"unchanged on all sides", "x", "x", "x", "x"},
+		{"added by ours", "", "x", "", "x"},
+		{"added by theirs", "", "", "x", "x"},
+		{"added by both", "", "x", "x", "x"},
+		{"removed by ours", "x", "", "x", ""},
+		{"removed by theirs", "x", "x", "", ""},
+		{"one kept, one removed", "x y", "x y", "y", "y"},
+	}
+	dir := t.TempDir()
+	write := func(name, bad string) string {
+		t.Helper()
+		var data []byte
+		for _, f := range strings.Fields(bad) {
+			data = append(data, "{bad "+f+"\n"...)
+		}
+		data = append(data, `{"id":"a","type":"task","text":"ok"}`+"\n"...)
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	s := &FSStore{root: dir}
+	for _, tt := range tests {
+		base, ours, theirs := write("base", tt.base), write("ours", tt.ours), write("theirs", tt.theirs)
+		if _, err := s.MergeFiles(base, ours, theirs, ours, nil); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(ours)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items, bad := parseBullets(data)
+		var got []string
+		for _, line := range bad {
+			got = append(got, strings.TrimPrefix(string(line), "{bad "))
+		}
+		if g := strings.Join(got, " "); g != tt.want || len(items) != 1 {
+			t.Errorf("%s: kept %q and %d bullets, want %q and 1", tt.name, g, len(items), tt.want)
+		}
+	}
+}
+
+func TestConflictOriginal(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"17.sync-conflict-20240301-101500-ABCDEFG.jsonl", "17.jsonl"},
+		{"future.sync-conflict-20240301-101500-ABCDEFG.jsonl", "future.jsonl"},
+		{"17 (Sam's conflicted copy 2024-03-01).jsonl", "17.jsonl"},
+		{"17.jsonl", ""},
+		{"17.sync-conflict-20240301-101500-ABCDEFG.json", ""},
+		{"17 (copy).jsonl", ""},
+	}
+	for _, tt := range tests {
+		got, ok := conflictOriginal(tt.name)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("conflictOriginal(%q) = %q, %v; want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// Change records one bullet's state on a day before and after a mutation.
+// Before is nil for insertions and After is nil for deletions; Index is the
+// bullet's position within the day so deletions can be restored in place.
+// Log names the collection for changes outside the daily log.
+type Change struct {
+	Log    string        `json:"log,omitempty"`
+	Date   time.Time     `json:"date"`
+	Index  int           `json:"index"`
+	Before *model.Bullet `json:"before,omitempty"`
+	After  *model.Bullet `json:"after,omitempty"`
+}
+
+// Op is one user-visible mutation (e.g. "complete: write report") made of
+// the changes needed to apply or revert it.
+type Op struct {
+	Label   string    `json:"label"`
+	At      time.Time `json:"at"`
+	Changes []Change  `json:"changes"`
+}
+
+// OpLog holds the undo and redo stacks, most recent last. In an encrypted
+// journal both are sealed into Sealed; see EncryptedStore.
+type OpLog struct {
+	Undo   []Op   `json:"undo"`
+	Redo   []Op   `json:"redo"`
+	Sealed string `json:"sealed,omitempty"`
+}
+
+// OpLogger is implemented by stores that persist the undo/redo journal.
+// UpdateOpLog applies fn to the journal as one step that concurrent writers
+// do not interleave with, so no process drops another's operations.
+type OpLogger interface {
+	LoadOpLog() (OpLog, error)
+	SaveOpLog(l OpLog) error
+	UpdateOpLog(fn func(l *OpLog) error) error
+}
+
+func (s *FSStore) opLogPath() string { return filepath.Join(s.root, "oplog.json") }
+
+// LoadOpLog reads the undo/redo journal; a missing file yields an empty log.
+func (s *FSStore) LoadOpLog() (OpLog, error) {
+	var l OpLog
+	err := readJSONFile(s.opLogPath(), &l)
+	return l, err
+}
+
+// SaveOpLog atomically replaces the undo/redo journal.
+func (s *FSStore) SaveOpLog(l OpLog) error {
+	return s.locked(func() error { return writeJSONFile(s.opLogPath(), &l) })
+}
+
+// UpdateOpLog holds the lock from reading the journal until fn's changes
+// are written.
+func (s *FSStore) UpdateOpLog(fn func(l *OpLog) error) error {
+	return s.locked(func() error {
+		var l OpLog
+		if err := readJSONFile(s.opLogPath(), &l); err != nil {
+			return err
+		}
+		if err := fn(&l); err != nil {
+			return err
+		}
+		return writeJSONFile(s.opLogPath(), &l)
+	})
+}
+
+var _ OpLogger = (*FSStore)(nil)
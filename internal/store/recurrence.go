@@ -0,0 +1,64 @@
+package store
+
+import (
+	"path/filepath"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// RecurrenceStore is implemented by stores that persist recurrence rules.
+// UpdateRecurrences replaces the rules with those fn returns for the current
+// ones and appends the bullets fn returns to their days, as one step that
+// concurrent writers do not interleave with, so two processes never generate
+// the same instance.
+type RecurrenceStore interface {
+	LoadRecurrences() ([]model.Recurrence, error)
+	SaveRecurrences(rs []model.Recurrence) error
+	UpdateRecurrences(fn func(rs []model.Recurrence) ([]model.Recurrence, []Day, error)) error
+}
+
+func (s *FSStore) recurrencesPath() string { return filepath.Join(s.root, "recurrences.json") }
+
+// LoadRecurrences reads all recurrence rules; a missing file yields none.
+func (s *FSStore) LoadRecurrences() ([]model.Recurrence, error) {
+	var rs []model.Recurrence
+	err := readJSONFile(s.recurrencesPath(), &rs)
+	return rs, err
+}
+
+// SaveRecurrences atomically replaces the recurrence rules.
+func (s *FSStore) SaveRecurrences(rs []model.Recurrence) error {
+	if rs == nil {
+		rs = []model.Recurrence{}
+	}
+	return s.locked(func() error { return writeJSONFile(s.recurrencesPath(), rs) })
+}
+
+// UpdateRecurrences holds the lock from reading the rules until the rules
+// fn returns and its bullets are written.
+func (s *FSStore) UpdateRecurrences(fn func(rs []model.Recurrence) ([]model.Recurrence, []Day, error)) error {
+	return s.locked(func() error {
+		var rs []model.Recurrence
+		if err := readJSONFile(s.recurrencesPath(), &rs); err != nil {
+			return err
+		}
+		rs, add, err := fn(rs)
+		if err != nil {
+			return err
+		}
+		for _, d := range add {
+			for _, b := range d.Items {
+				if err := s.appendFile(s.dayPath(d.Date), b); err != nil {
+					return err
+				}
+			}
+			s.reindexDay(d.Date)
+		}
+		if rs == nil {
+			rs = []model.Recurrence{}
+		}
+		return writeJSONFile(s.recurrencesPath(), rs)
+	})
+}
+
+var _ RecurrenceStore = (*FSStore)(nil)
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+)
+
+// SchemaVersion is the on-disk layout this build reads and writes. Bump it
+// together with a new entry in upgrades whenever stored data changes shape.
+const SchemaVersion = 2
+
+// ErrSchemaTooNew is returned when the data directory was written by a
+// newer BLT than this one.
+var ErrSchemaTooNew = errors.New("data directory uses a newer schema")
+
+// manifest is manifest.json at the data directory root. It versions every
+// file under the root, including prefs.json when it lives there.
+type manifest struct {
+	SchemaVersion int `json:"schema_version"`
+}
+
+// upgrade converts a data directory from version From to From+1. Apply
+// returns one line per change; with dryRun it only reports them.
+type upgrade struct {
+	From        int
+	Description string
+	Apply       func(s *FSStore, dryRun bool) ([]string, error)
+}
+
+// upgrades is the registry of schema steps, in order. Version 1 is any data
+// directory written before the manifest existed.
+var upgrades = []upgrade{
+	{From: 1, Description: "link legacy migrated and scheduled bullets to their copies", Apply: linkLegacyCopies},
+}
+
+// UpgradeStep reports what one schema step changed (or would change).
+type UpgradeStep struct {
+	From        int
+	To          int
+	Description string
+	Changes     []string
+}
+
+// UpgradeReport describes a schema upgrade. Backup is the archive taken
+// before upgrading, relative to the data directory.
+type UpgradeReport struct {
+	From   int
+	To     int
+	Backup string
+	Steps  []UpgradeStep
+}
+
+func (s *FSStore) manifestPath() string { return filepath.Join(s.root, "manifest.json") }
+
+// SchemaVersionOnDisk returns the data directory's schema version: the
+// manifest's, 1 for data written before manifests existed, or SchemaVersion
+// for an empty directory.
+func (s *FSStore) SchemaVersionOnDisk() (int, error) {
+	var m manifest
+	if err := readJSONFile(s.manifestPath(), &m); err != nil {
+		return 0, err
+	}
+	if m.SchemaVersion > 0 {
+		return m.SchemaVersion, nil
+	}
+	names, err := readNames(s.root)
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range names {
+		if name != lockName {
+			return 1, nil
+		}
+	}
+	return SchemaVersion, nil
+}
+
+// UpgradeSchema brings the data directory up to SchemaVersion, backing it up
+// to backups/ first and recording the new version in manifest.json after
+// each step. With dryRun nothing is written; each step then previews its
+// changes against the current data, so later steps may report less than
+// they would do after earlier ones ran.
+func (s *FSStore) UpgradeSchema(dryRun bool) (UpgradeReport, error) {
+	var rep UpgradeReport
+	run := func() error {
+		from, err := s.SchemaVersionOnDisk()
+		if err != nil {
+			return err
+		}
+		rep.From, rep.To = from, SchemaVersion
+		if from > SchemaVersion {
+			return fmt.Errorf("%w: version %d, this build supports up to %d; upgrade blt", ErrSchemaTooNew, from, SchemaVersion)
+		}
+		if from == SchemaVersion {
+			if _, err := os.Stat(s.manifestPath()); os.IsNotExist(err) && !dryRun {
+				return writeJSONFile(s.manifestPath(), manifest{SchemaVersion: SchemaVersion})
+			}
+			return nil
+		}
+		if !dryRun {
+			name := fmt.Sprintf("schema-v%d-%s", from, time.Now().Format("20060102-150405"))
+			path, err := s.writeBackup(name)
+			if err != nil {
+				return fmt.Errorf("backup before upgrade: %w", err)
+			}
+			rep.Backup = s.rel(path)
+		}
+		for _, u := range upgrades {
+			if u.From < from {
+				continue
+			}
+			changes, err := u.Apply(s, dryRun)
+			if err != nil {
+				return fmt.Errorf("upgrade to version %d: %w", u.From+1, err)
+			}
+			rep.Steps = append(rep.Steps, UpgradeStep{From: u.From, To: u.From + 1, Description: u.Description, Changes: changes})
+			if dryRun {
+				continue
+			}
+			if err := writeJSONFile(s.manifestPath(), manifest{SchemaVersion: u.From + 1}); err != nil {
+				return err
+			}
+		}
+		if !dryRun {
+			// Steps rewrite files directly; rebuild the index on next use.
+			if err := s.dropIndex(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if dryRun {
+		return rep, run()
+	}
+	return rep, s.locked(run)
+}
+
+// linkLegacyCopies (1 → 2) fills in the origin/clone links that migrations
+// and schedules have recorded since they were introduced. A Migrated or
+// Scheduled bullet without a clone ID is linked to the first unlinked bullet
+// with the same text on its target day: the scheduled date, or the next day.
+func linkLegacyCopies(s *FSStore, dryRun bool) ([]string, error) {
+	days, err := s.LoadRange(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	at := map[string]int{}
+	for i, d := range days {
+		at[d.Date.Format("2006-01-02")] = i
+	}
+	changed := map[int]bool{}
+	var changes []string
+	for i := range days {
+		d := days[i].Date
+		for k := range days[i].Items {
+			it := &days[i].Items[k]
+			if (it.Type != model.Migrated && it.Type != model.Scheduled) || it.CloneID != "" {
+				continue
+			}
+			target := d.AddDate(0, 0, 1)
+			if it.ScheduledFor != nil {
+				y, m, dd := it.ScheduledFor.Date()
+				target = time.Date(y, m, dd, 0, 0, 0, 0, time.Local)
+			}
+			j, ok := at[target.Format("2006-01-02")]
+			if !ok {
+				continue
+			}
+			for n := range days[j].Items {
+				c := &days[j].Items[n]
+				if c.OriginID != "" || c.ID == it.ID || !strings.EqualFold(c.Text, it.Text) {
+					continue
+				}
+				origin := d
+				it.CloneID = c.ID
+				c.OriginID, c.OriginDate = it.ID, &origin
+				changed[i], changed[j] = true, true
+				changes = append(changes, fmt.Sprintf("%s: %q → %s", s.rel(s.dayPath(d)), it.Text, target.Format("2006-01-02")))
+				break
+			}
+		}
+	}
+	if dryRun {
+		return changes, nil
+	}
+	for i := range changed {
+		if err := s.saveFile(s.dayPath(days[i].Date), days[i].Items); err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
+}
@@ -1,16 +1,94 @@
 package store
 
 import (
+	"errors"
+	"iter"
+	"slices"
 	"time"
 
 	"github.com/rdo34/blt/internal/model"
 )
 
+var (
+	// ErrNotFound is returned when no bullet matches an ID reference.
+	ErrNotFound = errors.New("no bullet matches id")
+	// ErrAmbiguousID is returned when an ID prefix matches more than one bullet.
+	ErrAmbiguousID = errors.New("id prefix is ambiguous")
+)
+
 // Store defines persistence operations for bullets.
 type Store interface {
 	LoadDay(date time.Time) ([]model.Bullet, error)
 	SaveDay(date time.Time, items []model.Bullet) error
 	Append(date time.Time, b model.Bullet) error
+	// Update replaces the bullet with b's ID. Unless the caller already
+	// moved UpdatedAt on, a changed bullet is stamped with model.Bullet.Touch.
 	Update(date time.Time, b model.Bullet) error
 	Delete(date time.Time, id string) error
+
+	// LoadRange returns the days in [start, end] that hold bullets, in
+	// chronological order. A zero start or end leaves that side open.
+	LoadRange(start, end time.Time) ([]Day, error)
+	// Range streams the same days as LoadRange, reading each only when the
+	// caller asks for it; stopping early skips the rest.
+	Range(start, end time.Time) iter.Seq2[Day, error]
+}
+
+// Day is one day's bullets as returned by range queries.
+type Day struct {
+	Date  time.Time
+	Items []model.Bullet
+}
+
+// touched returns b as the replacement of old for Update, stamping it with
+// Touch when it changed and the caller left UpdatedAt as it was.
+func touched(old, b model.Bullet) model.Bullet {
+	same := old.UpdatedAt == nil && b.UpdatedAt == nil ||
+		old.UpdatedAt != nil && b.UpdatedAt != nil && old.UpdatedAt.Equal(*b.UpdatedAt)
+	if same && !sameBullet(&old, &b) {
+		b.Touch(old, time.Now())
+	}
+	return b
+}
+
+// collectRange gathers a range iterator into a slice for LoadRange.
+func collectRange(seq iter.Seq2[Day, error]) ([]Day, error) {
+	var out []Day
+	for d, err := range seq {
+		if err != nil {
+			return out, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// Inserter is implemented by stores that can insert a bullet at a position
+// in a day's list as one step, so concurrent writers cannot interleave
+// between reading the day and writing it back. An index out of range
+// appends.
+type Inserter interface {
+	InsertAt(date time.Time, index int, b model.Bullet) error
+}
+
+// insertBullet returns items with b inserted at index, or appended when
+// index is out of range.
+func insertBullet(items []model.Bullet, index int, b model.Bullet) []model.Bullet {
+	if index < 0 || index > len(items) {
+		index = len(items)
+	}
+	return slices.Insert(items, index, b)
+}
+
+// Updater is implemented by stores that can rewrite a day's list as one
+// step that concurrent writers do not interleave with: fn gets the stored
+// bullets and returns those to store in their place.
+type Updater interface {
+	UpdateDay(date time.Time, fn func(items []model.Bullet) ([]model.Bullet, error)) error
+}
+
+// Finder is implemented by stores that can locate a bullet by its ID
+// (or a unique ID prefix) without knowing the owning day.
+type Finder interface {
+	FindID(ref string) (time.Time, model.Bullet, error)
 }
@@ -0,0 +1,355 @@
+// Package storetest holds a conformance suite for store.Store
+// implementations. Call Run from a test in the implementation's package:
+//
+//	func TestFSStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store {
+//			s, err := store.NewFSStore(t.TempDir())
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return s
+//		})
+//	}
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rdo34/blt/internal/model"
+	"github.com/rdo34/blt/internal/store"
+)
+
+// Run exercises the behaviour every Store must share. newStore is called once
+// per subtest and must return an empty store.
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, store.Store)
+	}{
+		{"EmptyDay", testEmptyDay},
+		{"AppendAssignsIDAndCreatedAt", testAppendDefaults},
+		{"AppendKeepsIDAndCreatedAt", testAppendKeeps},
+		{"AppendOrder", testAppendOrder},
+		{"SaveDayReplaces", testSaveDay},
+		{"InsertAt", testInsertAt},
+		{"UpdateDay", testUpdateDay},
+		{"Update", testUpdate},
+		{"UpdateStampsModification", testUpdateStamps},
+		{"UpdateMissingIsNoop", testUpdateMissing},
+		{"Delete", testDelete},
+		{"DeleteMissingIsNoop", testDeleteMissing},
+		{"DaysAreIndependent", testDaysIndependent},
+		{"TimeOfDayIgnored", testTimeOfDay},
+		{"LoadRange", testLoadRange},
+		{"RangeStopsEarly", testRangeStop},
+		{"ConcurrentAppends", testConcurrentAppends},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func task(text string) model.Bullet {
+	return model.Bullet{Type: model.Task, Text: text}
+}
+
+func load(t *testing.T, s store.Store, d time.Time) []model.Bullet {
+	t.Helper()
+	items, err := s.LoadDay(d)
+	if err != nil {
+		t.Fatalf("LoadDay(%s): %v", d.Format("2006-01-02"), err)
+	}
+	return items
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func texts(items []model.Bullet) []string {
+	out := make([]string, len(items))
+	for i, it := range items {
+		out[i] = it.Text
+	}
+	return out
+}
+
+func wantTexts(t *testing.T, items []model.Bullet, want ...string) {
+	t.Helper()
+	if got := texts(items); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("texts = %q, want %q", got, want)
+	}
+}
+
+func testEmptyDay(t *testing.T, s store.Store) {
+	if items := load(t, s, day(2024, 3, 1)); len(items) != 0 {
+		t.Fatalf("empty day has %d items", len(items))
+	}
+}
+
+func testAppendDefaults(t *testing.T, s store.Store) {
+	d := day(2024, 3, 1)
+	must(t, s.Append(d, task("a")))
+	items := load(t, s, d)
+	wantTexts(t, items, "a")
+	if items[0].ID == "" {
+		t.Error("Append did not assign an ID")
+	}
+	if items[0].CreatedAt.IsZero() {
+		t.Error("Append did not set CreatedAt")
+	}
+}
+
+func testAppendKeeps(t *testing.T, s store.Store) {
+	d := day(2024, 3, 1)
+	created := time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC)
+	b := task("a")
+	b.ID, b.CreatedAt = "fixed-id", created
+	must(t, s.Append(d, b))
+	items := load(t, s, d)
+	if items[0].ID != "fixed-id" {
+		t.Errorf("ID = %q, want fixed-id", items[0].ID)
+	}
+	if !items[0].CreatedAt.Equal(created) {
+		t.Errorf("CreatedAt = %v, want %v", items[0].CreatedAt, created)
+	}
+}
+
+func testAppendOrder(t *testing.T, s store.Store) {
+	d := day(2024, 3, 1)
+	for _, text := range []string{"a", "b", "c"} {
+		must(t, s.Append(d, task(text)))
+	}
+	wantTexts(t, load(t, s, d), "a", "b", "c")
+}
+
+func testSaveDay(t *testing.T, s store.Store) {
+	d := day(2024, 3, 1)
+	must(t, s.Append(d, task("old")))
+	must(t, s.SaveDay(d, []model.Bullet{task("x"), task("y")}))
+	items := load(t, s, d)
+	wantTexts(t, items, "x", "y")
+	if items[0].ID == "" || items[1].ID == "" || items[0].ID == items[1].ID {
+		t.Errorf("SaveDay IDs = %q, %q; want distinct non-empty", items[0].ID, items[1].ID)
+	}
+	must(t, s.SaveDay(d, nil))
+	if items := load(t, s, d); len(items) != 0 {
+		t.Errorf("day has %d items after saving none", len(items))
+	}
+}
+
+// testInsertAt checks stores implementing store.Inserter; others skip it.
+func testInsertAt(t *testing.T, s store.Store) {
+	ins, ok := s.(store.Inserter)
+	if !ok {
+		t.Skip("store does not implement Inserter")
+	}
+	d := day(2024, 3, 1)
+	must(t, ins.InsertAt(d, 0, task("b")))
+	must(t, ins.InsertAt(d, 0, task("a")))
+	must(t, ins.InsertAt(d, 2, task("d")))
+	must(t, ins.InsertAt(d, 2, task("c")))
+	must(t, ins.InsertAt(d, 9, task("e")))
+	must(t, ins.InsertAt(d, -1, task("f")))
+	items := load(t, s, d)
+	wantTexts(t, items, "a", "b", "c", "d", "e", "f")
+	if items[0].ID == "" || items[0].CreatedAt.IsZero() {
+		t.Errorf("inserted bullet has ID %q, CreatedAt %v", items[0].ID, items[0].CreatedAt)
+	}
+}
+
+func testUpdateDay(t *testing.T, s store.Store) {
+	u, ok := s.(store.Updater)
+	if !ok {
+		t.Skip("store does not implement Updater")
+	}
+	d := day(2024, 3, 1)
+	must(t, s.Append(d, task("a")))
+	must(t, s.Append(d, task("b")))
+	must(t, u.UpdateDay(d, func(items []model.Bullet) ([]model.Bullet, error) {
+		return append([]model.Bullet{items[1], items[0]}, task("c")), nil
+	}))
+	items := load(t, s, d)
+	wantTexts(t, items, "b", "a", "c")
+	if items[2].ID == "" || items[2].CreatedAt.IsZero() {
+		t.Errorf("added bullet has ID %q, CreatedAt %v", items[2].ID, items[2].CreatedAt)
+	}
+	failed := errors.New("failed")
+	err := u.UpdateDay(d, func([]model.Bullet) ([]model.Bullet, error) { return nil, failed })
+	if !errors.Is(err, failed) {
+		t.Errorf("UpdateDay returned %v, want fn's error", err)
+	}
+	wantTexts(t, load(t, s, d), "b", "a", "c")
+}
+
+func testUpdate(t *testing.T, s store.Store) {
+	d := day(2024, 3, 1)
+	must(t, s.Append(d, task("a")))
+	must(t, s.Append(d, task("b")))
+	b := load(t, s, d)[0]
+	b.Text, b.Type = "a2", model.Done
+	must(t, s.Update(d, b))
+	items := load(t, s, d)
+	wantTexts(t, items, "a2", "b")
+	if items[0].Type != model.Done {
+		t.Errorf("Type = %q, want done", items[0].Type)
+	}
+}
+
+func testUpdateStamps(t *testing.T, s store.Store) {
+	d := day(2024, 3, 1)
+	must(t, s.Append(d, task("a")))
+	b := load(t, s, d)[0]
+	b.Text = "a2"
+	must(t, s.Update(d, b))
+	got := load(t, s, d)[0]
+	if got.UpdatedAt == nil {
+		t.Fatal("Update did not set UpdatedAt")
+	}
+	if len(got.Revisions) != 1 || got.Revisions[0].Text != "a" {
+		t.Fatalf("Revisions = %+v, want the earlier text", got.Revisions)
+	}
+
+	// A caller that stamped the bullet itself is taken as is.
+	at := time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)
+	got.Text, got.UpdatedAt = "a3", &at
+	must(t, s.Update(d, got))
+	got = load(t, s, d)[0]
+	if got.UpdatedAt == nil || !got.UpdatedAt.Equal(at) || len(got.Revisions) != 1 {
+		t.Fatalf("caller's stamp replaced: UpdatedAt %v, %d revisions", got.UpdatedAt, len(got.Revisions))
+	}
+}
+
+func testUpdateMissing(t *testing.T, s store.Store) {
+	d := day(2024, 3, 1)
+	must(t, s.Append(d, task("a")))
+	b := task("ghost")
+	b.ID = "missing"
+	must(t, s.Update(d, b))
+	must(t, s.Update(day(2024, 3, 2), b))
+	wantTexts(t, load(t, s, d), "a")
+	if items := load(t, s, day(2024, 3, 2)); len(items) != 0 {
+		t.Errorf("Update of a missing ID added %d items", len(items))
+	}
+}
+
+func testDelete(t *testing.T, s store.Store) {
+	d := day(2024, 3, 1)
+	for _, text := range []string{"a", "b", "c"} {
+		must(t, s.Append(d, task(text)))
+	}
+	must(t, s.Delete(d, load(t, s, d)[1].ID))
+	wantTexts(t, load(t, s, d), "a", "c")
+}
+
+func testDeleteMissing(t *testing.T, s store.Store) {
+	d := day(2024, 3, 1)
+	must(t, s.Append(d, task("a")))
+	must(t, s.Delete(d, "missing"))
+	must(t, s.Delete(day(2024, 3, 2), "missing"))
+	wantTexts(t, load(t, s, d), "a")
+}
+
+func testDaysIndependent(t *testing.T, s store.Store) {
+	must(t, s.Append(day(2024, 3, 1), task("march")))
+	must(t, s.Append(day(2024, 4, 1), task("april")))
+	must(t, s.Append(day(2025, 3, 1), task("next year")))
+	wantTexts(t, load(t, s, day(2024, 3, 1)), "march")
+	wantTexts(t, load(t, s, day(2024, 4, 1)), "april")
+	wantTexts(t, load(t, s, day(2025, 3, 1)), "next year")
+}
+
+func testTimeOfDay(t *testing.T, s store.Store) {
+	morning := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	evening := time.Date(2024, 3, 1, 22, 15, 0, 0, time.Local)
+	must(t, s.Append(morning, task("a")))
+	wantTexts(t, load(t, s, evening), "a")
+}
+
+func testLoadRange(t *testing.T, s store.Store) {
+	for _, d := range []time.Time{day(2024, 3, 5), day(2024, 1, 31), day(2024, 2, 10), day(2023, 12, 31)} {
+		must(t, s.Append(d, task(d.Format("2006-01-02"))))
+	}
+	must(t, s.SaveDay(day(2024, 2, 1), nil)) // empty days are skipped
+
+	check := func(start, end time.Time, want ...string) {
+		t.Helper()
+		days, err := s.LoadRange(start, end)
+		must(t, err)
+		var got []string
+		for _, d := range days {
+			got = append(got, d.Date.Format("2006-01-02"))
+			if len(d.Items) != 1 || d.Items[0].Text != d.Date.Format("2006-01-02") {
+				t.Errorf("day %s has items %q", d.Date.Format("2006-01-02"), texts(d.Items))
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("LoadRange(%s, %s) = %q, want %q",
+				start.Format("2006-01-02"), end.Format("2006-01-02"), got, want)
+		}
+	}
+	check(time.Time{}, time.Time{}, "2023-12-31", "2024-01-31", "2024-02-10", "2024-03-05")
+	check(day(2024, 1, 31), day(2024, 2, 10), "2024-01-31", "2024-02-10")
+	check(day(2024, 2, 1), time.Time{}, "2024-02-10", "2024-03-05")
+	check(time.Time{}, day(2024, 1, 30), "2023-12-31")
+	check(day(2024, 2, 11), day(2024, 3, 4))
+}
+
+func testRangeStop(t *testing.T, s store.Store) {
+	for d := 1; d <= 5; d++ {
+		must(t, s.Append(day(2024, 3, d), task("x")))
+	}
+	n := 0
+	for _, err := range s.Range(time.Time{}, time.Time{}) {
+		must(t, err)
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	if n != 2 {
+		t.Fatalf("visited %d days, want 2", n)
+	}
+}
+
+func testConcurrentAppends(t *testing.T, s store.Store) {
+	d := day(2024, 3, 1)
+	const workers, each = 8, 10
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range each {
+				if err := s.Append(d, task(fmt.Sprintf("%d-%d", w, i))); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	items := load(t, s, d)
+	if len(items) != workers*each {
+		t.Fatalf("got %d items, want %d", len(items), workers*each)
+	}
+	seen := map[string]bool{}
+	for _, it := range items {
+		if seen[it.ID] {
+			t.Fatalf("duplicate ID %q", it.ID)
+		}
+		seen[it.ID] = true
+	}
+}
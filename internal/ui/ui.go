@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +32,15 @@ type UI struct {
 	selID       string
 	selDate     time.Time
 	centerWidth int
+	// dataDir is watched for changes made by other processes
+	dataDir       string
+	journal       string // name of the open journal
+	reloadPending bool
+	stopWatch     func()
+	// err is returned by Run when the data dir could not be opened
+	err error
+	// lineage caches "migrated N times since ..." notes per bullet ID for the current render
+	lineage map[string]string
 
 	// Inline input area (bottom, above controls)
 	inputActive    bool
@@ -40,17 +50,68 @@ type UI struct {
 	promptMessage   string
 }
 
-// New constructs the TUI with a title bar and controls footer.
+// New constructs the TUI with a title bar and controls footer over the
+// default journal. An encrypted data dir is unlocked from
+// BLT_PASSPHRASE/BLT_KEYFILE, or else with a passphrase prompt shown before
+// the journal.
 func New() *UI {
-	appView := tview.NewApplication()
+	return NewJournal("")
+}
 
-	// State and storage
-	st, err := store.NewDefaultFSStore()
-	if err != nil {
+// NewJournal is New for the journal called name; empty opens the default
+// journal.
+func NewJournal(name string) *UI {
+	u := &UI{app: tview.NewApplication()}
+	j, err := store.ResolveJournal(name)
+	switch {
+	case err != nil && name != "":
+		u.err = err
+	case err != nil:
 		// Fallback to a local workspace directory when OS dirs are unavailable.
-		st, _ = store.NewFSStore(".blt-data")
+		st, _ := store.NewFSStore(".blt-data")
+		u.build(st, st)
+	default:
+		if err := u.open(j); err != nil {
+			u.err = err
+		}
+	}
+	return u
+}
+
+// open builds the view over journal j, first asking for its passphrase
+// when it is encrypted and neither BLT_PASSPHRASE nor BLT_KEYFILE is set.
+func (u *UI) open(j store.Journal) error {
+	st, err := store.NewFSStore(j.Dir)
+	var data store.Store = st
+	switch {
+	case errors.Is(err, store.ErrEncrypted):
+		pass, ok, perr := store.PassphraseFromEnv()
+		if perr != nil {
+			return perr
+		}
+		if !ok {
+			u.journal = j.Name
+			u.showUnlock(j.Dir)
+			return nil
+		}
+		if st, data, err = store.NewEncryptedFSStore(j.Dir, pass); err != nil {
+			return err
+		}
+	case err != nil:
+		// Never fall back to another directory over data we cannot read.
+		return err
 	}
-	state := app.New(st)
+	u.journal = j.Name
+	u.build(st, data)
+	return nil
+}
+
+// build creates the journal view over data, the journal in st's data dir.
+func (u *UI) build(st *store.FSStore, data store.Store) {
+	appView := u.app
+	store.SetPreferencesDir(st.Root())
+	_, backupErr := app.DailyBackup(st, time.Now())
+	state := app.New(data)
 	// Load preferences if available
 	centerWidth := 80
 	if prefs, err := store.LoadPreferences(); err == nil {
@@ -59,6 +120,10 @@ func New() *UI {
 				state.JumpToDate(d)
 			}
 		}
+		state.Collection = prefs.Collection
+		if prefs.Period == model.PeriodCollection && prefs.Collection == "" {
+			prefs.Period = model.PeriodDay
+		}
 		if prefs.Period != "" {
 			state.SetPeriod(prefs.Period)
 		}
@@ -71,6 +136,9 @@ func New() *UI {
 		if len(prefs.Tags) > 0 {
 			state.SetTagFilter(prefs.Tags)
 		}
+		if m, err := app.ParseSortMode(prefs.Sort); err == nil {
+			state.Sort = m
+		}
 		if prefs.CenterWidth > 0 {
 			centerWidth = prefs.CenterWidth
 		}
@@ -119,8 +187,12 @@ func New() *UI {
 	// Place the wrapped view in the content area (we keep tview.List off-screen as a selection model)
 	grid.AddItem(wrap, 2, 1, 1, 1, 0, 0, true)
 
-	u := &UI{app: appView, grid: grid, list: list, wrapView: wrap, state: state, title: titleGrid, titleLeft: titleLeft, titleRight: titleRight, controls: controls, sidebar: sideBar}
+	u.grid, u.list, u.wrapView, u.state = grid, list, wrap, state
+	u.title, u.titleLeft, u.titleRight, u.controls, u.sidebar = titleGrid, titleLeft, titleRight, controls, sideBar
 	u.centerWidth = centerWidth
+	if st != nil {
+		u.dataDir = st.Root()
+	}
 	u.refreshList()
 	// Update controls when selection changes to reflect context-aware keybinds and track selection key
 	list.SetChangedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
@@ -170,6 +242,9 @@ func New() *UI {
 		case tcell.KeyEscape:
 			appView.Stop()
 			return nil
+		case tcell.KeyCtrlR:
+			u.redo()
+			return nil
 		case tcell.KeyRune:
 			switch event.Rune() {
 			case 'j':
@@ -204,6 +279,19 @@ func New() *UI {
 				_ = u.state.SetPeriod(model.PeriodMonth)
 				u.refreshList()
 				return nil
+			case '4':
+				_ = u.state.SetPeriod(model.PeriodMonthLog)
+				u.refreshList()
+				return nil
+			case '5':
+				_ = u.state.SetPeriod(model.PeriodFuture)
+				u.refreshList()
+				return nil
+			case 'p':
+				if u.state.Period == model.PeriodMonthLog {
+					u.pullFuture()
+				}
+				return nil
 			case '[':
 				_ = u.state.PrevPeriod()
 				u.refreshList()
@@ -231,15 +319,72 @@ func New() *UI {
 			case '?':
 				u.showHelp()
 				return nil
+			case 'i':
+				if !u.emptyState {
+					u.showDetails()
+				}
+				return nil
+			case 'u':
+				u.undo()
+				return nil
+			case 'R':
+				u.showRecurrences()
+				return nil
+			case 'D':
+				u.showTrash()
+				return nil
+			case 'O':
+				u.showJournals()
+				return nil
+			case 'J', 'K':
+				if !u.emptyState && u.editable() {
+					delta := 1
+					if event.Rune() == 'K' {
+						delta = -1
+					}
+					u.moveSelected(delta)
+				}
+				return nil
+			case 'S':
+				u.cycleSort()
+				return nil
+			case 'C':
+				u.showCollections()
+				return nil
+			case 'I':
+				u.showIndex()
+				return nil
+			case 'V':
+				u.startReview()
+				return nil
+			case 'M':
+				if !u.emptyState && u.editable() {
+					u.showMoveToCollectionDialog()
+				}
+				return nil
 			case 'a':
-				if u.state.Period != model.PeriodDay {
+				if !u.editable() {
 					return nil
 				}
 				u.showAddDialog()
 				return nil
+			case 'A':
+				if !u.emptyState {
+					if !u.editable() {
+						return nil
+					}
+					u.showAddChildDialog()
+				}
+				return nil
+			case 'z':
+				if !u.emptyState {
+					u.state.ToggleCollapsed(u.list.GetCurrentItem())
+					u.refreshList()
+				}
+				return nil
 			case 'e':
 				if !u.emptyState {
-					if u.state.Period != model.PeriodDay {
+					if !u.editable() {
 						return nil
 					}
 					u.showEditDialog()
@@ -247,7 +392,7 @@ func New() *UI {
 				return nil
 			case 'x':
 				if !u.emptyState {
-					if u.state.Period != model.PeriodDay {
+					if !u.editable() {
 						return nil
 					}
 					u.showDeleteConfirm()
@@ -255,15 +400,23 @@ func New() *UI {
 				return nil
 			case 'c':
 				if !u.emptyState {
-					if u.state.Period != model.PeriodDay {
+					if !u.editable() {
 						return nil
 					}
 					u.completeSelected()
 				}
 				return nil
+			case 'X':
+				if !u.emptyState {
+					if !u.editable() {
+						return nil
+					}
+					u.cancelSelected()
+				}
+				return nil
 			case 'm':
 				if !u.emptyState {
-					if u.state.Period != model.PeriodDay {
+					if !u.editable() {
 						return nil
 					}
 					u.migrateSelected()
@@ -271,7 +424,7 @@ func New() *UI {
 				return nil
 			case 's':
 				if !u.emptyState {
-					if u.state.Period != model.PeriodDay {
+					if !u.editable() {
 						return nil
 					}
 					// If currently Scheduled, pressing 's' undoes scheduling without dialog
@@ -287,7 +440,7 @@ func New() *UI {
 				return nil
 			case 't':
 				if !u.emptyState {
-					if u.state.Period != model.PeriodDay {
+					if !u.editable() {
 						return nil
 					}
 					u.showTypePicker()
@@ -295,7 +448,7 @@ func New() *UI {
 				return nil
 			case '#':
 				if !u.emptyState {
-					if u.state.Period != model.PeriodDay {
+					if !u.editable() {
 						return nil
 					}
 					u.showTagsDialog()
@@ -340,13 +493,87 @@ func New() *UI {
 	pages := tview.NewPages()
 	pages.AddPage("main", grid, true, true)
 	u.pages = pages
+	if backupErr != nil {
+		u.showError("Daily backup failed: " + backupErr.Error())
+	}
+}
 
-	return u
+// showUnlock asks for the passphrase of the encrypted data dir in dir and
+// opens the journal once it matches.
+func (u *UI) showUnlock(dir string) {
+	msg := tview.NewTextView().SetTextAlign(tview.AlignCenter).
+		SetText("Journal is encrypted. Enter passphrase (Esc to quit)")
+	field := tview.NewInputField().SetLabel("Passphrase: ").SetMaskCharacter('*')
+	styleInputField(field)
+	field.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEscape:
+			u.app.Stop()
+		case tcell.KeyEnter:
+			st, data, err := store.NewEncryptedFSStore(dir, field.GetText())
+			if err != nil {
+				field.SetText("")
+				msg.SetText(err.Error() + ", try again (Esc to quit)")
+				return
+			}
+			u.build(st, data)
+			u.watch()
+			u.app.SetRoot(u.pages, true).SetFocus(u.wrapView)
+		}
+	})
+	form := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(msg, 1, 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(field, 1, 0, true)
+	u.pages = tview.NewPages()
+	u.pages.AddPage("unlock", center(60, 3, form), true, true)
 }
 
-// Run starts the application event loop.
+// Run starts the application event loop, reloading the view whenever
+// journal files in the data dir change on disk.
 func (u *UI) Run() error {
-	return u.app.SetRoot(u.pages, true).SetFocus(u.wrapView).Run()
+	if u.err != nil {
+		return u.err
+	}
+	defer func() {
+		if u.stopWatch != nil {
+			u.stopWatch()
+		}
+	}()
+	u.watch()
+	var focus tview.Primitive = u.pages
+	if u.state != nil {
+		focus = u.wrapView
+	}
+	return u.app.SetRoot(u.pages, true).SetFocus(focus).Run()
+}
+
+// watch starts reloading the view on changes in the data dir, once the
+// journal is open.
+func (u *UI) watch() {
+	if u.dataDir != "" && u.stopWatch == nil {
+		u.stopWatch = watchDir(u.dataDir, func() { u.app.QueueUpdateDraw(u.reload) })
+	}
+}
+
+// reload re-reads the visible range after a change on disk, keeping the
+// selection. While an input or overlay is open the reload is retried later,
+// so the indexes it acts on do not shift underneath it.
+func (u *UI) reload() {
+	if u.inputActive {
+		if !u.reloadPending {
+			u.reloadPending = true
+			time.AfterFunc(watchSettle, func() {
+				u.app.QueueUpdateDraw(func() {
+					u.reloadPending = false
+					u.reload()
+				})
+			})
+		}
+		return
+	}
+	_ = u.state.Refresh()
+	u.refreshList()
 }
 
 // refreshList rebuilds the list items from state and sets empty-state if needed.
@@ -355,6 +582,7 @@ func (u *UI) refreshList() {
 	prevIdx := u.list.GetCurrentItem()
 	u.list.Clear()
 	vis := u.state.Visible()
+	u.lineage = u.state.LineageSummaries(vis)
 	u.updateSidebar()
 	if len(vis) == 0 {
 		u.list.AddItem("⟂ No items for today — press 'a' to add", "", 0, nil)
@@ -395,13 +623,27 @@ func (u *UI) refreshList() {
 func (u *UI) updateStatus() {
 	// Title shows product name on left and scope+range+completion on right
 	scope := strings.Title(string(u.state.Period))
+	switch u.state.Period {
+	case model.PeriodMonthLog:
+		scope = "Monthly Log"
+	case model.PeriodFuture:
+		scope = "Future Log"
+	case model.PeriodCollection:
+		scope = "Collection"
+	}
 	rng := u.stateVisibleRangeLabel()
 	pct := u.percentComplete()
 	left := "[red::b]BLT[-]"
+	if u.journal != "" && u.journal != store.DefaultJournal {
+		left += "  " + tvEscape(u.journal)
+	}
 	right := scope + " " + rng
 	if pct >= 0 {
 		right += "  (" + strconv.Itoa(pct) + "% done)"
 	}
+	if u.state.Sort != app.SortManual {
+		right += "  by " + u.state.Sort.String()
+	}
 	if u.titleLeft != nil {
 		u.titleLeft.SetText(left)
 	}
@@ -411,7 +653,7 @@ func (u *UI) updateStatus() {
 
 	filters := u.filtersSummary()
 	note := ""
-	if u.state.Period != model.PeriodDay {
+	if !u.editable() {
 		note = "    (Edits disabled in Week/Month — switch to Day)"
 	}
 	// When input is active, show only Enter/Esc hints
@@ -435,7 +677,7 @@ func (u *UI) updateStatus() {
 		if isToday {
 			base = strings.Replace(base, "  [T] Today", "", 1)
 		}
-		u.controls.SetText(base + filters + note)
+		u.controls.SetText(base + filters + note + u.warningSummary())
 	}
 }
 
@@ -456,11 +698,28 @@ func (u *UI) stateVisibleRangeLabel() string {
 	case model.PeriodMonth:
 		r := u.stateVisibleRange()
 		return r.Start.Format("2006-01-02") + " → " + r.End.Format("2006-01-02")
+	case model.PeriodMonthLog:
+		return u.state.CurrentDate.Format("January 2006")
+	case model.PeriodFuture:
+		return ""
+	case model.PeriodCollection:
+		return tvEscape(u.state.Collection)
 	default:
 		return u.state.CurrentDate.Format("2006-01-02")
 	}
 }
 
+// editable reports whether the current view allows modifying bullets: the
+// day view, the monthly and future logs, and collections, which each map to
+// one file.
+func (u *UI) editable() bool {
+	switch u.state.Period {
+	case model.PeriodDay, model.PeriodMonthLog, model.PeriodFuture, model.PeriodCollection:
+		return true
+	}
+	return false
+}
+
 func (u *UI) stateVisibleRange() model.DateRange {
 	// Recompute similarly to app.dateRange; duplicate to avoid export
 	d := u.state.CurrentDate
@@ -509,7 +768,75 @@ func (u *UI) filtersSummary() string {
 
 // Dialogs and actions
 func (u *UI) showAddDialog() {
-	field := tview.NewInputField().SetLabel("Add: ").SetFieldWidth(60)
+	label := "Add: "
+	if u.state.Period == model.PeriodFuture {
+		label = "Add (YYYY-MM text): "
+	}
+	field := tview.NewInputField().SetLabel(label).SetFieldWidth(60)
+	field.SetBorder(false)
+	styleInputField(field)
+	field.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			u.hideInput()
+			return nil
+		case tcell.KeyEnter:
+			text := field.GetText()
+			if strings.TrimSpace(text) == "" {
+				return nil
+			}
+			if u.state.Period == model.PeriodFuture {
+				u.addFuture(text)
+			} else {
+				_, _ = u.state.Add(text)
+			}
+			u.hideInput()
+			u.refreshList()
+			return nil
+		}
+		return event
+	})
+	u.showInput(field)
+}
+
+// addFuture adds text to the future log. A leading YYYY-MM picks the target
+// month; otherwise the item goes under next month.
+func (u *UI) addFuture(text string) {
+	month := time.Now().AddDate(0, 1, 0)
+	if f := strings.Fields(text); len(f) > 1 {
+		if t, err := time.ParseInLocation("2006-01", f[0], time.Local); err == nil {
+			month = t
+			text = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), f[0]))
+		}
+	}
+	b := model.Bullet{Text: text, Type: model.Task, CreatedAt: time.Now()}
+	if _, err := u.state.AddToLog(app.LogFuture, month, b); err != nil {
+		u.showError(err.Error())
+	}
+}
+
+// pullFuture migrates due future-log items into the shown monthly log.
+func (u *UI) pullFuture() {
+	n, err := u.state.PullFuture(u.state.CurrentDate)
+	if err != nil {
+		u.showError(err.Error())
+		return
+	}
+	u.refreshList()
+	if n == 0 {
+		u.showNotice("Nothing due in the future log")
+		return
+	}
+	u.showNotice("Pulled " + itoa(n) + " item(s) from the future log")
+}
+
+func (u *UI) showAddChildDialog() {
+	idx := u.list.GetCurrentItem()
+	vis := u.state.Visible()
+	if idx < 0 || idx >= len(vis) {
+		return
+	}
+	field := tview.NewInputField().SetLabel("Add sub-item: ").SetFieldWidth(60)
 	field.SetBorder(false)
 	styleInputField(field)
 	field.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -522,7 +849,10 @@ func (u *UI) showAddDialog() {
 			if strings.TrimSpace(text) == "" {
 				return nil
 			}
-			_, _ = u.state.Add(text)
+			if b, err := u.state.AddChild(idx, text); err == nil && b.ID != "" {
+				u.selID = b.ID
+				u.selDate = vis[idx].Date
+			}
 			u.hideInput()
 			u.refreshList()
 			return nil
@@ -582,12 +912,77 @@ func (u *UI) completeSelected() {
 	u.refreshList()
 }
 
+func (u *UI) cancelSelected() {
+	idx := u.list.GetCurrentItem()
+	_ = u.state.CancelIndex(idx)
+	u.refreshList()
+}
+
 func (u *UI) migrateSelected() {
 	idx := u.list.GetCurrentItem()
 	_ = u.state.MigrateIndex(idx)
 	u.refreshList()
 }
 
+// moveSelected moves the selected bullet delta places among its siblings.
+// Manual moves only show in the manual order, so other sorts refuse them.
+func (u *UI) moveSelected(delta int) {
+	if u.state.Sort != app.SortManual {
+		u.showNotice("Sorted by " + u.state.Sort.String() + "; press S until manual order to reorder")
+		return
+	}
+	idx, err := u.state.MoveIndex(u.list.GetCurrentItem(), delta)
+	u.refreshList()
+	if err != nil {
+		u.showError(err.Error())
+		return
+	}
+	u.list.SetCurrentItem(idx)
+}
+
+// cycleSort switches to the next sort mode.
+func (u *UI) cycleSort() {
+	next := app.SortModes[0]
+	for i, m := range app.SortModes {
+		if m == u.state.Sort {
+			next = app.SortModes[(i+1)%len(app.SortModes)]
+		}
+	}
+	err := u.state.SetSort(next)
+	u.refreshList()
+	if err != nil {
+		u.showError(err.Error())
+		return
+	}
+	u.showNotice("Sort: " + next.String())
+}
+
+func (u *UI) undo() {
+	label, err := u.state.Undo()
+	u.refreshList()
+	switch {
+	case err != nil:
+		u.showError(err.Error())
+	case label == "":
+		u.showNotice("Nothing to undo")
+	default:
+		u.showNotice("Undid " + label)
+	}
+}
+
+func (u *UI) redo() {
+	label, err := u.state.Redo()
+	u.refreshList()
+	switch {
+	case err != nil:
+		u.showError(err.Error())
+	case label == "":
+		u.showNotice("Nothing to redo")
+	default:
+		u.showNotice("Redid " + label)
+	}
+}
+
 func (u *UI) showScheduleDialog() {
 	idx := u.list.GetCurrentItem()
 	vis := u.state.Visible()
@@ -693,6 +1088,8 @@ func (u *UI) showTypeFilter() {
 					types = append(types, model.Migrated)
 				case "scheduled":
 					types = append(types, model.Scheduled)
+				case "cancelled", "canceled":
+					types = append(types, model.Cancelled)
 				case "event":
 					types = append(types, model.Event)
 				case "note":
@@ -754,19 +1151,28 @@ func (u *UI) showHelp() {
 		"  j/k   PgUp/PgDn   g/G",
 		"",
 		"Scope:",
-		"  1 Day   2 Week   3 Month",
+		"  1 Day   2 Week   3 Month   4 Monthly log   5 Future log",
 		"  [ Prev  ] Next   d Jump to date   T Today",
 		"",
 		"Filters:",
 		"  / Text  : Type (toggle)   F Tags",
 		"",
-		"Modify (Day only):",
-		"  a Add   e Edit   x Delete   t Type   # Tags",
+		"Modify (Day and logs):",
+		"  a Add   A Sub-item   e Edit   x Delete   t Type   # Tags",
+		"  z Expand/Collapse   u Undo   Ctrl-R Redo   R Recurring",
+		"  J/K Move item down/up   S Sort: manual, type, created, tag, open first",
+		"  i Details: timestamps, lineage and earlier versions",
+		"  C Collections   M Move to collection   I Index",
+		"  D Trash: restore or empty deleted items   O Open another journal",
+		"  V Review open tasks in the current Day/Week/Month",
 		"",
 		"Actions:",
 		"  c Complete (toggle Task/Done)",
+		"  X Cancel: strike an irrelevant task (toggle)",
 		"  m Migrate (toggle on Migrated)",
 		"  s Schedule (toggle on Scheduled)",
+		"  p Pull due future-log items (Monthly log)",
+		"  m in a collection moves the item into today's log",
 		"",
 		"Close: Esc",
 	}
@@ -793,6 +1199,36 @@ func (u *UI) showHelp() {
 	u.app.SetFocus(tv)
 }
 
+// showDetails opens the detail view of the selected bullet.
+func (u *UI) showDetails() {
+	idx := u.list.GetCurrentItem()
+	vis := u.state.Visible()
+	if idx < 0 || idx >= len(vis) {
+		return
+	}
+	lines := u.state.Details(vis[idx], time.Now())
+	for i, l := range lines {
+		lines[i] = tvEscape(l)
+	}
+	lines = append(lines, "", "Close: Esc")
+	tv := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(true).
+		SetWordWrap(true).
+		SetText(strings.Join(lines, "\n"))
+	tv.SetBorder(false)
+	tv.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' || event.Rune() == 'i' {
+			u.pages.RemovePage("details")
+			u.app.SetFocus(u.wrapView)
+			return nil
+		}
+		return nil
+	})
+	u.pages.AddPage("details", pad(wrapWithRules(tv), 1, 1), true, true)
+	u.app.SetFocus(tv)
+}
+
 // center returns a centered primitive with a fixed size.
 func center(w, h int, p tview.Primitive) tview.Primitive {
 	return tview.NewFlex().
@@ -925,6 +1361,8 @@ func formatBullet(b model.Bullet) string {
 		prefix = "»"
 	case model.Scheduled:
 		prefix = "⧗"
+	case model.Cancelled:
+		prefix = "✗"
 	case model.Event:
 		prefix = "◇"
 	case model.Note:
@@ -957,15 +1395,28 @@ func formatBullet(b model.Bullet) string {
 			tagStr = "  [::d]" + strings.Join(parts, " ") + "[-:-:-]"
 		}
 	}
-	return prefix + " " + tvEscape(b.Text) + tagStr
+	text := tvEscape(b.Text)
+	if b.Type == model.Cancelled {
+		text = "[::s]" + text + "[::-]"
+	}
+	return prefix + " " + text + tagStr
 }
 
 func (u *UI) formatEntry(e app.Entry) string {
-	label := formatBullet(e.Item)
-	if u.state.Period != model.PeriodDay {
-		return tvEscape(e.Date.Format("2006-01-02")) + "  " + label
+	label := strings.Repeat("  ", e.Depth) + formatBullet(e.Item)
+	if note := u.lineage[e.Item.ID]; note != "" {
+		label += "  [::d](" + tvEscape(note) + ")[-:-:-]"
+	}
+	if e.Children > 0 && u.state.Collapsed[e.Item.ID] {
+		label += "  [::d](+" + itoa(e.Children) + ")[-:-:-]"
 	}
-	return label
+	switch u.state.Period {
+	case model.PeriodDay, model.PeriodMonthLog, model.PeriodCollection:
+		return label
+	case model.PeriodFuture:
+		return tvEscape(e.Date.Format("Jan 2006")) + "  " + label
+	}
+	return tvEscape(e.Date.Format("2006-01-02")) + "  " + label
 }
 
 // contextControls builds the controls footer dynamically based on selection and scope.
@@ -973,17 +1424,27 @@ func (u *UI) contextControls() string {
 	// Base navigation/help always visible
 	nav := "  [j/k] Move  [T] Today  [?] Help"
 	// In non-day views, show default plus note handled by caller
-	if u.state.Period != model.PeriodDay {
+	if !u.editable() {
 		return DefaultControls
 	}
+	if u.state.Period == model.PeriodMonthLog {
+		nav = "  [p] Pull future" + nav
+	}
 	// Day view: build modify keys based on selected item's type
 	var parts []string
-	parts = append(parts, "[a] Add", "[e] Edit", "[x] Delete", "[t] Type", "[#] Tags")
+	parts = append(parts, "[a] Add", "[A] Sub-item", "[e] Edit", "[x] Delete", "[t] Type", "[#] Tags")
 	// Resolve current selection
 	idx := u.list.GetCurrentItem()
 	vis := u.state.Visible()
 	if idx >= 0 && idx < len(vis) {
 		typ := vis[idx].Item.Type
+		if vis[idx].Children > 0 {
+			if u.state.Collapsed[vis[idx].Item.ID] {
+				parts = append(parts, "[z] Expand")
+			} else {
+				parts = append(parts, "[z] Collapse")
+			}
+		}
 		// Complete available for Task and Done (toggle), not for Migrated/Scheduled/others
 		if typ == model.Task || typ == model.Done {
 			if typ == model.Done {
@@ -992,13 +1453,24 @@ func (u *UI) contextControls() string {
 				parts = append(parts, "[c] Complete")
 			}
 		}
+		// Cancel available for Task, and Cancelled (to restore)
+		if typ == model.Task {
+			parts = append(parts, "[X] Cancel")
+		} else if typ == model.Cancelled {
+			parts = append(parts, "[X] Restore")
+		}
 		// Migrate available for Task/Event and Migrated (for undo)
 		if typ == model.Task || typ == model.Event || typ == model.Migrated {
 			if typ == model.Migrated {
 				parts = append(parts, "[m] Unmigrate")
+			} else if u.state.Period == model.PeriodCollection {
+				parts = append(parts, "[m] To today")
 			} else {
 				parts = append(parts, "[m] Migrate")
 			}
+			if typ != model.Migrated {
+				parts = append(parts, "[M] To collection")
+			}
 		}
 		// Schedule available for Task/Event and Scheduled (for undo)
 		if typ == model.Task || typ == model.Event || typ == model.Scheduled {
@@ -1016,7 +1488,7 @@ func (u *UI) contextControls() string {
 }
 
 // percentComplete computes percentage of completed actionable items (Task/Done) in the visible range.
-// Returns -1 when there are no actionable items.
+// Cancelled tasks are not actionable and do not count. Returns -1 when there are no actionable items.
 func (u *UI) percentComplete() int {
 	vis := u.state.Visible()
 	total := 0
@@ -1129,34 +1601,756 @@ func (u *UI) showTypePicker() {
 	u.updateStatus()
 }
 
-// renderWrapped renders the visible entries into the word-wrapped TextView with regions
-// so that long bullet lines wrap instead of being cut off.
-func (u *UI) renderWrapped(entries []app.Entry) {
-	var b strings.Builder
-	if len(entries) == 0 {
-		b.WriteString("⟂ No items for today — press 'a' to add\n")
-	} else {
-		for i, e := range entries {
-			// Start region for selection highlighting of this item
-			regionID := "item:" + strconv.Itoa(i)
-			b.WriteString("[\"" + regionID + "\"]")
-			b.WriteString(u.formatEntry(e))
-			// End region
-			b.WriteString("[\"\"]\n")
+// showRecurrences opens an overlay listing recurrence rules, with keys to add
+// and remove rules.
+func (u *UI) showRecurrences() {
+	rules, err := u.state.Recurrences()
+	if err != nil {
+		u.showError(err.Error())
+		return
+	}
+	dismiss := func() {
+		u.pages.RemovePage("recurrences")
+		u.inputActive = false
+		u.app.SetFocus(u.wrapView)
+		u.updateStatus()
+	}
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(false)
+	if len(rules) == 0 {
+		list.AddItem("No recurring items — press 'a' to add", "", 0, nil)
+	}
+	for _, r := range rules {
+		list.AddItem(tvEscape(r.Rule())+"  "+formatBullet(model.Bullet{Type: r.Type, Text: r.Text, Tags: r.Tags}), "", 0, nil)
+	}
+	list.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEscape {
+			dismiss()
+			return nil
+		}
+		if ev.Key() == tcell.KeyRune {
+			switch ev.Rune() {
+			case 'j':
+				moveDown(list)
+				return nil
+			case 'k':
+				moveUp(list)
+				return nil
+			case 'a':
+				dismiss()
+				u.showAddRecurrenceDialog()
+				return nil
+			case 'x':
+				idx := list.GetCurrentItem()
+				if idx >= 0 && idx < len(rules) {
+					if err := u.state.RemoveRecurrence(rules[idx].ID); err != nil {
+						dismiss()
+						u.showError(err.Error())
+						return nil
+					}
+					dismiss()
+					u.showRecurrences()
+				}
+				return nil
+			}
 		}
+		return ev
+	})
+
+	hintText := "[j/k] Move   [a] Add   [x] Remove   [esc] Close"
+	hints := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(false).
+		SetText(hintText)
+	hints.SetBorder(false)
+	rows := list.GetItemCount()
+	inner := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(list, rows, 0, true).
+		AddItem(hints, 1, 0, false)
+	height := rows + 3
+	if height < 6 {
+		height = 6
 	}
-	u.wrapView.SetText(b.String())
-	// Keep highlight in sync with current selection
-	u.highlightSelection(u.list.GetCurrentItem())
+	u.pages.AddPage("recurrences", center(u.centerWidth, height, wrapWithRules(inner)), true, true)
+	u.inputActive = true
+	u.app.SetFocus(list)
+	u.updateStatus()
 }
 
-// highlightSelection highlights the current item region in the wrapped view and scrolls to it.
-func (u *UI) highlightSelection(idx int) {
-	if idx < 0 {
-		u.wrapView.Highlight()
+// showTrash opens an overlay listing deleted bullets, newest first, with
+// keys to restore one to where it was deleted from or to empty the trash.
+func (u *UI) showTrash() {
+	items, err := u.state.Trash()
+	if err != nil {
+		u.showError(err.Error())
 		return
 	}
-	regionID := "item:" + strconv.Itoa(idx)
+	dismiss := func() {
+		u.pages.RemovePage("trash")
+		u.inputActive = false
+		u.app.SetFocus(u.wrapView)
+		u.updateStatus()
+	}
+	now := time.Now()
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(false)
+	if len(items) == 0 {
+		list.AddItem("Trash is empty", "", 0, nil)
+	}
+	for _, b := range items {
+		line := formatBullet(b) + "  [gray](" + tvEscape(app.DeletedFrom(b))
+		if b.Deleted != nil {
+			line += ", deleted " + app.Ago(b.Deleted.At, now)
+		}
+		list.AddItem(line+")[-]", "", 0, nil)
+	}
+
+	hintText := "[j/k] Move   [r/enter] Restore   [E] Empty   [esc] Close"
+	hints := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(false).
+		SetText(hintText)
+	hints.SetBorder(false)
+	confirming := false
+	restore := func() {
+		idx := list.GetCurrentItem()
+		if idx < 0 || idx >= len(items) {
+			return
+		}
+		b, err := u.state.Restore(items[idx].ID)
+		dismiss()
+		u.refreshList()
+		if err != nil {
+			u.showError(err.Error())
+			return
+		}
+		u.showNotice("Restored " + b.Text + " to " + app.DeletedFrom(b))
+	}
+	list.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if confirming {
+			confirming = false
+			hints.SetText(hintText)
+			if ev.Key() != tcell.KeyEnter {
+				return nil
+			}
+			n, err := u.state.EmptyTrash()
+			dismiss()
+			if err != nil {
+				u.showError(err.Error())
+				return nil
+			}
+			u.showNotice("Deleted " + strconv.Itoa(n) + " item(s) for good")
+			return nil
+		}
+		switch ev.Key() {
+		case tcell.KeyEscape:
+			dismiss()
+			return nil
+		case tcell.KeyEnter:
+			restore()
+			return nil
+		case tcell.KeyRune:
+			switch ev.Rune() {
+			case 'j':
+				moveDown(list)
+				return nil
+			case 'k':
+				moveUp(list)
+				return nil
+			case 'r':
+				restore()
+				return nil
+			case 'E':
+				if len(items) > 0 {
+					confirming = true
+					hints.SetText("Empty the trash for good? [enter] Yes   [esc] No")
+				}
+				return nil
+			}
+		}
+		return ev
+	})
+
+	rows := list.GetItemCount()
+	inner := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(list, rows, 0, true).
+		AddItem(hints, 1, 0, false)
+	height := rows + 3
+	if height < 6 {
+		height = 6
+	}
+	u.pages.AddPage("trash", center(u.centerWidth, height, wrapWithRules(inner)), true, true)
+	u.inputActive = true
+	u.app.SetFocus(list)
+	u.updateStatus()
+}
+
+// showJournals opens an overlay listing the journals, with Enter switching
+// to the selected one.
+func (u *UI) showJournals() {
+	journals, err := store.Journals()
+	if err != nil {
+		u.showError(err.Error())
+		return
+	}
+	dismiss := func() {
+		u.pages.RemovePage("journals")
+		u.inputActive = false
+		u.app.SetFocus(u.wrapView)
+		u.updateStatus()
+	}
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(false)
+	for _, j := range journals {
+		mark := "  "
+		if j.Name == u.journal {
+			mark = "• "
+		}
+		list.AddItem(mark+tvEscape(j.Name)+"  [gray]"+tvEscape(j.Dir)+"[-]", "", 0, nil)
+	}
+	for i, j := range journals {
+		if j.Name == u.journal {
+			list.SetCurrentItem(i)
+		}
+	}
+	list.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEscape:
+			dismiss()
+			return nil
+		case tcell.KeyEnter:
+			idx := list.GetCurrentItem()
+			dismiss()
+			if idx >= 0 && idx < len(journals) && journals[idx].Name != u.journal {
+				u.switchJournal(journals[idx])
+			}
+			return nil
+		case tcell.KeyRune:
+			switch ev.Rune() {
+			case 'j':
+				moveDown(list)
+				return nil
+			case 'k':
+				moveUp(list)
+				return nil
+			}
+		}
+		return ev
+	})
+
+	hints := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(false).
+		SetText("[j/k] Move   [enter] Open   [esc] Close   (blt journal add NAME DIR)")
+	hints.SetBorder(false)
+	rows := list.GetItemCount()
+	inner := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(list, rows, 0, true).
+		AddItem(hints, 1, 0, false)
+	height := rows + 3
+	if height < 6 {
+		height = 6
+	}
+	u.pages.AddPage("journals", center(u.centerWidth, height, wrapWithRules(inner)), true, true)
+	u.inputActive = true
+	u.app.SetFocus(list)
+	u.updateStatus()
+}
+
+// switchJournal closes the open journal and shows j in its place; the
+// preferences of each journal are kept in its own data dir.
+func (u *UI) switchJournal(j store.Journal) {
+	if u.stopWatch != nil {
+		u.stopWatch()
+		u.stopWatch = nil
+	}
+	if err := u.open(j); err != nil {
+		u.watch()
+		u.showError(err.Error())
+		return
+	}
+	if u.dataDir != j.Dir {
+		// Waiting for the passphrase; the unlock prompt opens the journal.
+		u.app.SetRoot(u.pages, true).SetFocus(u.pages)
+		return
+	}
+	u.watch()
+	u.app.SetRoot(u.pages, true).SetFocus(u.wrapView)
+}
+
+// showAddRecurrenceDialog prompts for "[type] RULE: text", e.g.
+// "event weekly mon,wed: Standup".
+func (u *UI) showAddRecurrenceDialog() {
+	field := tview.NewInputField().SetLabel("Recurring ([type] rule: text): ").SetFieldWidth(60)
+	field.SetBorder(false)
+	styleInputField(field)
+	field.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			u.hideInput()
+			return nil
+		case tcell.KeyEnter:
+			spec, text, ok := strings.Cut(field.GetText(), ":")
+			if !ok || strings.TrimSpace(text) == "" {
+				return nil
+			}
+			typ := model.Task
+			fields := strings.Fields(spec)
+			if len(fields) > 0 {
+				switch strings.ToLower(fields[0]) {
+				case "task", "event", "note":
+					typ = model.BulletType(strings.ToLower(fields[0]))
+					spec = strings.Join(fields[1:], " ")
+				}
+			}
+			r, err := model.ParseRule(spec)
+			if err != nil {
+				u.hideInput()
+				u.showError(err.Error())
+				return nil
+			}
+			r.Type = typ
+			r.Text = strings.TrimSpace(text)
+			_, err = u.state.AddRecurrence(r)
+			u.hideInput()
+			u.refreshList()
+			if err != nil {
+				u.showError(err.Error())
+			}
+			return nil
+		}
+		return event
+	})
+	u.showInput(field)
+}
+
+// showCollections opens an overlay listing named collections. Enter opens
+// the selected collection in the main view.
+func (u *UI) showCollections() {
+	cols, err := u.state.Collections()
+	if err != nil {
+		u.showError(err.Error())
+		return
+	}
+	dismiss := func() {
+		u.pages.RemovePage("collections")
+		u.inputActive = false
+		u.app.SetFocus(u.wrapView)
+		u.updateStatus()
+	}
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(false)
+	if len(cols) == 0 {
+		list.AddItem("No collections — press 'a' to create one", "", 0, nil)
+	}
+	for _, c := range cols {
+		list.AddItem(tvEscape(c.Name)+"  [::d]("+itoa(c.Count)+" items, "+itoa(c.Open)+" open)[-:-:-]", "", 0, nil)
+	}
+	list.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		idx := list.GetCurrentItem()
+		switch ev.Key() {
+		case tcell.KeyEscape:
+			dismiss()
+			return nil
+		case tcell.KeyEnter:
+			if idx >= 0 && idx < len(cols) {
+				dismiss()
+				u.selID = ""
+				if err := u.state.OpenCollection(cols[idx].Name); err != nil {
+					u.showError(err.Error())
+				}
+				u.refreshList()
+			}
+			return nil
+		case tcell.KeyRune:
+			switch ev.Rune() {
+			case 'j':
+				moveDown(list)
+				return nil
+			case 'k':
+				moveUp(list)
+				return nil
+			case 'a':
+				dismiss()
+				u.showPrompt("New collection: ", "", func(name string) error {
+					if err := u.state.CreateCollection(name); err != nil {
+						return err
+					}
+					return u.state.OpenCollection(name)
+				})
+				return nil
+			case 'r':
+				if idx >= 0 && idx < len(cols) {
+					from := cols[idx].Name
+					dismiss()
+					u.showPrompt("Rename to: ", from, func(to string) error {
+						return u.state.RenameCollection(from, to)
+					})
+				}
+				return nil
+			case 'x':
+				if idx >= 0 && idx < len(cols) {
+					name := cols[idx].Name
+					dismiss()
+					u.inputActive = true
+					u.promptMessage = "Delete collection " + tvEscape(name) + " and move its " + itoa(cols[idx].Count) + " items to the trash?"
+					u.confirmCallback = func(confirm bool) {
+						if !confirm {
+							return
+						}
+						if err := u.state.DeleteCollection(name); err != nil {
+							u.showError(err.Error())
+							return
+						}
+						u.refreshList()
+					}
+					u.updateStatus()
+				}
+				return nil
+			}
+		}
+		return ev
+	})
+
+	hintText := "[j/k] Move   [enter] Open   [a] New   [r] Rename   [x] Delete   [esc] Close"
+	hints := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(false).
+		SetText(hintText)
+	hints.SetBorder(false)
+	rows := list.GetItemCount()
+	inner := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(list, rows, 0, true).
+		AddItem(hints, 1, 0, false)
+	height := rows + 3
+	if height < 6 {
+		height = 6
+	}
+	u.pages.AddPage("collections", center(u.centerWidth, height, wrapWithRules(inner)), true, true)
+	u.inputActive = true
+	u.app.SetFocus(list)
+	u.updateStatus()
+}
+
+// review is the state of a migration review walking open tasks one by one.
+type review struct {
+	title   string
+	entries []app.Entry
+	pos     int
+	counts  app.ReviewCounts
+}
+
+// startReview walks every open task in the current Day/Week/Month range,
+// asking what to do with each, and ends by noting the decisions in today's
+// log.
+func (u *UI) startReview() {
+	var title string
+	switch u.state.Period {
+	case model.PeriodDay:
+		title = u.state.CurrentDate.Format("Jan 2, 2006")
+	case model.PeriodWeek:
+		title = "week of " + u.state.Range().Start.Format("Jan 2, 2006")
+	case model.PeriodMonth:
+		title = u.state.CurrentDate.Format("January 2006")
+	default:
+		u.showError("Review works on the Day, Week and Month views")
+		return
+	}
+	entries, err := u.state.ReviewItems(u.state.Range())
+	if err != nil {
+		u.showError(err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		u.showNotice("Nothing open to review")
+		return
+	}
+	u.showReviewItem(&review{title: title, entries: entries, counts: app.ReviewCounts{}})
+}
+
+// showReviewItem presents the current review item, or finishes the review
+// once every item has been decided.
+func (u *UI) showReviewItem(r *review) {
+	if r.pos >= len(r.entries) {
+		u.finishReview(r)
+		return
+	}
+	e := r.entries[r.pos]
+	dismiss := func() {
+		u.pages.RemovePage("review")
+		u.inputActive = false
+		u.app.SetFocus(u.wrapView)
+		u.updateStatus()
+	}
+	decide := func(d app.ReviewDecision, target time.Time) {
+		if err := u.state.Review(e, d, target); err != nil {
+			u.showError(err.Error())
+			return
+		}
+		r.counts[d]++
+		r.pos++
+		u.showReviewItem(r)
+	}
+	lines := []string{
+		"[::b]Review " + tvEscape(r.title) + "[-:-:-]  " + itoa(r.pos+1) + "/" + itoa(len(r.entries)),
+		"",
+		e.Date.Format("Mon Jan 2") + "   " + formatBullet(e.Item),
+	}
+	if note := u.state.LineageSummary(e); note != "" {
+		lines = append(lines, "[::d]("+tvEscape(note)+")[-:-:-]")
+	}
+	tv := tview.NewTextView().SetDynamicColors(true).SetWrap(true).SetWordWrap(true)
+	tv.SetText(strings.Join(lines, "\n"))
+	tv.SetBorder(false)
+	tv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEscape {
+			dismiss()
+			u.finishReview(r)
+			return nil
+		}
+		switch ev.Rune() {
+		case 'm':
+			dismiss()
+			decide(app.ReviewMigrate, time.Time{})
+		case 's':
+			dismiss()
+			u.showReviewPrompt(r, "Schedule for (YYYY-MM-DD): ", time.Now().AddDate(0, 0, 1).Format("2006-01-02"), "2006-01-02", func(t time.Time) {
+				decide(app.ReviewSchedule, t)
+			})
+		case 'f':
+			dismiss()
+			u.showReviewPrompt(r, "Future log month (YYYY-MM): ", e.Date.AddDate(0, 1, 1-e.Date.Day()).Format("2006-01"), "2006-01", func(t time.Time) {
+				decide(app.ReviewFuture, t)
+			})
+		case 'x':
+			dismiss()
+			decide(app.ReviewCancel, time.Time{})
+		case 'c':
+			dismiss()
+			decide(app.ReviewDone, time.Time{})
+		case 'n', ' ':
+			dismiss()
+			decide(app.ReviewSkip, time.Time{})
+		}
+		return nil
+	})
+	hints := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(false).
+		SetText("[m] Next day  [s] Schedule  [f] Future log  [x] Irrelevant  [c] Done  [n] Skip  [esc] Finish")
+	hints.SetWrap(true)
+	hints.SetBorder(false)
+	inner := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(tv, 0, 1, true).
+		AddItem(hints, 2, 0, false)
+	u.pages.AddPage("review", center(u.centerWidth, 10, wrapWithRules(inner)), true, true)
+	u.inputActive = true
+	u.app.SetFocus(tv)
+	u.updateStatus()
+}
+
+// showReviewPrompt asks for a date in layout, then resumes the review via
+// fn. Esc returns to the same item.
+func (u *UI) showReviewPrompt(r *review, label, def, layout string, fn func(time.Time)) {
+	field := tview.NewInputField().SetLabel(label).SetText(def).SetFieldWidth(20)
+	field.SetBorder(false)
+	styleInputField(field)
+	field.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			u.hideInput()
+			u.showReviewItem(r)
+			return nil
+		case tcell.KeyEnter:
+			t, err := time.ParseInLocation(layout, strings.TrimSpace(field.GetText()), time.Local)
+			if err != nil {
+				return nil
+			}
+			u.hideInput()
+			fn(t)
+			return nil
+		}
+		return event
+	})
+	u.showInput(field)
+}
+
+// finishReview writes the summary note and reports it in the footer.
+func (u *UI) finishReview(r *review) {
+	if r.pos == 0 {
+		u.refreshList()
+		return
+	}
+	// Items never reached count as skipped.
+	r.counts[app.ReviewSkip] += len(r.entries) - r.pos
+	text, err := u.state.FinishReview(r.title, r.counts)
+	u.refreshList()
+	if err != nil {
+		u.showError(err.Error())
+		return
+	}
+	u.showNotice(tvEscape(text))
+}
+
+// showIndex opens the journal index: collections, tags and months. Enter
+// jumps to the collection, or to the month view (tag-filtered for tags).
+func (u *UI) showIndex() {
+	idx, err := u.state.BuildIndex()
+	if err != nil {
+		u.showError(err.Error())
+		return
+	}
+	dismiss := func() {
+		u.pages.RemovePage("index")
+		u.inputActive = false
+		u.app.SetFocus(u.wrapView)
+		u.updateStatus()
+	}
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(false)
+	// actions[i] runs when Enter is pressed on row i; headers have none.
+	var actions []func() error
+	header := func(title string) {
+		list.AddItem("[::b]"+title+"[-:-:-]", "", 0, nil)
+		actions = append(actions, nil)
+	}
+	header("Collections")
+	for _, c := range idx.Collections {
+		name := c.Name
+		list.AddItem("  "+tvEscape(name)+"  [::d]("+itoa(c.Count)+" items, "+itoa(c.Open)+" open)[-:-:-]", "", 0, nil)
+		actions = append(actions, func() error { return u.state.OpenCollection(name) })
+	}
+	header("Tags")
+	for _, t := range idx.Tags {
+		rng := t.First.Format("2006-01-02")
+		if !t.Last.Equal(t.First) {
+			rng += " → " + t.Last.Format("2006-01-02")
+		}
+		list.AddItem("  "+tvEscape("#"+t.Tag)+"  [::d]("+itoa(t.Count)+", "+rng+")[-:-:-]", "", 0, nil)
+		actions = append(actions, func() error { return u.state.ShowTag(t) })
+	}
+	header("Months")
+	for i := len(idx.Months) - 1; i >= 0; i-- {
+		m := idx.Months[i]
+		list.AddItem("  "+m.Month.Format("January 2006")+"  [::d]("+itoa(m.Total)+" items, "+itoa(m.Open)+" open, "+itoa(m.Done)+" done)[-:-:-]", "", 0, nil)
+		actions = append(actions, func() error { return u.state.ShowMonth(m.Month) })
+	}
+	list.SetCurrentItem(1)
+	list.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEscape:
+			dismiss()
+			return nil
+		case tcell.KeyEnter:
+			i := list.GetCurrentItem()
+			if i >= 0 && i < len(actions) && actions[i] != nil {
+				dismiss()
+				u.selID = ""
+				if err := actions[i](); err != nil {
+					u.showError(err.Error())
+				}
+				u.refreshList()
+			}
+			return nil
+		case tcell.KeyRune:
+			switch ev.Rune() {
+			case 'j':
+				moveDown(list)
+				return nil
+			case 'k':
+				moveUp(list)
+				return nil
+			}
+		}
+		return ev
+	})
+
+	hints := tview.NewTextView().
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(false).
+		SetText("[j/k] Move   [enter] Go to   [esc] Close")
+	hints.SetBorder(false)
+	inner := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(list, 0, 1, true).
+		AddItem(hints, 1, 0, false)
+	u.pages.AddPage("index", pad(wrapWithRules(inner), 1, 1), true, true)
+	u.inputActive = true
+	u.app.SetFocus(list)
+	u.updateStatus()
+}
+
+// showMoveToCollectionDialog migrates the selected item into a collection,
+// creating the collection if needed.
+func (u *UI) showMoveToCollectionDialog() {
+	idx := u.list.GetCurrentItem()
+	u.showPrompt("Move to collection: ", "", func(name string) error {
+		return u.state.MoveToCollectionIndex(idx, name)
+	})
+}
+
+// showPrompt asks for a single line of text and passes it to fn, showing
+// any error in the footer.
+func (u *UI) showPrompt(label, def string, fn func(string) error) {
+	field := tview.NewInputField().SetLabel(label).SetText(def).SetFieldWidth(60)
+	field.SetBorder(false)
+	styleInputField(field)
+	field.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			u.hideInput()
+			return nil
+		case tcell.KeyEnter:
+			text := strings.TrimSpace(field.GetText())
+			if text == "" {
+				return nil
+			}
+			err := fn(text)
+			u.hideInput()
+			u.refreshList()
+			if err != nil {
+				u.showError(err.Error())
+			}
+			return nil
+		}
+		return event
+	})
+	u.showInput(field)
+}
+
+// renderWrapped renders the visible entries into the word-wrapped TextView with regions
+// so that long bullet lines wrap instead of being cut off.
+func (u *UI) renderWrapped(entries []app.Entry) {
+	var b strings.Builder
+	if len(entries) == 0 {
+		b.WriteString("⟂ No items for today — press 'a' to add\n")
+	} else {
+		for i, e := range entries {
+			// Start region for selection highlighting of this item
+			regionID := "item:" + strconv.Itoa(i)
+			b.WriteString("[\"" + regionID + "\"]")
+			b.WriteString(u.formatEntry(e))
+			// End region
+			b.WriteString("[\"\"]\n")
+		}
+	}
+	u.wrapView.SetText(b.String())
+	// Keep highlight in sync with current selection
+	u.highlightSelection(u.list.GetCurrentItem())
+}
+
+// highlightSelection highlights the current item region in the wrapped view and scrolls to it.
+func (u *UI) highlightSelection(idx int) {
+	if idx < 0 {
+		u.wrapView.Highlight()
+		return
+	}
+	regionID := "item:" + strconv.Itoa(idx)
 	u.wrapView.Highlight(regionID)
 	u.wrapView.ScrollToHighlight()
 }
@@ -1197,12 +2391,31 @@ func (u *UI) showTagsDialog() {
 	u.showInput(field)
 }
 
+// warningSummary flags storage problems, such as unreadable lines skipped
+// while loading, on a line of their own in the footer.
+func (u *UI) warningSummary() string {
+	ws := u.state.Warnings()
+	if len(ws) == 0 {
+		return ""
+	}
+	msg := "\n⚠ " + ws[0]
+	if len(ws) > 1 {
+		msg += " (+" + strconv.Itoa(len(ws)-1) + " more)"
+	}
+	return msg
+}
+
 // showError displays a blocking modal with an OK button.
 func (u *UI) showError(msg string) {
 	// Inline ephemeral error in controls footer
 	u.controls.SetText("[red]" + msg + "[-]  " + u.contextControls() + u.filtersSummary())
 }
 
+// showNotice displays an inline informational message in the controls footer.
+func (u *UI) showNotice(msg string) {
+	u.controls.SetText(msg + "  " + u.contextControls() + u.filtersSummary())
+}
+
 // Inline input helpers
 func (u *UI) showInput(p tview.Primitive) {
 	if u.inputActive && u.inputPrimitive != nil {
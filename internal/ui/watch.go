@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Timing for the data dir watcher: changes are coalesced until the
+// directory has been quiet for watchSettle, and the polling fallback scans
+// every pollInterval.
+const (
+	watchSettle  = 150 * time.Millisecond
+	pollInterval = time.Second
+)
+
+// watchDir calls onChange from a background goroutine whenever journal
+// files under dir change, using inotify where available and polling
+// otherwise. Bursts of changes (e.g. a temp file renamed over a day file)
+// produce a single call. The returned func stops watching.
+func watchDir(dir string, onChange func()) (stop func()) {
+	events := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case events <- struct{}{}:
+		default:
+		}
+	}
+	done := make(chan struct{})
+	stopEvents, err := watchEvents(dir, notify)
+	if err != nil {
+		stopEvents = pollDir(dir, notify)
+	}
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-events:
+			}
+			// Wait for the burst to settle before reloading.
+			for settled := false; !settled; {
+				select {
+				case <-done:
+					return
+				case <-events:
+				case <-time.After(watchSettle):
+					settled = true
+				}
+			}
+			onChange()
+		}
+	}()
+	return func() {
+		stopEvents()
+		close(done)
+	}
+}
+
+// journalFile reports whether a file name holds journal data the TUI shows:
+// day, monthly, future and collection logs, and recurrence rules.
+func journalFile(name string) bool {
+	return strings.HasSuffix(name, ".jsonl") || name == "recurrences.json"
+}
+
+// skipDir reports whether a directory under the data dir is never watched,
+// such as hidden directories.
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// pollDir calls notify whenever the size or mtime of a journal file under
+// dir changes, or one appears or disappears.
+func pollDir(dir string, notify func()) (stop func()) {
+	done := make(chan struct{})
+	last := snapshot(dir)
+	go func() {
+		t := time.NewTicker(pollInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			if cur := snapshot(dir); !sameSnapshot(last, cur) {
+				last = cur
+				notify()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+type fileStamp struct {
+	size  int64
+	mtime time.Time
+}
+
+func snapshot(dir string) map[string]fileStamp {
+	out := map[string]fileStamp{}
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !journalFile(d.Name()) {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			out[path] = fileStamp{size: fi.Size(), mtime: fi.ModTime()}
+		}
+		return nil
+	})
+	return out
+}
+
+func sameSnapshot(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w.size != v.size || !w.mtime.Equal(v.mtime) {
+			return false
+		}
+	}
+	return true
+}
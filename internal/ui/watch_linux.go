@@ -0,0 +1,99 @@
+//go:build linux
+
+package ui
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO |
+	syscall.IN_MOVED_FROM | syscall.IN_DELETE
+
+// watchEvents watches dir and its subdirectories with inotify, adding
+// watches for directories created later (e.g. a new YYYY/MM).
+func watchEvents(dir string, notify func()) (stop func(), err error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	// A non-blocking fd is registered with the runtime poller, so Close
+	// unblocks the reader below.
+	f := os.NewFile(uintptr(fd), "inotify")
+	var mu sync.Mutex
+	dirs := map[int32]string{}
+	add := func(root string) error {
+		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				if path == root {
+					return err
+				}
+				return nil
+			}
+			if path != dir && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			wd, err := syscall.InotifyAddWatch(fd, path, inotifyMask)
+			if err != nil {
+				if path == root {
+					return err
+				}
+				return nil
+			}
+			mu.Lock()
+			dirs[int32(wd)] = path
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := add(dir); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			changed := false
+			for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+				ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+				nameBytes := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+				off += syscall.SizeofInotifyEvent + int(ev.Len)
+				name := string(nameBytes)
+				for len(name) > 0 && name[len(name)-1] == 0 {
+					name = name[:len(name)-1]
+				}
+				switch {
+				case ev.Mask&syscall.IN_Q_OVERFLOW != 0:
+					changed = true
+				case ev.Mask&syscall.IN_ISDIR != 0:
+					if ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 && !skipDir(name) {
+						mu.Lock()
+						parent, ok := dirs[ev.Wd]
+						mu.Unlock()
+						if ok {
+							// Files may already exist in a directory created
+							// and filled before its watch was added.
+							_ = add(filepath.Join(parent, name))
+							changed = true
+						}
+					}
+				case journalFile(name):
+					changed = true
+				}
+			}
+			if changed {
+				notify()
+			}
+		}
+	}()
+	return func() { f.Close() }, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package ui
+
+import "errors"
+
+// watchEvents has no native implementation here; watchDir falls back to
+// polling.
+func watchEvents(string, func()) (func(), error) {
+	return nil, errors.New("file events not supported")
+}